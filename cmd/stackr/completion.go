@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
+)
+
+// completionCommands are the words offered in place of a stack name.
+var completionCommands = []string{
+	"init", "list", "history", "all", "tear-down", "pause", "unpause", "pull", "update", "backup", "restore", "compose",
+	"vars-only", "get-vars", "run-cron", "top", "logs", "events", "upgrade-config", "self-update", "watch", "validate", "uninstall",
+	"remote", "completion",
+}
+
+// completionFlags are the long flags offered after "-".
+var completionFlags = []string{
+	"help", "version", "debug", "dry-run", "tag", "tag-digest", "tag-from-git", "yes", "purge", "json",
+	"recreate-env", "stacks", "exclude", "parallel", "profile", "check-ports",
+	"incremental", "full", "accept-env-changes", "force-clone", "follow", "tail", "format",
+	"only-changed", "compress", "env", "from", "limit", "volumes", "no-pull",
+	"force-recreate", "continue-on-error", "print-env-diff", "names-only", "compose-file",
+}
+
+var (
+	completionShells     = []string{"bash", "zsh", "fish"}
+	completionRemoteCmds = []string{"list", "status", "sync", "clean", "validate"}
+)
+
+const bashCompletion = `# bash completion for stackr
+# Load with: source <(stackr completion bash)
+_stackr() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        completion) COMPREPLY=($(compgen -W "{{shells}}" -- "$cur")); return ;;
+        remote) COMPREPLY=($(compgen -W "{{remote}}" -- "$cur")); return ;;
+    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "{{flags}}" -- "$cur"))
+        return
+    fi
+    COMPREPLY=($(compgen -W "{{commands}} $(stackr list --names-only 2>/dev/null)" -- "$cur"))
+}
+complete -F _stackr stackr
+`
+
+const zshCompletion = `#compdef stackr
+# zsh completion for stackr
+# Load with: source <(stackr completion zsh)
+_stackr() {
+    case "${words[CURRENT-1]}" in
+        completion) compadd {{shells}}; return ;;
+        remote) compadd {{remote}}; return ;;
+    esac
+    if [[ "$PREFIX" == -* ]]; then
+        compadd -- {{flags}}
+        return
+    fi
+    compadd -- {{commands}} ${(f)"$(stackr list --names-only 2>/dev/null)"}
+}
+compdef _stackr stackr
+`
+
+const fishCompletion = `# fish completion for stackr
+# Load with: stackr completion fish | source
+complete -c stackr -f
+complete -c stackr -n '__fish_seen_subcommand_from completion' -a '{{shells}}'
+complete -c stackr -n '__fish_seen_subcommand_from remote' -a '{{remote}}'
+complete -c stackr -a '{{commands}}'
+complete -c stackr -a '(stackr list --names-only 2>/dev/null)' -d stack
+{{fishflags}}
+`
+
+// writeCompletion writes the completion script for shell to w. Stack names
+// are completed at runtime through "stackr list --names-only".
+func writeCompletion(w io.Writer, shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("unsupported shell %q (expected %s)", shell, strings.Join(completionShells, ", "))
+	}
+
+	flags := make([]string, len(completionFlags))
+	fishFlags := make([]string, len(completionFlags))
+	for i, flag := range completionFlags {
+		flags[i] = "--" + flag
+		fishFlags[i] = "complete -c stackr -l " + flag
+	}
+
+	replacer := strings.NewReplacer(
+		"{{shells}}", strings.Join(completionShells, " "),
+		"{{remote}}", strings.Join(completionRemoteCmds, " "),
+		"{{commands}}", strings.Join(completionCommands, " "),
+		"{{flags}}", strings.Join(flags, " "),
+		"{{fishflags}}", strings.Join(fishFlags, "\n"),
+	)
+	_, err := io.WriteString(w, replacer.Replace(script))
+	return err
+}
+
+// writeStackNames prints one stack name per line, for list --names-only and
+// the completion scripts.
+func writeStackNames(cfg config.Config, w io.Writer) error {
+	stacks, err := stackcmd.DiscoverStacks(cfg)
+	if err != nil {
+		return err
+	}
+	for _, stack := range stacks {
+		_, _ = fmt.Fprintln(w, stack.Name)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, writeCompletion(&out, shell))
+			script := out.String()
+			require.Contains(t, script, "stackr list --names-only")
+			require.Contains(t, script, "tear-down")
+			require.Contains(t, script, "dry-run")
+			require.NotContains(t, script, "{{")
+		})
+	}
+
+	require.Error(t, writeCompletion(&bytes.Buffer{}, "powershell"))
+}
+
+func TestWriteStackNames(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	for _, stack := range []string{"web", "db"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, stack), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, stack, "docker-compose.yml"), []byte("services: {}\n"), 0o644))
+	}
+	// Not a stack, so not offered
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "notes"), 0o755))
+
+	var out bytes.Buffer
+	require.NoError(t, writeStackNames(config.Config{RepoRoot: root, StacksDir: stacksDir}, &out))
+	require.Equal(t, []string{"db", "web"}, strings.Fields(out.String()))
+}
+
+func TestParseArgsCompletion(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"completion", "zsh"})
+	require.NoError(t, err)
+	require.Equal(t, "zsh", opts.Completion)
+
+	opts, _, _, err = parseArgs([]string{"__complete"})
+	require.NoError(t, err)
+	require.True(t, opts.CompleteStacks)
+
+	_, _, _, err = parseArgs([]string{"completion"})
+	require.Error(t, err)
+
+	opts, _, _, err = parseArgs([]string{"list", "--names-only"})
+	require.NoError(t, err)
+	require.True(t, opts.NamesOnly)
+
+	_, _, _, err = parseArgs([]string{"--names-only"})
+	require.ErrorContains(t, err, "requires the list command")
+	_, _, _, err = parseArgs([]string{"list", "--names-only", "--json"})
+	require.ErrorContains(t, err, "cannot be combined")
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	"github.com/jamestiberiuskirk/stackr/internal/audit"
 	"github.com/jamestiberiuskirk/stackr/internal/config"
 	"github.com/jamestiberiuskirk/stackr/internal/cronjobs"
+	"github.com/jamestiberiuskirk/stackr/internal/runner"
 	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
 )
 
@@ -30,37 +44,125 @@ Usage:
 
 Examples:
   stackr init
+  stackr list --json
   stackr all update
+  stackr all update --exclude noisy --exclude legacy
+  stackr all update --parallel 4
+  stackr all pull --parallel 4
+  stackr all update --continue-on-error
   stackr myapp update --tag v1.0.3
+  stackr myapp update --tag v1.0.3 --tag-digest
+  stackr myapp update --tag v1.0.3 --print-env-diff
+  stackr myapp update --tag-from-git
   stackr myapp compose up --build
+  stackr myapp update --compose-file compose.prod.yml
   stackr myapp vars-only -- env | grep STACKR_PROV
   stackr monitoring get-vars
+  stackr monitoring get-vars --recreate-env
   stackr mystack run-cron backup
   stackr mystack run-cron backup -- /app/script.sh --verbose
+  stackr all run-cron --list
+  stackr myapp restore --from 20240102_030405
+  stackr history myapp --limit 5
+  stackr validate
+  stackr uninstall --yes --purge
+  stackr watch --stacks myapp,monitoring
+  stackr myapp top --json
+  stackr myapp logs web --follow --tail 100
+  stackr myapp events
 
 Flags:
   -h, --help         Show this help message
   -v, --version      Show version information
   -D, --debug        Print debug messages
-      --dry-run      Do not execute write actions; print docker compose config
+      --dry-run      Do not execute write actions; print docker compose config, or
+                     for tear-down, pause, unpause and vars-only the command instead
       --tag <tag>    Update .env with image tag before deployment (requires update command)
+      --tag-digest   With --tag, pin the tag to its registry digest (tag@sha256:...)
+      --tag-from-git With update, use "git describe --tags" of the repo root as the tag
+      --print-env-diff
+                     With a tag update, print a diff of the .env keys it changed
+  -y, --yes          Confirm destructive commands (required by uninstall, skips the restore
+                     and tear-down prompts)
+      --purge        With uninstall, also remove pool volumes and backups
+      --json         Print list, remote list/status, top and update results as JSON
+      --format <tmpl>
+                     Print list or remote list/status items through a Go template,
+                     e.g. '{{.Name}} {{.Type}}'
+      --names-only   With list, print only the stack names, one per line
+      --compose-file <name>
+                     Use this compose file (relative to the stack dir) instead of the
+                     stack's configured ones
+      --recreate-env With get-vars, rebuild the stack's .env block, dropping unused vars
+      --stacks <a,b> With watch, only redeploy the listed stacks
+      --exclude <stack>
+                     Skip a stack when running on all stacks (repeatable)
+      --parallel <n> Operate on up to n stacks at once (default 1); output is grouped per stack
+      --continue-on-error
+                     Keep going when a stack fails and report every failure at the end
+      --env <KEY>    Forward this host env var to compose when env.passthrough restricts
+                     them (repeatable)
+      --profile <name>
+                     Active profile, e.g. to pick a remote stack's release.refs entry
+                     (defaults to $STACKR_PROFILE)
+      --check-ports  Before bringing a stack up, fail if its published host ports are taken
+      --only-changed With update, skip stacks whose running containers match their
+                     compose config and image tags
+      --no-pull      With update, skip pulling images and restart with the local ones
+      --force-recreate
+                     With update, recreate containers even if no new images were pulled
+      --incremental  With backup, only copy files changed since the stack's last backup
+      --full         With backup, force a full copy (starts a new incremental chain)
+      --compress     With backup, write each directory as a .tar.gz
+      --volumes      With backup, also dump the stack's named docker volumes
+      --from <ts>    With restore, restore the backup taken at <ts> instead of the latest
+      --limit <n>    With history, show at most <n> deploys (default 20)
+      --accept-env-changes
+                     Deploy a remote stack even if its merged env changed since the last deploy
 
 Commands (can be combined):
   init           Initialize a new stackr project with config and example stacks
+  list           List discovered stacks with their type and compose file
+  validate       Check config, compose files and env vars of every stack (or the
+                 named ones) without deploying; exits non-zero if any is invalid
+  history [stack...]
+                 Show recent HTTP deploys from the audit log, newest first
   all            Run on all stacks
   tear-down      Run "docker compose down" for the stack(s)
+  pause          Freeze the stack's containers ("docker compose pause")
+  unpause        Resume paused containers ("docker compose unpause")
+  pull           Pull the stack(s)' images without restarting anything
+                 (with --parallel <n> to pull several stacks at once)
   update         Pull latest images and restart stack(s)
   backup         Back up config/volumes to BACKUP_DIR
+  restore        Copy the stack's latest backup (or --from <ts>) back into place
   compose        Shorthand for "vars-only -- docker compose -f $DCFP <args...>"
   vars-only      Load env vars for the stack(s) and execute the command after --
   get-vars       Scan compose files for env vars and append missing entries to .env
   run-cron <svc> Manually execute a cron job service (optionally with custom command after --)
+                 (--list to show the stack's cron services instead, --json for JSON)
+  top            Show CPU, memory, network and disk I/O of the stack's running containers
+  logs [svc...]  Show the stack's logs, optionally only for the given services
+                 (-f/--follow to stream until Ctrl-C, --tail <n> to limit lines)
+  events         Stream docker events of the stack's containers until Ctrl-C
+  upgrade-config Rename deprecated keys in .stackr.yaml (use --dry-run to preview)
+  self-update    Replace this binary with the latest release after verifying its checksum
+  watch          Stay in the foreground and redeploy a stack whenever its files change
+  uninstall      Tear down every stack (requires --yes; --purge also removes pools and backups)
+  completion <shell>
+                 Print a completion script for bash, zsh or fish
 
 Remote stack management:
   remote list              List all remote stacks and their sync status
   remote status <stack>    Show detailed status of a remote stack
   remote sync <stack>      Manually sync a remote stack from its Git repository
+                           (--force-clone removes the clone and re-clones it first)
   remote clean <stack>     Remove the cached clone of a remote stack
+  remote validate <stack>  Sync a remote stack and print its rendered compose config
+                           without deploying it
+
+  Add --json to "remote list" or "remote status" for machine-readable output.
+  Add --dry-run to "remote sync" or "remote clean" to print what they would do.
 `
 
 func main() {
@@ -86,6 +188,14 @@ func main() {
 		return
 	}
 
+	// Handle completion separately (doesn't need config)
+	if opts.Completion != "" {
+		if err := writeCompletion(os.Stdout, opts.Completion); err != nil {
+			log.Fatalf("completion failed: %v", err)
+		}
+		return
+	}
+
 	// Handle init command separately (doesn't need config)
 	if opts.Init {
 		if err := stackcmd.RunInit(); err != nil {
@@ -94,9 +204,73 @@ func main() {
 		return
 	}
 
+	// Handle self-update separately (doesn't need config)
+	if opts.SelfUpdate {
+		if err := runSelfUpdate(context.Background(), selfUpdateBaseURL(), os.Stdout); err != nil {
+			log.Fatalf("self-update failed: %v", err)
+		}
+		return
+	}
+
 	repoRootOverride := strings.TrimSpace(os.Getenv("STACKR_REPO_ROOT"))
 
+	// The config loaders below read the active profile from STACKR_PROFILE
+	if opts.Profile != "" {
+		_ = os.Setenv("STACKR_PROFILE", opts.Profile)
+	}
+
+	// Called by the completion scripts on every <Tab>, so stay silent on errors
+	if opts.CompleteStacks {
+		repoRoot, err := config.ResolveRepoRoot(repoRootOverride)
+		if err != nil {
+			return
+		}
+		cfg, err := config.LoadForCLI(repoRoot)
+		if err != nil {
+			return
+		}
+		_ = writeStackNames(cfg, os.Stdout)
+		return
+	}
+
+	// Handle upgrade-config separately (must work before the config is valid)
+	if opts.UpgradeCfg {
+		repoRoot, err := config.ResolveRepoRoot(repoRootOverride)
+		if err != nil {
+			log.Fatalf("failed to determine repo root: %v", err)
+		}
+		if err := runUpgradeConfig(repoRoot, opts.DryRun); err != nil {
+			log.Fatalf("upgrade-config failed: %v", err)
+		}
+		return
+	}
+
 	// Handle run-cron command (needs config but bypasses normal stack manager)
+	if opts.RunCron && opts.CronList {
+		if !opts.All && len(opts.Stacks) == 0 {
+			log.Fatalf("run-cron --list requires a stack name or all")
+		}
+
+		repoRoot, err := config.ResolveRepoRoot(repoRootOverride)
+		if err != nil {
+			log.Fatalf("failed to determine repo root: %v", err)
+		}
+
+		cfg, err := config.LoadForCLI(repoRoot)
+		if err != nil {
+			log.Fatalf("failed to load config: %v", err)
+		}
+
+		stacks := opts.Stacks
+		if opts.All {
+			stacks = nil
+		}
+		if err := runCronList(cfg, stacks, opts.JSON, os.Stdout); err != nil {
+			log.Fatalf("run-cron --list failed: %v", err)
+		}
+		return
+	}
+
 	if opts.RunCron {
 		if len(opts.Stacks) != 1 {
 			log.Fatalf("run-cron requires exactly one stack name")
@@ -122,6 +296,47 @@ func main() {
 		return
 	}
 
+	// Handle list command (needs config but bypasses normal stack manager)
+	if opts.List {
+		repoRoot, err := config.ResolveRepoRoot(repoRootOverride)
+		if err != nil {
+			log.Fatalf("failed to determine repo root: %v", err)
+		}
+
+		cfg, err := config.LoadForCLI(repoRoot)
+		if err != nil {
+			log.Fatalf("failed to load config: %v", err)
+		}
+
+		if opts.NamesOnly {
+			err = writeStackNames(cfg, os.Stdout)
+		} else {
+			err = runList(cfg, opts.JSON, opts.Format, os.Stdout)
+		}
+		if err != nil {
+			log.Fatalf("list failed: %v", err)
+		}
+		return
+	}
+
+	// Handle history command (needs config but bypasses normal stack manager)
+	if opts.History {
+		repoRoot, err := config.ResolveRepoRoot(repoRootOverride)
+		if err != nil {
+			log.Fatalf("failed to determine repo root: %v", err)
+		}
+
+		cfg, err := config.LoadForCLI(repoRoot)
+		if err != nil {
+			log.Fatalf("failed to load config: %v", err)
+		}
+
+		if err := runHistory(cfg, opts.Stacks, opts.HistoryLimit, opts.JSON, os.Stdout); err != nil {
+			log.Fatalf("history failed: %v", err)
+		}
+		return
+	}
+
 	// Handle remote command (needs config but bypasses normal stack manager)
 	if opts.Remote {
 		repoRoot, err := config.ResolveRepoRoot(repoRootOverride)
@@ -150,19 +365,30 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Emit the same structured result as the deploy API
+	if opts.JSON && opts.Update {
+		if err := runDeployJSON(ctx, cfg, opts, os.Stdout); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		return
+	}
+
 	manager, err := stackcmd.NewManager(cfg)
 	if err != nil {
 		log.Fatalf("failed to initialize stack manager: %v", err)
 	}
 
-	if err := manager.Run(context.Background(), opts); err != nil {
+	if err := manager.Run(ctx, opts); err != nil {
 		log.Fatalf("error: %v", err)
 	}
 }
 
 func parseArgs(args []string) (stackcmd.Options, bool, bool, error) {
 	var opts stackcmd.Options
-	var showVersion bool
+	var showVersion, stacksFlag bool
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 		switch arg {
@@ -180,6 +406,12 @@ func parseArgs(args []string) (stackcmd.Options, bool, bool, error) {
 			}
 			i++
 			opts.Tag = args[i]
+		case "--tag-digest":
+			opts.TagDigest = true
+		case "--tag-from-git":
+			opts.TagFromGit = true
+		case "--print-env-diff":
+			opts.PrintEnvDiff = true
 		case "all":
 			opts.All = true
 		case "tear-down":
@@ -201,30 +433,198 @@ func parseArgs(args []string) (stackcmd.Options, bool, bool, error) {
 			opts.GetVars = true
 		case "init":
 			opts.Init = true
+		case "uninstall":
+			opts.Uninstall = true
+		case "validate":
+			opts.Validate = true
+		case "-y", "--yes":
+			opts.Yes = true
+		case "--purge":
+			opts.Purge = true
+		case "--json":
+			opts.JSON = true
+		case "--recreate-env":
+			opts.RecreateEnv = true
+		case "--accept-env-changes":
+			opts.AcceptEnvChanges = true
+		case "upgrade-config":
+			opts.UpgradeCfg = true
+		case "self-update":
+			opts.SelfUpdate = true
+		case "watch":
+			opts.Watch = true
+		case "top":
+			opts.Top = true
+		case "pause":
+			opts.Pause = true
+		case "unpause":
+			opts.Unpause = true
+		case "pull":
+			opts.Pull = true
+		case "logs":
+			opts.Logs = true
+		case "-f", "--follow":
+			opts.LogsFollow = true
+		case "events":
+			opts.Events = true
+		case "--tail":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--tail requires a number of lines")
+			}
+			i++
+			opts.LogsTail = args[i]
+		case "list":
+			opts.List = true
+		case "history":
+			opts.History = true
+		case "--limit":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--limit requires a number")
+			}
+			i++
+			limit, err := strconv.Atoi(args[i])
+			if err != nil || limit < 1 {
+				return opts, false, false, fmt.Errorf("--limit must be a positive number, got %q", args[i])
+			}
+			opts.HistoryLimit = limit
+		case "completion":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("completion requires a shell (bash, zsh, fish)")
+			}
+			i++
+			opts.Completion = args[i]
+		case "__complete":
+			opts.CompleteStacks = true
+		case "--stacks":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--stacks requires a comma-separated list of stacks")
+			}
+			stacksFlag = true
+			i++
+			for _, name := range strings.Split(args[i], ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					opts.Stacks = append(opts.Stacks, name)
+				}
+			}
+		case "--exclude":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--exclude requires a stack name")
+			}
+			i++
+			opts.Exclude = append(opts.Exclude, args[i])
+		case "--env":
+			if i+1 >= len(args) || strings.TrimSpace(args[i+1]) == "" || strings.Contains(args[i+1], "=") {
+				return opts, false, false, fmt.Errorf("--env requires a host env var name")
+			}
+			i++
+			opts.EnvPassthrough = append(opts.EnvPassthrough, strings.TrimSpace(args[i]))
+		case "--parallel":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--parallel requires a number")
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 1 {
+				return opts, false, false, fmt.Errorf("--parallel must be a positive number, got %q", args[i])
+			}
+			opts.Parallel = n
+		case "--profile":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--profile requires a profile name")
+			}
+			i++
+			opts.Profile = args[i]
+		case "--format":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--format requires a template")
+			}
+			i++
+			opts.Format = args[i]
+		case "--names-only":
+			opts.NamesOnly = true
+		case "--compose-file":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--compose-file requires a file name")
+			}
+			i++
+			opts.ComposeFile = args[i]
+		case "--check-ports":
+			opts.CheckPorts = true
+		case "--continue-on-error":
+			opts.ContinueOnError = true
+		case "--only-changed":
+			opts.OnlyChanged = true
+		case "--no-pull":
+			opts.NoPull = true
+		case "--force-recreate":
+			opts.ForceRecreate = true
+		case "--incremental":
+			opts.Incremental = true
+		case "--full":
+			opts.Full = true
+		case "--compress":
+			opts.Compress = true
+		case "--volumes":
+			opts.Volumes = true
+		case "restore":
+			opts.Restore = true
+		case "--from":
+			if i+1 >= len(args) {
+				return opts, false, false, fmt.Errorf("--from requires a backup timestamp")
+			}
+			i++
+			opts.RestoreFrom = args[i]
 		case "remote":
 			opts.Remote = true
 			if i+1 >= len(args) {
-				return opts, false, false, fmt.Errorf("remote requires a subcommand (list, status, sync, clean)")
+				return opts, false, false, fmt.Errorf("remote requires a subcommand (list, status, sync, clean, validate)")
 			}
 			i++
 			opts.RemoteSubCmd = args[i]
 			switch opts.RemoteSubCmd {
 			case "list":
 				// no additional args needed
-			case "status", "sync", "clean":
-				if i+1 >= len(args) {
+			case "status", "sync", "clean", "validate":
+				// The stack name is the first argument that isn't a flag, so
+				// "remote status --json myapp" works too
+				for j := i + 1; j < len(args); j++ {
+					if args[j] == "--format" {
+						j++
+						continue
+					}
+					if !strings.HasPrefix(args[j], "-") {
+						opts.RemoteStack = args[j]
+						break
+					}
+				}
+				if opts.RemoteStack == "" {
 					return opts, false, false, fmt.Errorf("remote %s requires a stack name", opts.RemoteSubCmd)
 				}
-				i++
-				opts.RemoteStack = args[i]
 			default:
-				return opts, false, false, fmt.Errorf("unknown remote subcommand %q (expected list, status, sync, clean)", opts.RemoteSubCmd)
+				return opts, false, false, fmt.Errorf("unknown remote subcommand %q (expected list, status, sync, clean, validate)", opts.RemoteSubCmd)
+			}
+			opts.JSON = opts.JSON || slices.Contains(args[i+1:], "--json")
+			opts.ForceClone = slices.Contains(args[i+1:], "--force-clone")
+			opts.DryRun = opts.DryRun || slices.Contains(args[i+1:], "--dry-run")
+			if idx := slices.Index(args[i+1:], "--format"); idx >= 0 {
+				if i+idx+2 >= len(args) {
+					return opts, false, false, fmt.Errorf("--format requires a template")
+				}
+				opts.Format = args[i+idx+2]
+			}
+			if opts.ForceClone && opts.RemoteSubCmd != "sync" {
+				return opts, false, false, fmt.Errorf("--force-clone requires remote sync")
 			}
 			i = len(args) // consume remaining args
 		case "run-cron":
 			opts.RunCron = true
+			if i+1 < len(args) && args[i+1] == "--list" {
+				opts.CronList = true
+				i++
+				break
+			}
 			if i+1 >= len(args) {
-				return opts, false, false, fmt.Errorf("run-cron requires a service name")
+				return opts, false, false, fmt.Errorf("run-cron requires a service name (or --list)")
 			}
 			i++
 			opts.CronService = args[i]
@@ -246,12 +646,95 @@ func parseArgs(args []string) (stackcmd.Options, bool, bool, error) {
 			if strings.HasPrefix(arg, "-") {
 				return opts, false, false, fmt.Errorf("unknown flag %s", arg)
 			}
-			if !opts.All {
+			// Names after "logs" pick services, not stacks
+			if opts.Logs {
+				opts.LogServices = append(opts.LogServices, arg)
+			} else if !opts.All {
 				opts.Stacks = append(opts.Stacks, arg)
 			}
 		}
 	}
 
+	if opts.RecreateEnv && !opts.GetVars {
+		return opts, false, false, fmt.Errorf("--recreate-env requires the get-vars command")
+	}
+	if (opts.Incremental || opts.Full || opts.Compress || opts.Volumes) && !opts.Backup {
+		return opts, false, false, fmt.Errorf("--incremental, --full, --compress and --volumes require the backup command")
+	}
+	if opts.HistoryLimit != 0 && !opts.History {
+		return opts, false, false, fmt.Errorf("--limit requires the history command")
+	}
+	if opts.RestoreFrom != "" && !opts.Restore {
+		return opts, false, false, fmt.Errorf("--from requires the restore command")
+	}
+	if opts.Restore && opts.Backup {
+		return opts, false, false, fmt.Errorf("backup and restore cannot be combined")
+	}
+	if opts.Restore && (opts.All || len(opts.Stacks) != 1) {
+		return opts, false, false, fmt.Errorf("restore requires exactly one stack")
+	}
+	if (opts.LogsFollow || opts.LogsTail != "") && !opts.Logs {
+		return opts, false, false, fmt.Errorf("--follow and --tail require the logs command")
+	}
+	if opts.LogsFollow && (opts.All || len(opts.Stacks) != 1) {
+		return opts, false, false, fmt.Errorf("--follow requires exactly one stack")
+	}
+	if opts.Events && (opts.All || len(opts.Stacks) != 1) {
+		return opts, false, false, fmt.Errorf("events requires exactly one stack")
+	}
+	if opts.Format != "" {
+		if !opts.List && !(opts.Remote && (opts.RemoteSubCmd == "list" || opts.RemoteSubCmd == "status")) {
+			return opts, false, false, fmt.Errorf("--format requires list, remote list or remote status")
+		}
+		if opts.JSON {
+			return opts, false, false, fmt.Errorf("--format and --json cannot be combined")
+		}
+		if _, err := parseFormat(opts.Format); err != nil {
+			return opts, false, false, err
+		}
+	}
+	if opts.ComposeFile != "" && filepath.IsAbs(opts.ComposeFile) {
+		return opts, false, false, fmt.Errorf("--compose-file must be relative to the stack directory, got %q", opts.ComposeFile)
+	}
+	if opts.NamesOnly {
+		if !opts.List {
+			return opts, false, false, fmt.Errorf("--names-only requires the list command")
+		}
+		if opts.JSON || opts.Format != "" {
+			return opts, false, false, fmt.Errorf("--names-only cannot be combined with --json or --format")
+		}
+	}
+	if opts.Pause && opts.Unpause {
+		return opts, false, false, fmt.Errorf("pause and unpause cannot be combined")
+	}
+	if opts.Pull && (opts.Update || opts.TearDown) {
+		return opts, false, false, fmt.Errorf("pull cannot be combined with update or tear-down")
+	}
+	if stacksFlag && !opts.Watch {
+		return opts, false, false, fmt.Errorf("--stacks requires the watch command")
+	}
+	if opts.OnlyChanged && !opts.Update {
+		return opts, false, false, fmt.Errorf("--only-changed requires the update command")
+	}
+	if (opts.NoPull || opts.ForceRecreate) && !opts.Update {
+		return opts, false, false, fmt.Errorf("--no-pull and --force-recreate require the update command")
+	}
+	if opts.TagFromGit && opts.Tag != "" {
+		return opts, false, false, fmt.Errorf("--tag and --tag-from-git cannot be combined")
+	}
+	if opts.TagFromGit && !opts.Update {
+		return opts, false, false, fmt.Errorf("--tag-from-git requires the update command")
+	}
+	if opts.TagDigest && opts.Tag == "" && !opts.TagFromGit {
+		return opts, false, false, fmt.Errorf("--tag-digest requires --tag or --tag-from-git")
+	}
+	if opts.PrintEnvDiff && opts.Tag == "" && !opts.TagFromGit {
+		return opts, false, false, fmt.Errorf("--print-env-diff requires --tag or --tag-from-git")
+	}
+	if opts.JSON && opts.Update && (opts.All || len(opts.Stacks) != 1) {
+		return opts, false, false, fmt.Errorf("--json with update requires exactly one stack")
+	}
+
 	return opts, false, showVersion, nil
 }
 
@@ -262,6 +745,15 @@ func runRemoteCommand(cfg config.Config, opts stackcmd.Options) error {
 		if err != nil {
 			return err
 		}
+		if opts.JSON {
+			if statuses == nil {
+				statuses = []*stackcmd.RemoteStackStatus{}
+			}
+			return printJSON(statuses)
+		}
+		if opts.Format != "" {
+			return writeFormatted(os.Stdout, opts.Format, statuses)
+		}
 		if len(statuses) == 0 {
 			fmt.Println("No remote stacks configured.")
 			return nil
@@ -277,6 +769,12 @@ func runRemoteCommand(cfg config.Config, opts stackcmd.Options) error {
 		if err != nil {
 			return err
 		}
+		if opts.JSON {
+			return printJSON(status)
+		}
+		if opts.Format != "" {
+			return writeFormatted(os.Stdout, opts.Format, []*stackcmd.RemoteStackStatus{status})
+		}
 		fmt.Print(stackcmd.FormatRemoteStackStatus(status, true))
 		return nil
 
@@ -288,20 +786,274 @@ func runRemoteCommand(cfg config.Config, opts stackcmd.Options) error {
 		if envVars == nil {
 			envVars = make(map[string]string)
 		}
-		if err := stackcmd.SyncRemoteStack(cfg, opts.RemoteStack, envVars); err != nil {
+		if opts.DryRun {
+			plan, err := stackcmd.PlanSyncRemoteStack(cfg, opts.RemoteStack, envVars, opts.ForceClone)
+			if err != nil {
+				return err
+			}
+			for _, step := range plan {
+				fmt.Printf("[DRY RUN] Would %s\n", step)
+			}
+			return nil
+		}
+		if err := stackcmd.SyncRemoteStack(cfg, opts.RemoteStack, envVars, opts.ForceClone); err != nil {
 			return err
 		}
 		fmt.Printf("Successfully synced remote stack %q\n", opts.RemoteStack)
 		return nil
 
 	case "clean":
+		if opts.DryRun {
+			repoPath, err := stackcmd.PlanCleanRemoteStack(cfg, opts.RemoteStack)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("[DRY RUN] Would remove %s\n", repoPath)
+			return nil
+		}
 		if err := stackcmd.CleanRemoteStack(cfg, opts.RemoteStack); err != nil {
 			return err
 		}
 		fmt.Printf("Successfully cleaned remote stack %q\n", opts.RemoteStack)
 		return nil
 
+	case "validate":
+		manager, err := stackcmd.NewManager(cfg)
+		if err != nil {
+			return err
+		}
+		out, err := manager.ValidateRemote(context.Background(), opts.RemoteStack)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+
 	default:
 		return fmt.Errorf("unknown remote subcommand %q", opts.RemoteSubCmd)
 	}
 }
+
+// runList prints every discovered stack with its type and primary compose
+// file, as a table, as JSON or through the --format template.
+func runList(cfg config.Config, asJSON bool, format string, w io.Writer) error {
+	stacks, err := stackcmd.DiscoverStacks(cfg)
+	if err != nil {
+		return err
+	}
+
+	if format != "" {
+		return writeFormatted(w, format, stacks)
+	}
+
+	if asJSON {
+		if stacks == nil {
+			stacks = []stackcmd.StackInfo{}
+		}
+		return writeJSON(w, stacks)
+	}
+
+	if len(stacks) == 0 {
+		_, _ = fmt.Fprintf(w, "No stacks found in %s\n", cfg.StacksDir)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tTYPE\tCOMPOSE FILE")
+	for _, stack := range stacks {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", stack.Name, stack.Type, stack.PrimaryComposePath())
+	}
+	return tw.Flush()
+}
+
+// runCronList prints the cron services of the given stacks, or of every
+// stack when stacks is empty.
+func runCronList(cfg config.Config, stacks []string, asJSON bool, w io.Writer) error {
+	jobs, err := cronjobs.ListJobs(cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(stacks) > 0 {
+		known, err := stackcmd.DiscoverStacks(cfg)
+		if err != nil {
+			return err
+		}
+		for _, stack := range stacks {
+			if !slices.ContainsFunc(known, func(info stackcmd.StackInfo) bool { return info.Name == stack }) {
+				return fmt.Errorf("stack %q not found in %s", stack, cfg.StacksDir)
+			}
+		}
+		jobs = slices.DeleteFunc(jobs, func(job cronjobs.JobInfo) bool {
+			return !slices.Contains(stacks, job.Stack)
+		})
+	}
+
+	if asJSON {
+		return writeJSON(w, jobs)
+	}
+
+	if len(jobs) == 0 {
+		_, _ = fmt.Fprintln(w, "No cron services found (add a stackr.cron.schedule label to a service)")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STACK\tSERVICE\tSCHEDULE\tPROFILE\tRUN ON DEPLOY\tENABLED")
+	for _, job := range jobs {
+		schedule := job.Schedule
+		if schedule == "" {
+			schedule = "(manual)"
+		} else if job.Timezone != "" {
+			schedule = fmt.Sprintf("%s (%s)", schedule, job.Timezone)
+		}
+		profile := job.Profile
+		if profile == "" {
+			profile = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%t\n",
+			job.Stack, job.Service, schedule, profile, job.RunOnDeploy, job.Enabled)
+	}
+	return tw.Flush()
+}
+
+// defaultHistoryLimit is how many deploys history prints without --limit.
+const defaultHistoryLimit = 20
+
+// runHistory prints the most recent deploys recorded in the audit log,
+// newest first, optionally only those of the given stacks.
+func runHistory(cfg config.Config, stacks []string, limit int, asJSON bool, w io.Writer) error {
+	path := cfg.Global.Audit.LogPath(cfg.RepoRoot)
+	if path == "" {
+		return errors.New("the audit log is disabled; set audit.log in .stackr.yaml to record deploys")
+	}
+	entries, err := audit.Read(path)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if len(stacks) > 0 {
+		entries = slices.DeleteFunc(entries, func(entry audit.Entry) bool {
+			return !slices.Contains(stacks, entry.Stack)
+		})
+	}
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	slices.Reverse(entries)
+
+	if asJSON {
+		if entries == nil {
+			entries = []audit.Entry{}
+		}
+		return writeJSON(w, entries)
+	}
+
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintf(w, "No deploys recorded in %s\n", path)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tSTACK\tTAG\tRESULT\tAUTH\tSOURCE")
+	for _, entry := range entries {
+		result := entry.Result
+		if entry.Error != "" {
+			result = fmt.Sprintf("%s (%s)", result, entry.Error)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Time.Format(time.RFC3339), entry.Stack, entry.Tag, result, entry.Auth, entry.SourceIP)
+	}
+	return tw.Flush()
+}
+
+func runUpgradeConfig(repoRoot string, dryRun bool) error {
+	path := config.GlobalConfigPath(repoRoot)
+	changes, err := config.UpgradeConfigFile(path, dryRun)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%s is up to date\n", path)
+		return nil
+	}
+
+	prefix := ""
+	if dryRun {
+		prefix = "[DRY RUN] Would have "
+	}
+	for _, change := range changes {
+		fmt.Printf("%s%s\n", prefix, change)
+	}
+	if !dryRun {
+		fmt.Printf("Updated %s\n", path)
+	}
+	return nil
+}
+
+// runDeployJSON deploys a single stack with its output captured and writes
+// the result to w in the format the deploy API returns.
+func runDeployJSON(ctx context.Context, cfg config.Config, opts stackcmd.Options, w io.Writer) error {
+	// Resolve the tag up front so the result reports it
+	if opts.TagFromGit {
+		tag, err := stackcmd.TagFromGit(ctx, cfg.RepoRoot)
+		if err != nil {
+			return err
+		}
+		opts.Tag, opts.TagFromGit = tag, false
+	}
+
+	var stdout bytes.Buffer
+	manager, err := stackcmd.NewManagerWithWriters(cfg, &stdout, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("failed to initialize stack manager: %w", err)
+	}
+
+	if err := manager.Run(ctx, opts); err != nil {
+		// Nothing goes to stdout on failure, so surface the captured output
+		_, _ = io.Copy(os.Stderr, &stdout)
+		return err
+	}
+
+	return writeJSON(w, runner.NewResult(opts.Stacks[0], opts.Tag, stdout.String()))
+}
+
+// parseFormat compiles a --format template.
+func parseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// writeFormatted renders each item through the --format template, one line
+// per item.
+func writeFormatted[T any](w io.Writer, format string, items []T) error {
+	tmpl, err := parseFormat(format)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := tmpl.Execute(w, item); err != nil {
+			return fmt.Errorf("failed to render --format template: %w", err)
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	return writeJSON(os.Stdout, v)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -1,10 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/jamestiberiuskirk/stackr/internal/audit"
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/runner"
 	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
 )
 
@@ -25,6 +36,82 @@ func TestParseArgsVarsOnly(t *testing.T) {
 	require.Equal(t, []string{"env"}, opts.VarsCommand)
 }
 
+func TestParseArgsExclude(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"all", "update", "--exclude", "noisy", "--exclude", "legacy"})
+	require.NoError(t, err)
+	require.True(t, opts.All)
+	require.Empty(t, opts.Stacks)
+	require.Equal(t, []string{"noisy", "legacy"}, opts.Exclude)
+
+	_, _, _, err = parseArgs([]string{"all", "update", "--exclude"})
+	require.Error(t, err)
+}
+
+func TestParseArgsRemoteSyncForceClone(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"remote", "sync", "myapp", "--force-clone"})
+	require.NoError(t, err)
+	require.Equal(t, "myapp", opts.RemoteStack)
+	require.True(t, opts.ForceClone)
+
+	_, _, _, err = parseArgs([]string{"remote", "status", "myapp", "--force-clone"})
+	require.Error(t, err)
+}
+
+func TestParseArgsRemoteStatusJSON(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"remote", "status", "myapp", "--json"})
+	require.NoError(t, err)
+	require.Equal(t, "myapp", opts.RemoteStack)
+	require.True(t, opts.JSON)
+
+	opts, _, _, err = parseArgs([]string{"remote", "status", "--json", "myapp"})
+	require.NoError(t, err)
+	require.Equal(t, "myapp", opts.RemoteStack)
+	require.True(t, opts.JSON)
+
+	// A flag is never taken for the stack name
+	_, _, _, err = parseArgs([]string{"remote", "status", "--json"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "requires a stack name")
+
+	opts, _, _, err = parseArgs([]string{"remote", "status", "--format", "{{.Name}}", "myapp"})
+	require.NoError(t, err)
+	require.Equal(t, "myapp", opts.RemoteStack)
+	require.Equal(t, "{{.Name}}", opts.Format)
+}
+
+func TestParseArgsRemoteDryRun(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"remote", "sync", "myapp", "--dry-run"})
+	require.NoError(t, err)
+	require.True(t, opts.DryRun)
+
+	opts, _, _, err = parseArgs([]string{"--dry-run", "remote", "clean", "myapp"})
+	require.NoError(t, err)
+	require.True(t, opts.DryRun)
+	require.Equal(t, "clean", opts.RemoteSubCmd)
+}
+
+func TestParseArgsRemoteValidate(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"remote", "validate", "myapp"})
+	require.NoError(t, err)
+	require.True(t, opts.Remote)
+	require.Equal(t, "validate", opts.RemoteSubCmd)
+	require.Equal(t, "myapp", opts.RemoteStack)
+
+	_, _, _, err = parseArgs([]string{"remote", "validate"})
+	require.Error(t, err)
+}
+
+func TestParseArgsStacksRequiresWatch(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"watch", "--stacks", "myapp,monitoring"})
+	require.NoError(t, err)
+	require.True(t, opts.Watch)
+	require.Equal(t, []string{"myapp", "monitoring"}, opts.Stacks)
+
+	_, _, _, err = parseArgs([]string{"all", "update", "--stacks", "myapp"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "watch")
+}
+
 func TestParseArgsUnknownFlag(t *testing.T) {
 	_, _, _, err := parseArgs([]string{"--wat"})
 	require.Error(t, err)
@@ -36,3 +123,392 @@ func TestParseArgsVersion(t *testing.T) {
 	require.False(t, help)
 	require.True(t, version)
 }
+
+func TestParseArgsUpdateJSONRequiresOneStack(t *testing.T) {
+	_, _, _, err := parseArgs([]string{"all", "update", "--json"})
+	require.Error(t, err)
+
+	opts, _, _, err := parseArgs([]string{"myapp", "update", "--tag", "v2", "--json"})
+	require.NoError(t, err)
+	require.True(t, opts.JSON)
+}
+
+func TestRunDeployJSONEmitsResult(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "stacks/demo"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), []byte("DEMO_IMAGE_TAG=v1\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "stacks/demo/docker-compose.yml"), []byte(`
+services:
+  web:
+    image: nginx:${DEMO_IMAGE_TAG}
+`), 0o644))
+
+	// docker stub that succeeds without output
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global: config.GlobalConfig{
+			Paths: config.PathsConfig{BackupDir: "./backups"},
+		},
+	}
+	opts := stackcmd.Options{Stacks: []string{"demo"}, Update: true, Tag: "v2", JSON: true}
+
+	var out bytes.Buffer
+	require.NoError(t, runDeployJSON(context.Background(), cfg, opts, &out))
+
+	var result runner.Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	require.Equal(t, "ok", result.Status)
+	require.Equal(t, "demo", result.Stack)
+	require.Equal(t, "v2", result.Tag)
+	require.Equal(t, "demo: new images downloaded, restarting stack", result.Stdout)
+}
+
+func TestRunListPrintsStacks(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "web"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "web", "docker-compose.yml"), []byte("services: {}\n"), 0o644))
+	cfg := config.Config{RepoRoot: root, StacksDir: stacksDir}
+
+	var out bytes.Buffer
+	require.NoError(t, runList(cfg, false, "", &out))
+	require.Contains(t, out.String(), "NAME")
+	require.Regexp(t, `web\s+local\s+`+regexp.QuoteMeta(filepath.Join(stacksDir, "web", "docker-compose.yml")), out.String())
+
+	out.Reset()
+	require.NoError(t, runList(cfg, true, "", &out))
+	var stacks []stackcmd.StackInfo
+	require.NoError(t, json.Unmarshal(out.Bytes(), &stacks))
+	require.Len(t, stacks, 1)
+	require.Equal(t, "web", stacks[0].Name)
+	require.Equal(t, stackcmd.StackTypeLocal, stacks[0].Type)
+	require.Equal(t, []string{filepath.Join(stacksDir, "web", "docker-compose.yml")}, stacks[0].ComposePaths)
+}
+
+func TestRunHistory(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.Config{RepoRoot: root}
+	cfg.Global.Audit.Log = ".stackr/audit.jsonl"
+
+	var out bytes.Buffer
+	require.NoError(t, runHistory(cfg, nil, 0, false, &out))
+	require.Contains(t, out.String(), "No deploys recorded")
+
+	log := audit.New(filepath.Join(root, ".stackr", "audit.jsonl"))
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, entry := range []audit.Entry{
+		{Stack: "web", Tag: "v1.0.0", Result: "succeeded"},
+		{Stack: "api", Tag: "v2.0.0", Result: "succeeded"},
+		{Stack: "web", Tag: "v1.1.0", Result: "failed", Error: "pull failed"},
+		{Stack: "web", Tag: "v1.2.0", Result: "succeeded"},
+	} {
+		entry.Time = base.Add(time.Duration(i) * time.Hour)
+		entry.Auth = "token"
+		entry.SourceIP = "203.0.113.7"
+		require.NoError(t, log.Record(entry))
+	}
+
+	out.Reset()
+	require.NoError(t, runHistory(cfg, []string{"web"}, 2, false, &out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Regexp(t, `^TIME\s+STACK\s+TAG\s+RESULT\s+AUTH\s+SOURCE$`, lines[0])
+	require.Regexp(t, `^2024-05-01T15:00:00Z\s+web\s+v1\.2\.0\s+succeeded\s+token\s+203\.0\.113\.7$`, lines[1])
+	require.Regexp(t, `^2024-05-01T14:00:00Z\s+web\s+v1\.1\.0\s+failed \(pull failed\)\s+token`, lines[2])
+
+	out.Reset()
+	require.NoError(t, runHistory(cfg, nil, 0, true, &out))
+	var entries []audit.Entry
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entries))
+	require.Len(t, entries, 4)
+	require.Equal(t, "v1.2.0", entries[0].Tag)
+	require.Equal(t, "api", entries[2].Stack)
+
+	cfg.Global.Audit.Log = ""
+	require.ErrorContains(t, runHistory(cfg, nil, 0, false, &out), "audit log is disabled")
+}
+
+func TestParseArgsHistory(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"history", "web", "api", "--limit", "5", "--json"})
+	require.NoError(t, err)
+	require.True(t, opts.History)
+	require.True(t, opts.JSON)
+	require.Equal(t, 5, opts.HistoryLimit)
+	require.Equal(t, []string{"web", "api"}, opts.Stacks)
+
+	for _, args := range [][]string{
+		{"history", "--limit"},
+		{"history", "--limit", "0"},
+		{"history", "--limit", "many"},
+		{"list", "--limit", "5"},
+	} {
+		_, _, _, err := parseArgs(args)
+		require.Error(t, err, "args %v", args)
+	}
+}
+
+func TestRunListFormat(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	for _, name := range []string{"api", "web"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, name), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, name, "docker-compose.yml"), []byte("services: {}\n"), 0o644))
+	}
+	cfg := config.Config{RepoRoot: root, StacksDir: stacksDir}
+
+	var out bytes.Buffer
+	require.NoError(t, runList(cfg, false, "{{.Name}} {{.Type}}", &out))
+	require.Equal(t, "api local\nweb local\n", out.String())
+}
+
+func TestParseArgsRunCronList(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"all", "run-cron", "--list", "--json"})
+	require.NoError(t, err)
+	require.True(t, opts.RunCron)
+	require.True(t, opts.CronList)
+	require.True(t, opts.All)
+	require.True(t, opts.JSON)
+	require.Empty(t, opts.CronService)
+
+	opts, _, _, err = parseArgs([]string{"web", "run-cron", "backup"})
+	require.NoError(t, err)
+	require.False(t, opts.CronList)
+	require.Equal(t, "backup", opts.CronService)
+
+	_, _, _, err = parseArgs([]string{"web", "run-cron"})
+	require.ErrorContains(t, err, "--list")
+}
+
+func TestRunCronList(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	stacks := map[string]string{
+		"api": "services:\n  app:\n    image: nginx\n",
+		"web": "services:\n  scraper:\n    profiles: [jobs]\n    labels:\n      - stackr.cron.schedule=0 1 * * *\n      - stackr.cron.run_on_deploy=true\n",
+		"db":  "services:\n  backup:\n    labels:\n      stackr.cron.schedule: \"\"\n",
+	}
+	for name, compose := range stacks {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, name), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, name, "docker-compose.yml"), []byte(compose), 0o644))
+	}
+	cfg := config.Config{RepoRoot: root, StacksDir: stacksDir}
+
+	var out bytes.Buffer
+	require.NoError(t, runCronList(cfg, nil, false, &out))
+	require.Equal(t, "STACK  SERVICE  SCHEDULE   PROFILE  RUN ON DEPLOY  ENABLED\n"+
+		"db     backup   (manual)   -        false          true\n"+
+		"web    scraper  0 1 * * *  jobs     true           true\n", out.String())
+
+	out.Reset()
+	require.NoError(t, runCronList(cfg, []string{"api"}, false, &out))
+	require.Contains(t, out.String(), "No cron services found")
+
+	out.Reset()
+	require.NoError(t, runCronList(cfg, []string{"db"}, true, &out))
+	require.JSONEq(t, `[{"stack":"db","service":"backup","schedule":"","run_on_deploy":false,"enabled":true}]`, out.String())
+
+	require.ErrorContains(t, runCronList(cfg, []string{"nope"}, false, &out), `stack "nope" not found`)
+}
+
+func TestParseArgsPull(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"pull", "all", "--parallel", "4"})
+	require.NoError(t, err)
+	require.True(t, opts.Pull)
+	require.True(t, opts.All)
+	require.Equal(t, 4, opts.Parallel)
+
+	_, _, _, err = parseArgs([]string{"myapp", "pull", "update"})
+	require.ErrorContains(t, err, "pull cannot be combined")
+}
+
+func TestParseArgsFormat(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"list", "--format", "{{.Name}}"})
+	require.NoError(t, err)
+	require.Equal(t, "{{.Name}}", opts.Format)
+
+	opts, _, _, err = parseArgs([]string{"remote", "status", "myapp", "--format", "{{.Name}} {{.CurrentVersion}}"})
+	require.NoError(t, err)
+	require.Equal(t, "{{.Name}} {{.CurrentVersion}}", opts.Format)
+
+	_, _, _, err = parseArgs([]string{"list", "--format", "{{.Name"})
+	require.ErrorContains(t, err, "invalid --format template")
+
+	_, _, _, err = parseArgs([]string{"list", "--format", "{{.Name}}", "--json"})
+	require.Error(t, err)
+
+	_, _, _, err = parseArgs([]string{"myapp", "update", "--format", "{{.Name}}"})
+	require.Error(t, err)
+
+	_, _, _, err = parseArgs([]string{"list", "--format"})
+	require.Error(t, err)
+}
+
+func TestParseArgsOnlyChanged(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"all", "update", "--only-changed"})
+	require.NoError(t, err)
+	require.True(t, opts.OnlyChanged)
+
+	_, _, _, err = parseArgs([]string{"all", "backup", "--only-changed"})
+	require.Error(t, err)
+}
+
+func TestParseArgsEnv(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "vars-only", "--env", "AWS_PROFILE", "--env", "KUBECONFIG", "--", "env"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"AWS_PROFILE", "KUBECONFIG"}, opts.EnvPassthrough)
+
+	for _, args := range [][]string{
+		{"myapp", "update", "--env"},
+		{"myapp", "update", "--env", "KEY=value"},
+	} {
+		_, _, _, err := parseArgs(args)
+		require.Error(t, err, "args %v", args)
+	}
+}
+
+func TestParseArgsRestore(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "restore", "--from", "20240102_030405", "--yes"})
+	require.NoError(t, err)
+	require.True(t, opts.Restore)
+	require.True(t, opts.Yes)
+	require.Equal(t, "20240102_030405", opts.RestoreFrom)
+	require.Equal(t, []string{"myapp"}, opts.Stacks)
+
+	for _, args := range [][]string{
+		{"myapp", "restore", "--from"},
+		{"myapp", "backup", "--from", "20240102_030405"},
+		{"myapp", "backup", "restore"},
+		{"all", "restore"},
+		{"myapp", "other", "restore"},
+	} {
+		_, _, _, err := parseArgs(args)
+		require.Error(t, err, "args %v", args)
+	}
+}
+
+func TestParseArgsTagFromGit(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "update", "--tag-from-git", "--tag-digest"})
+	require.NoError(t, err)
+	require.True(t, opts.TagFromGit)
+	require.True(t, opts.TagDigest)
+
+	for _, args := range [][]string{
+		{"myapp", "update", "--tag-from-git", "--tag", "v1.0.0"},
+		{"myapp", "tear-down", "--tag-from-git"},
+	} {
+		_, _, _, err := parseArgs(args)
+		require.Error(t, err, "args %v", args)
+	}
+}
+
+func TestParseArgsPullFlags(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "update", "--no-pull", "--force-recreate"})
+	require.NoError(t, err)
+	require.True(t, opts.NoPull)
+	require.True(t, opts.ForceRecreate)
+
+	for _, args := range [][]string{
+		{"myapp", "--no-pull"},
+		{"myapp", "tear-down", "--force-recreate"},
+	} {
+		_, _, _, err := parseArgs(args)
+		require.Error(t, err, "args %v", args)
+	}
+}
+
+func TestParseArgsContinueOnError(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"all", "update", "--continue-on-error"})
+	require.NoError(t, err)
+	require.True(t, opts.ContinueOnError)
+	require.True(t, opts.All)
+}
+
+func TestParseArgsPrintEnvDiff(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "update", "--tag", "v1.0.3", "--print-env-diff"})
+	require.NoError(t, err)
+	require.True(t, opts.PrintEnvDiff)
+
+	_, _, _, err = parseArgs([]string{"myapp", "update", "--print-env-diff"})
+	require.ErrorContains(t, err, "--print-env-diff requires --tag or --tag-from-git")
+}
+
+func TestParseArgsComposeFile(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "update", "--compose-file", "compose.prod.yml"})
+	require.NoError(t, err)
+	require.Equal(t, "compose.prod.yml", opts.ComposeFile)
+
+	_, _, _, err = parseArgs([]string{"myapp", "update", "--compose-file"})
+	require.ErrorContains(t, err, "--compose-file requires a file name")
+	_, _, _, err = parseArgs([]string{"myapp", "update", "--compose-file", "/etc/compose.yml"})
+	require.ErrorContains(t, err, "must be relative")
+}
+
+func TestParseArgsEvents(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "events"})
+	require.NoError(t, err)
+	require.True(t, opts.Events)
+	require.Equal(t, []string{"myapp"}, opts.Stacks)
+
+	_, _, _, err = parseArgs([]string{"all", "events"})
+	require.ErrorContains(t, err, "events requires exactly one stack")
+}
+
+func TestParseArgsSelfUpdate(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"self-update"})
+	require.NoError(t, err)
+	require.True(t, opts.SelfUpdate)
+}
+
+func TestRunListMissingStacksDir(t *testing.T) {
+	cfg := config.Config{StacksDir: filepath.Join(t.TempDir(), "missing")}
+	require.Error(t, runList(cfg, false, "", &bytes.Buffer{}))
+}
+
+func TestParseArgsParallel(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"all", "update", "--parallel", "4"})
+	require.NoError(t, err)
+	require.Equal(t, 4, opts.Parallel)
+
+	for _, args := range [][]string{
+		{"all", "update", "--parallel"},
+		{"all", "update", "--parallel", "0"},
+		{"all", "update", "--parallel", "many"},
+	} {
+		_, _, _, err := parseArgs(args)
+		require.Error(t, err, "args: %v", args)
+	}
+}
+
+func TestParseArgsLogs(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "logs", "web", "db", "-f", "--tail", "100"})
+	require.NoError(t, err)
+	require.True(t, opts.Logs)
+	require.Equal(t, []string{"myapp"}, opts.Stacks)
+	require.Equal(t, []string{"web", "db"}, opts.LogServices)
+	require.True(t, opts.LogsFollow)
+	require.Equal(t, "100", opts.LogsTail)
+
+	for _, args := range [][]string{
+		{"myapp", "--follow"},
+		{"myapp", "logs", "--tail"},
+		{"all", "logs", "--follow"},
+	} {
+		_, _, _, err := parseArgs(args)
+		require.Error(t, err, "args: %v", args)
+	}
+}
+
+func TestParseArgsProfile(t *testing.T) {
+	opts, _, _, err := parseArgs([]string{"myapp", "update", "--profile", "prod"})
+	require.NoError(t, err)
+	require.Equal(t, "prod", opts.Profile)
+
+	_, _, _, err = parseArgs([]string{"myapp", "update", "--profile"})
+	require.Error(t, err)
+}
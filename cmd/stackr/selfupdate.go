@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// defaultReleaseURL serves the assets of the latest GitHub release.
+const defaultReleaseURL = "https://github.com/jamestiberiuskirk/stackr/releases/latest/download"
+
+// checksumsAsset is the release asset listing the sha256 of every binary,
+// in sha256sum format.
+const checksumsAsset = "checksums.txt"
+
+var selfUpdateClient = &http.Client{Timeout: 5 * time.Minute}
+
+// selfUpdateBaseURL returns the release download URL, which
+// STACKR_RELEASE_URL overrides (mirrors, tests).
+func selfUpdateBaseURL() string {
+	if url := strings.TrimSpace(os.Getenv("STACKR_RELEASE_URL")); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return defaultReleaseURL
+}
+
+// runSelfUpdate replaces the running binary with the latest release for this
+// OS/arch.
+func runSelfUpdate(ctx context.Context, baseURL string, w io.Writer) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the stackr binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to locate the stackr binary: %w", err)
+	}
+	return selfUpdate(ctx, baseURL, exe, w)
+}
+
+// selfUpdate downloads the release binary for this OS/arch from baseURL,
+// checks it against the published checksums and renames it over exe. The
+// download lands next to exe so the rename is atomic.
+func selfUpdate(ctx context.Context, baseURL, exe string, w io.Writer) error {
+	asset := fmt.Sprintf("stackr-%s-%s", runtime.GOOS, runtime.GOARCH)
+	want, err := releaseChecksum(ctx, baseURL, asset)
+	if err != nil {
+		return err
+	}
+
+	if current, err := fileSHA256(exe); err == nil && current == want {
+		_, _ = fmt.Fprintf(w, "stackr is already up to date (%s)\n", Version)
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".stackr-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create download file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	hash := sha256.New()
+	err = download(ctx, baseURL+"/"+asset, io.MultiWriter(tmp, hash))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", asset, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset, want, got)
+	}
+
+	mode := os.FileMode(0o755)
+	if info, err := os.Stat(exe); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Updated %s to the latest release (sha256 %s)\n", exe, want)
+	return nil
+}
+
+// releaseChecksum returns the sha256 the release's checksums file lists for
+// asset.
+func releaseChecksum(ctx context.Context, baseURL, asset string) (string, error) {
+	var buf strings.Builder
+	if err := download(ctx, baseURL+"/"+checksumsAsset, &buf); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks binary-mode entries with a leading "*"
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s does not list %s", checksumsAsset, asset)
+}
+
+func download(ctx context.Context, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := selfUpdateClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
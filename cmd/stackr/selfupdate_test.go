@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// releaseServer serves a fake release with binary as this platform's asset
+// and checksum listed for it in checksums.txt.
+func releaseServer(t *testing.T, binary []byte, checksum string) *httptest.Server {
+	t.Helper()
+	asset := fmt.Sprintf("stackr-%s-%s", runtime.GOOS, runtime.GOARCH)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "0000  stackr-plan9-mips\n%s  %s\n", checksum, asset)
+	})
+	mux.HandleFunc("/"+asset, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSelfUpdate(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "stackr")
+	require.NoError(t, os.WriteFile(exe, []byte("old binary"), 0o750))
+
+	newBinary := []byte("new binary")
+	srv := releaseServer(t, newBinary, sha256Hex(newBinary))
+
+	var out bytes.Buffer
+	require.NoError(t, selfUpdate(t.Context(), srv.URL, exe, &out))
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	require.Equal(t, newBinary, data)
+	info, err := os.Stat(exe)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+	require.Contains(t, out.String(), "Updated "+exe)
+
+	// Running again finds nothing to do
+	out.Reset()
+	require.NoError(t, selfUpdate(t.Context(), srv.URL, exe, &out))
+	require.Contains(t, out.String(), "already up to date")
+
+	// Only the binary is left in its directory
+	entries, err := os.ReadDir(filepath.Dir(exe))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestSelfUpdateChecksumMismatch(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "stackr")
+	require.NoError(t, os.WriteFile(exe, []byte("old binary"), 0o755))
+
+	srv := releaseServer(t, []byte("tampered binary"), sha256Hex([]byte("new binary")))
+
+	err := selfUpdate(t.Context(), srv.URL, exe, &bytes.Buffer{})
+	require.ErrorContains(t, err, "checksum mismatch")
+	data, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	require.Equal(t, "old binary", string(data))
+	entries, err := os.ReadDir(filepath.Dir(exe))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestSelfUpdateAssetNotListed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "0000  stackr-plan9-mips")
+	}))
+	defer srv.Close()
+
+	_, err := releaseChecksum(t.Context(), srv.URL, "stackr-linux-amd64")
+	require.ErrorContains(t, err, "checksums.txt does not list stackr-linux-amd64")
+}
+
+func TestSelfUpdateBaseURL(t *testing.T) {
+	t.Setenv("STACKR_RELEASE_URL", "")
+	require.Equal(t, defaultReleaseURL, selfUpdateBaseURL())
+	t.Setenv("STACKR_RELEASE_URL", "http://mirror.local/stackr/")
+	require.Equal(t, "http://mirror.local/stackr", selfUpdateBaseURL())
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,15 +13,28 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/jamestiberiuskirk/stackr/internal/config"
 	"github.com/jamestiberiuskirk/stackr/internal/cronjobs"
 	"github.com/jamestiberiuskirk/stackr/internal/httpapi"
+	"github.com/jamestiberiuskirk/stackr/internal/metrics"
 	"github.com/jamestiberiuskirk/stackr/internal/removal"
 	"github.com/jamestiberiuskirk/stackr/internal/runner"
 	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
+	"github.com/jamestiberiuskirk/stackr/internal/stacklock"
 	"github.com/jamestiberiuskirk/stackr/internal/watch"
 )
 
+var (
+	// Version is set at build time via -ldflags
+	Version = "dev"
+	// Commit is set at build time via -ldflags
+	Commit = "unknown"
+	// Date is set at build time via -ldflags
+	Date = "unknown"
+)
+
 const (
 	shutdownTimeout = 30 * time.Second
 
@@ -28,50 +42,72 @@ const (
 )
 
 func main() {
-	// we should slog +context logging across the entire server here
-	log.SetFlags(log.LstdFlags | log.Lmsgprefix)
+	logger, err := newLogger(os.Getenv("STACKR_LOG_FORMAT"), os.Stderr)
+	if err != nil {
+		fatal(slog.Default(), "invalid log format", "error", err)
+	}
+	// Lines logged through the package-level slog and log functions go
+	// through the same handler
+	slog.SetDefault(logger)
 
 	repoRoot := os.Getenv("STACKR_REPO_ROOT")
 	if repoRoot == "" {
 		repoRoot = defaultRepoRoot
 	}
 
-	repoRoot, err := config.ResolveRepoRoot(repoRoot)
+	repoRoot, err = config.ResolveRepoRoot(repoRoot)
 	if err != nil {
-		log.Fatalf("failed to determine repo root: %v", err)
+		fatal(logger, "failed to determine repo root", "error", err)
 	}
 
 	if strings.TrimSpace(os.Getenv("STACKR_REPO_ROOT")) != "" {
-		log.Printf("using repo root override: %s", repoRoot)
+		logger.Info("using repo root override", "repo_root", repoRoot)
 	}
 
 	cfg, err := config.Load(repoRoot)
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		fatal(logger, "failed to load config", "error", err)
 	}
 
+	registry := prometheus.NewRegistry()
+	stackrMetrics := metrics.New(registry, cfg)
+
 	run := runner.New(cfg)
-	handler := httpapi.New(cfg, run)
+	run.SetLogger(logger)
+	run.SetMetrics(stackrMetrics)
 
-	scheduler, err := cronjobs.New(cfg)
+	scheduler, err := cronjobs.NewWithLogger(cfg, logger)
 	if err != nil {
-		log.Fatalf("failed to initialize cron scheduler: %v", err)
+		fatal(logger, "failed to initialize cron scheduler", "error", err)
 	}
+	scheduler.SetMetrics(stackrMetrics)
+
+	// Deploys and cron runs of the same stack wait for each other
+	stackLocks := stacklock.New()
+	run.SetStackLocks(stackLocks)
+	scheduler.SetStackLocks(stackLocks)
+
+	handler := httpapi.New(cfg, run, scheduler, httpapi.BuildInfo{
+		Version: Version,
+		Commit:  Commit,
+		Date:    Date,
+	}, registry, logger)
 
 	if err := scheduler.Start(); err != nil {
-		log.Fatalf("failed to start cron scheduler: %v", err)
+		fatal(logger, "failed to start cron scheduler", "error", err)
 	}
 
 	// Initialize removal handler
 	removalHandler := removal.NewHandler(cfg, removal.HandlerConfig{
 		ContinueOnArchiveError: true,
 		CleanupTimeout:         5 * time.Minute,
+		Logger:                 logger,
 	})
 
 	// Get initial stack list and initialize tracker
 	initialStacks, err := loadStackNames(cfg)
 	if err != nil {
-		log.Printf("warning: failed to load initial stack list: %v", err)
+		logger.Warn("failed to load initial stack list", "error", err)
 	} else {
 		removalHandler.Initialize(initialStacks)
 	}
@@ -82,8 +118,10 @@ func main() {
 	{
 		var watchCtx context.Context
 		watchCtx, watchCancel = context.WithCancel(context.Background())
-		if err := watch.WatchStacks(watchCtx, cfg.StacksDir, func(path string) {
-			log.Printf("stack change detected (%s), checking for changes", path)
+		// The scheduler holds the config /admin/reload last applied
+		watchIgnore := func() []string { return scheduler.Config().WatchIgnore() }
+		if err := watch.WatchStacks(watchCtx, cfg.StacksDir, watchIgnore, func(path string) {
+			logger.Info("stack change detected, checking for changes", "path", path)
 
 			cbCtx, cbCancel := context.WithTimeout(watchCtx, watchCallbackTimeout)
 			defer cbCancel()
@@ -93,28 +131,30 @@ func main() {
 				defer close(done)
 
 				// Load current stack state
-				currentStacks, err := loadStackNames(cfg)
+				current := scheduler.Config()
+				currentStacks, err := loadStackNames(current)
 				if err != nil {
-					log.Printf("failed to load current stacks: %v", err)
+					logger.Error("failed to load current stacks", "error", err)
 					return
 				}
 
 				// Check for removals BEFORE reloading cron (important for cleanup ordering)
+				removalHandler.SetConfig(current)
 				removalHandler.CheckForRemovals(currentStacks)
 
 				// Then reload cron jobs
 				if err := scheduler.Reload(); err != nil {
-					log.Printf("failed to reload cron scheduler: %v", err)
+					logger.Error("failed to reload cron scheduler", "error", err)
 				}
 			}()
 
 			select {
 			case <-done:
 			case <-cbCtx.Done():
-				log.Printf("warning: watcher callback timed out after %v", watchCallbackTimeout)
+				logger.Warn("watcher callback timed out", "timeout", watchCallbackTimeout)
 			}
 		}); err != nil {
-			log.Printf("stack watcher disabled: %v", err)
+			logger.Warn("stack watcher disabled", "error", err)
 			watchCancel()
 			watchCancel = nil
 		}
@@ -129,7 +169,7 @@ func main() {
 		IdleTimeout:       time.Minute,
 	}
 
-	log.Printf("Stackr listening on %s:%s (stacks dir: %s)", cfg.Host, cfg.Port, cfg.StacksDir)
+	logger.Info("Stackr listening", "addr", server.Addr, "stacks_dir", cfg.StacksDir)
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -143,15 +183,16 @@ func main() {
 
 	select {
 	case err := <-errCh:
-		log.Fatalf("server error: %v", err)
+		fatal(logger, "server error", "error", err)
 	case sig := <-sigCh:
-		log.Printf("signal received (%s), shutting down", sig)
+		logger.Info("signal received, shutting down", "signal", sig.String())
 	}
 
 	if watchCancel != nil {
 		watchCancel()
 	}
 
+	// Interrupts running cron jobs and backups instead of leaving them behind
 	if scheduler != nil {
 		scheduler.Stop()
 	}
@@ -160,10 +201,35 @@ func main() {
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("server shutdown error: %v", err)
+		fatal(logger, "server shutdown error", "error", err)
 	}
 
-	log.Printf("server stopped gracefully")
+	// Cancelled async deploys still restore the .env they changed
+	if err := handler.Shutdown(ctx); err != nil {
+		logger.Warn("async deploys did not finish before shutdown", "error", err)
+	}
+
+	logger.Info("server stopped gracefully")
+}
+
+// newLogger returns the server's logger for a STACKR_LOG_FORMAT value: the
+// default text logger when empty or "text", one JSON object per line on w
+// for "json".
+func newLogger(format string, w io.Writer) (*slog.Logger, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return slog.Default(), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	default:
+		return nil, fmt.Errorf("unknown STACKR_LOG_FORMAT %q (want text or json)", format)
+	}
+}
+
+// fatal logs msg as an error and exits.
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
 }
 
 // loadStackNames scans the stacks directory and returns the names of all valid stacks
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger(t *testing.T) {
+	for _, format := range []string{"", "text", " TEXT "} {
+		logger, err := newLogger(format, &bytes.Buffer{})
+		require.NoError(t, err)
+		require.Same(t, slog.Default(), logger)
+	}
+
+	var buf bytes.Buffer
+	logger, err := newLogger("json", &buf)
+	require.NoError(t, err)
+	logger.Info("deployment finished", "stack", "app", "tag", "v2", "status", "succeeded")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "deployment finished", line["msg"])
+	require.Equal(t, "app", line["stack"])
+	require.Equal(t, "v2", line["tag"])
+	require.Equal(t, "succeeded", line["status"])
+
+	_, err = newLogger("xml", &buf)
+	require.ErrorContains(t, err, `unknown STACKR_LOG_FORMAT "xml"`)
+}
@@ -0,0 +1,105 @@
+// Package audit records HTTP deploys in an append-only JSON lines file and
+// reads them back for `stackr history`.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is one line of the deploy audit log.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Stack    string    `json:"stack"`
+	Tag      string    `json:"tag"`
+	Auth     string    `json:"auth"`
+	TokenSHA string    `json:"token_sha256,omitempty"`
+	SourceIP string    `json:"source_ip"`
+	JobID    string    `json:"job_id,omitempty"`
+	Result   string    `json:"result"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Log appends deploy records to a JSON lines file. A nil Log records
+// nothing.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// New returns a Log writing to path, or nil when path is empty (auditing
+// disabled).
+func New(path string) *Log {
+	if path == "" {
+		return nil
+	}
+	return &Log{path: path}
+}
+
+// Record appends entry to the log. It is append-only: existing lines are
+// never rewritten.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the entries in the log at path, oldest first. A missing log
+// has no entries. Blank lines are skipped.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".stackr", "audit.jsonl")
+
+	entries, err := Read(path)
+	require.NoError(t, err)
+	require.Empty(t, entries, "a missing log has no entries")
+
+	log := New(path)
+	first := Entry{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Stack: "web", Tag: "v1.0.0", Auth: "webhook", Result: "succeeded"}
+	second := Entry{Time: time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC), Stack: "web", Tag: "v1.1.0", Auth: "token", Result: "failed", Error: "pull failed"}
+	require.NoError(t, log.Record(first))
+	require.NoError(t, log.Record(second))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	entries, err = Read(path)
+	require.NoError(t, err)
+	require.Equal(t, []Entry{first, second}, entries)
+}
+
+func TestNilLogRecordsNothing(t *testing.T) {
+	require.Nil(t, New(""))
+	var log *Log
+	require.NoError(t, log.Record(Entry{Stack: "web"}))
+}
+
+func TestReadRejectsMalformedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"stack\":\"web\"}\n\nnot json\n"), 0o600))
+
+	_, err := Read(path)
+	require.ErrorContains(t, err, "audit.jsonl:3")
+}
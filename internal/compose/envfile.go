@@ -0,0 +1,55 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFileList holds the paths of a service's env_file entry, which Docker
+// Compose accepts as a string, a list of strings, or a list of
+// {path, required} mappings.
+type EnvFileList []string
+
+// UnmarshalYAML implements custom unmarshalling for Docker Compose env_file formats.
+func (e *EnvFileList) UnmarshalYAML(value *yaml.Node) error {
+	var result []string
+	if value == nil || value.Kind == 0 {
+		*e = result
+		return nil
+	}
+
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if path := strings.TrimSpace(value.Value); path != "" {
+			result = append(result, path)
+		}
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			switch item.Kind {
+			case yaml.ScalarNode:
+				if path := strings.TrimSpace(item.Value); path != "" {
+					result = append(result, path)
+				}
+			case yaml.MappingNode:
+				var entry struct {
+					Path string `yaml:"path"`
+				}
+				if err := item.Decode(&entry); err != nil {
+					return err
+				}
+				if path := strings.TrimSpace(entry.Path); path != "" {
+					result = append(result, path)
+				}
+			default:
+				return fmt.Errorf("unsupported env_file entry: %s", item.ShortTag())
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported env_file format: %s", value.ShortTag())
+	}
+
+	*e = result
+	return nil
+}
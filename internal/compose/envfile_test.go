@@ -0,0 +1,30 @@
+package compose
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEnvFileListFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want EnvFileList
+	}{
+		{"string", `env_file: app.env`, EnvFileList{"app.env"}},
+		{"list", `env_file: [a.env, b.env]`, EnvFileList{"a.env", "b.env"}},
+		{"mappings", "env_file:\n  - path: a.env\n    required: false\n  - b.env\n", EnvFileList{"a.env", "b.env"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var svc struct {
+				EnvFile EnvFileList `yaml:"env_file"`
+			}
+			require.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &svc))
+			require.Equal(t, tt.want, svc.EnvFile)
+		})
+	}
+}
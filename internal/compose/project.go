@@ -0,0 +1,17 @@
+package compose
+
+import (
+	"regexp"
+	"strings"
+)
+
+var projectNameChars = regexp.MustCompile(`[a-z0-9_-]`)
+
+// ProjectName returns the compose project name of a stack: the stack name
+// normalized the way Docker Compose normalizes a directory name. Stackr
+// passes it with -p so a project_directory override does not rename the
+// project.
+func ProjectName(stack string) string {
+	name := strings.Join(projectNameChars.FindAllString(strings.ToLower(stack), -1), "")
+	return strings.TrimLeft(name, "_-")
+}
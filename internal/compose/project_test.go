@@ -0,0 +1,13 @@
+package compose
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectName(t *testing.T) {
+	require.Equal(t, "myapp", ProjectName("myapp"))
+	require.Equal(t, "my-app_2", ProjectName("My-App_2"))
+	require.Equal(t, "webapp", ProjectName("_web.app"))
+}
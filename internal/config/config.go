@@ -3,9 +3,15 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,24 +22,61 @@ type StackConfig struct {
 }
 
 type Config struct {
-	Token        string
-	EnvFile      string
-	Host         string
-	Port         string
-	RepoRoot     string
-	HostRepoRoot string
-	StacksDir    string
-	Global       GlobalConfig
+	Token         string
+	TokenFile     string
+	DeployTokens  map[string][]string
+	WebhookSecret string
+	EnvFile       string
+	Host          string
+	Port          string
+	RepoRoot      string
+	HostRepoRoot  string
+	StacksDir     string
+	Profile       string
+	Global        GlobalConfig
 }
 
 type GlobalConfig struct {
-	Path            string      `yaml:"-"`
-	Stacks          string      `yaml:"stacks_dir"`
-	RemoteStacksDir string      `yaml:"remote_stacks_dir"`
-	Cron            CronConfig  `yaml:"cron"`
-	HTTP            HTTPConfig  `yaml:"http"`
-	Paths           PathsConfig `yaml:"paths"`
-	Env             EnvConfig   `yaml:"env"`
+	Path            string        `yaml:"-"`
+	Stacks          string        `yaml:"stacks_dir"`
+	RemoteStacksDir string        `yaml:"remote_stacks_dir"`
+	Cron            CronConfig    `yaml:"cron"`
+	HTTP            HTTPConfig    `yaml:"http"`
+	Paths           PathsConfig   `yaml:"paths"`
+	Backup          BackupConfig  `yaml:"backup"`
+	Removal         RemovalConfig `yaml:"removal"`
+	Watch           WatchConfig   `yaml:"watch"`
+	Audit           AuditConfig   `yaml:"audit"`
+	Env             EnvConfig     `yaml:"env"`
+	// PullPolicy decides whether update pulls images before bringing a
+	// stack up: always, missing or never (default always)
+	PullPolicy string `yaml:"pull_policy"`
+}
+
+// Image pull policies for pull_policy.
+const (
+	// PullAlways pulls whenever a registry has a newer image.
+	PullAlways = "always"
+	// PullMissing only pulls images that are not on the host.
+	PullMissing = "missing"
+	// PullNever never pulls; update restarts with the local images.
+	PullNever = "never"
+)
+
+// ImagePullPolicy returns the configured pull policy, or PullAlways.
+func (g GlobalConfig) ImagePullPolicy() string {
+	if policy := strings.TrimSpace(g.PullPolicy); policy != "" {
+		return policy
+	}
+	return PullAlways
+}
+
+func validatePullPolicy(policy string) error {
+	switch strings.TrimSpace(policy) {
+	case "", PullAlways, PullMissing, PullNever:
+		return nil
+	}
+	return fmt.Errorf("pull_policy must be %s, %s or %s, got %q", PullAlways, PullMissing, PullNever, policy)
 }
 
 type CronConfig struct {
@@ -41,21 +84,272 @@ type CronConfig struct {
 	EnableFileLogs     bool   `yaml:"enable_file_logs"`
 	LogsDir            string `yaml:"logs_dir"`
 	ContainerRetention int    `yaml:"docker_container_retention"`
+	// MaxConcurrent caps how many cron jobs run at once; jobs over the cap
+	// wait for a free slot (default 0, unlimited)
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// NotifyURL is a webhook that gets a JSON POST when a job fails
+	// (default empty, disabled)
+	NotifyURL string `yaml:"notify_url"`
+	// NotifyOnSuccess also posts successful runs to NotifyURL
+	NotifyOnSuccess bool `yaml:"notify_on_success"`
+}
+
+func (c CronConfig) validate() error {
+	if c.MaxConcurrent < 0 {
+		return fmt.Errorf("cron.max_concurrent must not be negative, got %d", c.MaxConcurrent)
+	}
+	if c.NotifyURL != "" {
+		// The URL often embeds a webhook secret, so it is not echoed
+		u, err := url.Parse(c.NotifyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("cron.notify_url must be an http or https URL")
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML accepts container_retention as an alias of
+// docker_container_retention; the latter wins when both are set.
+func (c *CronConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain CronConfig
+	decoded := plain(*c)
+	if err := value.Decode(&decoded); err != nil {
+		return err
+	}
+
+	var retention struct {
+		Alias   *int `yaml:"container_retention"`
+		Current *int `yaml:"docker_container_retention"`
+	}
+	if err := value.Decode(&retention); err != nil {
+		return err
+	}
+
+	*c = CronConfig(decoded)
+	if retention.Alias != nil && retention.Current == nil {
+		c.ContainerRetention = *retention.Alias
+	}
+	return nil
 }
 
 type HTTPConfig struct {
 	BaseDomain string `yaml:"base_domain"`
+	// MetricsRequireToken makes GET /metrics require the bearer token
+	// (default false, open for Prometheus scrapers)
+	MetricsRequireToken bool `yaml:"metrics_require_token"`
+	// Tokens are extra bearer tokens that may only POST /deploy, each for
+	// the listed stacks ("*" for any); STACKR_TOKEN stays the admin token
+	Tokens map[string][]string `yaml:"tokens"`
+}
+
+func (h HTTPConfig) validate() error {
+	for _, token := range slices.Sorted(maps.Keys(h.Tokens)) {
+		if strings.TrimSpace(token) == "" || strings.ContainsAny(token, " \t\r\n") {
+			return errors.New("http.tokens: tokens must be non-empty and contain no whitespace")
+		}
+		if len(h.Tokens[token]) == 0 {
+			return errors.New("http.tokens: every token needs at least one stack (or \"*\")")
+		}
+		for _, stack := range h.Tokens[token] {
+			if strings.TrimSpace(stack) == "" {
+				return errors.New("http.tokens: stack names must not be empty")
+			}
+		}
+	}
+	return nil
 }
 
 type PathsConfig struct {
 	BackupDir string            `yaml:"backup_dir"`
 	Pools     map[string]string `yaml:"pools"`
 	Custom    map[string]string `yaml:"custom"`
+	// BackupCompress writes each backed up directory as a .tar.gz instead
+	// of a plain copy (same as backup --compress)
+	BackupCompress bool `yaml:"backup_compress"`
+	// BackupRetention is how many backups of each stack to keep; older ones
+	// are pruned after a successful backup (default 0, keep all)
+	BackupRetention int `yaml:"backup_retention"`
+}
+
+// poolNamePattern matches pool names once uppercased; they become part of
+// STACKR_PROV_POOL_<NAME> env var names.
+var poolNamePattern = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
+func (p PathsConfig) validate() error {
+	if p.BackupRetention < 0 {
+		return fmt.Errorf("paths.backup_retention must not be negative, got %d", p.BackupRetention)
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.Pools)) {
+		key := strings.ToUpper(strings.TrimSpace(name))
+		if key == "" {
+			return errors.New("paths.pools contains empty key")
+		}
+		if !poolNamePattern.MatchString(key) {
+			return fmt.Errorf("paths.pools: invalid pool name %q (use only letters, digits and underscores)", name)
+		}
+	}
+	return nil
+}
+
+type BackupConfig struct {
+	// ConfigDirs are the stack subdirectories copied by backups and removal archives
+	ConfigDirs []string `yaml:"config_dirs"`
+	// HeadroomMB is the free space that must remain on the backup filesystem
+	// after a backup (default 100, 0 disables the margin)
+	HeadroomMB *int `yaml:"headroom_mb"`
+	// PreserveOwnership copies the uid/gid of backed up files (best-effort,
+	// needs privileges to chown)
+	PreserveOwnership bool `yaml:"preserve_ownership"`
+}
+
+// DefaultBackupConfigDirs are archived when backup.config_dirs is not set.
+var DefaultBackupConfigDirs = []string{"config", "dashboards", "dynamic"}
+
+// Dirs returns the configured config dirs, or DefaultBackupConfigDirs.
+func (b BackupConfig) Dirs() []string {
+	if len(b.ConfigDirs) == 0 {
+		return DefaultBackupConfigDirs
+	}
+	return b.ConfigDirs
+}
+
+// validate rejects config dirs that are empty or reach outside the stack
+// directory.
+func (b BackupConfig) validate() error {
+	for _, dir := range b.ConfigDirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			return errors.New("backup.config_dirs contains an empty entry")
+		}
+		if !filepath.IsLocal(dir) {
+			return fmt.Errorf("backup.config_dirs: %q must be a path inside the stack directory", dir)
+		}
+	}
+	return nil
+}
+
+// DefaultBackupHeadroomMB is used when backup.headroom_mb is not set.
+const DefaultBackupHeadroomMB = 100
+
+// Headroom returns the configured headroom in bytes.
+func (b BackupConfig) Headroom() int64 {
+	mb := DefaultBackupHeadroomMB
+	if b.HeadroomMB != nil {
+		mb = max(*b.HeadroomMB, 0)
+	}
+	return int64(mb) << 20
+}
+
+type RemovalConfig struct {
+	// CleanupRetries is how often a failed docker command is retried while
+	// cleaning up a removed stack (default 2)
+	CleanupRetries *int `yaml:"cleanup_retries"`
+}
+
+// DefaultCleanupRetries is used when removal.cleanup_retries is not set.
+const DefaultCleanupRetries = 2
+
+// Retries returns the configured number of cleanup retries.
+func (r RemovalConfig) Retries() int {
+	if r.CleanupRetries == nil {
+		return DefaultCleanupRetries
+	}
+	return max(*r.CleanupRetries, 0)
+}
+
+type WatchConfig struct {
+	// DeployCooldown is the minimum time between two watch-triggered deploys
+	// of the same stack, as a Go duration such as "30s" (default 0, disabled)
+	DeployCooldown string `yaml:"deploy_cooldown"`
+	// Ignore lists glob patterns, relative to the stacks dir, of files whose
+	// changes don't reload cron jobs or trigger watch deploys (e.g.
+	// "**/logs/**", "*.db"). A pattern without a slash matches a file or
+	// directory of that name anywhere; "**" matches any number of
+	// directories. Everything under an ignored directory is ignored.
+	Ignore []string `yaml:"ignore"`
+}
+
+// Cooldown returns the parsed deploy cooldown; loadGlobalConfig has already
+// rejected values that do not parse.
+func (w WatchConfig) Cooldown() time.Duration {
+	d, _ := time.ParseDuration(strings.TrimSpace(w.DeployCooldown))
+	return max(d, 0)
+}
+
+func (w WatchConfig) validate() error {
+	if strings.TrimSpace(w.DeployCooldown) != "" {
+		if _, err := time.ParseDuration(strings.TrimSpace(w.DeployCooldown)); err != nil {
+			return fmt.Errorf("watch.deploy_cooldown: %w", err)
+		}
+	}
+	for _, pattern := range w.Ignore {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("watch.ignore contains an empty pattern")
+		}
+		for _, elem := range strings.Split(pattern, "/") {
+			if _, err := path.Match(elem, ""); err != nil {
+				return fmt.Errorf("watch.ignore: invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WatchIgnore returns the watch.ignore patterns plus the remote stacks dir
+// and the cron logs dir when they are inside the stacks dir, so clones and
+// job logs written there don't count as stack changes.
+func (c Config) WatchIgnore() []string {
+	patterns := slices.Clone(c.Global.Watch.Ignore)
+	for _, dir := range []string{c.Global.RemoteStacksDir, c.Global.Cron.LogsDir} {
+		if strings.TrimSpace(dir) == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(c.RepoRoot, dir)
+		}
+		rel, err := filepath.Rel(c.StacksDir, dir)
+		if err != nil || rel == "." || !filepath.IsLocal(rel) {
+			continue
+		}
+		patterns = append(patterns, filepath.ToSlash(rel))
+	}
+	return patterns
+}
+
+type AuditConfig struct {
+	// Log is the JSON lines file HTTP deploys are appended to, relative to
+	// the repo root (default .stackr/audit.jsonl, empty disables it)
+	Log string `yaml:"log"`
+}
+
+// LogPath returns the absolute audit log path, or "" when auditing is off.
+func (a AuditConfig) LogPath(repoRoot string) string {
+	path := strings.TrimSpace(a.Log)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(repoRoot, path)
 }
 
 type EnvConfig struct {
 	Global map[string]string            `yaml:"global"`
 	Stacks map[string]map[string]string `yaml:"stacks"`
+	// Passthrough limits the host env vars forwarded to compose to these
+	// names plus PATH and HOME (default unset, the whole host env)
+	Passthrough []string `yaml:"passthrough"`
+	// Mask adds key patterns (e.g. DATABASE_URL, *_DSN) whose values are
+	// shown as *** in debug and dry-run output, on top of *_TOKEN,
+	// *_PASSWORD and *_SECRET
+	Mask []string `yaml:"mask"`
+}
+
+func (e EnvConfig) validate() error {
+	for _, pattern := range e.Mask {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("env.mask: invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
 }
 
 const defaultGlobalConfig = ".stackr.yaml"
@@ -102,6 +396,20 @@ func loadConfig(repoRoot string, requireToken bool) (Config, error) {
 	globalCfg.Path = globalPath
 
 	token := strings.TrimSpace(os.Getenv("STACKR_TOKEN"))
+	tokenFile := strings.TrimSpace(os.Getenv("STACKR_TOKEN_FILE"))
+	if token == "" && tokenFile != "" {
+		if !filepath.IsAbs(tokenFile) {
+			tokenFile = filepath.Join(repoRoot, tokenFile)
+		}
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read STACKR_TOKEN_FILE: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	} else {
+		// Only persist rotated tokens when the token actually came from the file
+		tokenFile = ""
+	}
 	if token == "" && requireToken {
 		return Config{}, errors.New("STACKR_TOKEN is required")
 	}
@@ -146,15 +454,22 @@ func loadConfig(repoRoot string, requireToken bool) (Config, error) {
 		hostRepoRoot = repoRoot
 	}
 
+	// Active profile, e.g. to pick a remote stack's release ref per environment
+	profile := strings.TrimSpace(os.Getenv("STACKR_PROFILE"))
+
 	return Config{
-		Token:        token,
-		EnvFile:      envFile,
-		Host:         host,
-		Port:         port,
-		RepoRoot:     repoRoot,
-		HostRepoRoot: hostRepoRoot,
-		StacksDir:    stacksDir,
-		Global:       globalCfg,
+		Token:         token,
+		TokenFile:     tokenFile,
+		DeployTokens:  globalCfg.HTTP.Tokens,
+		WebhookSecret: strings.TrimSpace(os.Getenv("STACKR_WEBHOOK_SECRET")),
+		EnvFile:       envFile,
+		Host:          host,
+		Port:          port,
+		RepoRoot:      repoRoot,
+		HostRepoRoot:  hostRepoRoot,
+		StacksDir:     stacksDir,
+		Profile:       profile,
+		Global:        globalCfg,
 	}, nil
 }
 
@@ -163,7 +478,9 @@ func determineRepoRoot() (string, error) {
 	return os.Getwd()
 }
 
-func loadGlobalConfig(repoRoot string) (GlobalConfig, string, error) {
+// GlobalConfigPath returns the .stackr.yaml path for repoRoot, honouring
+// STACKR_CONFIG_FILE.
+func GlobalConfigPath(repoRoot string) string {
 	path := strings.TrimSpace(os.Getenv("STACKR_CONFIG_FILE"))
 	if path == "" {
 		path = defaultGlobalConfig
@@ -171,6 +488,11 @@ func loadGlobalConfig(repoRoot string) (GlobalConfig, string, error) {
 	if !filepath.IsAbs(path) {
 		path = filepath.Join(repoRoot, path)
 	}
+	return path
+}
+
+func loadGlobalConfig(repoRoot string) (GlobalConfig, string, error) {
+	path := GlobalConfigPath(repoRoot)
 
 	cfg := defaultGlobal()
 
@@ -185,6 +507,27 @@ func loadGlobalConfig(repoRoot string) (GlobalConfig, string, error) {
 	if err := yaml.Unmarshal(content, &cfg); err != nil {
 		return GlobalConfig{}, path, fmt.Errorf("failed to parse stackr config %s: %w", path, err)
 	}
+	if err := cfg.Cron.validate(); err != nil {
+		return GlobalConfig{}, path, fmt.Errorf("invalid stackr config %s: %w", path, err)
+	}
+	if err := cfg.Watch.validate(); err != nil {
+		return GlobalConfig{}, path, fmt.Errorf("invalid stackr config %s: %w", path, err)
+	}
+	if err := cfg.Paths.validate(); err != nil {
+		return GlobalConfig{}, path, fmt.Errorf("invalid stackr config %s: %w", path, err)
+	}
+	if err := cfg.Backup.validate(); err != nil {
+		return GlobalConfig{}, path, fmt.Errorf("invalid stackr config %s: %w", path, err)
+	}
+	if err := cfg.Env.validate(); err != nil {
+		return GlobalConfig{}, path, fmt.Errorf("invalid stackr config %s: %w", path, err)
+	}
+	if err := cfg.HTTP.validate(); err != nil {
+		return GlobalConfig{}, path, fmt.Errorf("invalid stackr config %s: %w", path, err)
+	}
+	if err := validatePullPolicy(cfg.PullPolicy); err != nil {
+		return GlobalConfig{}, path, fmt.Errorf("invalid stackr config %s: %w", path, err)
+	}
 
 	return cfg, path, nil
 }
@@ -207,6 +550,9 @@ func defaultGlobal() GlobalConfig {
 			Pools:     map[string]string{},
 			Custom:    map[string]string{},
 		},
+		Audit: AuditConfig{
+			Log: ".stackr/audit.jsonl",
+		},
 		Env: EnvConfig{
 			Global: map[string]string{},
 			Stacks: map[string]map[string]string{},
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -68,3 +69,194 @@ func TestLoad_UsesDefaultsWhenConfigMissing(t *testing.T) {
 	require.Equal("cron", cfg.Global.Cron.DefaultProfile)
 }
 
+func TestLoad_CronRetentionKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   int
+	}{
+		{name: "default", config: "cron:\n  profile: cron\n", want: 5},
+		{name: "current key", config: "cron:\n  docker_container_retention: 10\n", want: 10},
+		{name: "alias", config: "cron:\n  container_retention: 3\n", want: 3},
+		{name: "current key wins", config: "cron:\n  container_retention: 3\n  docker_container_retention: 8\n", want: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(tt.config), 0o644))
+
+			cfg, err := LoadForCLI(repo)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.Global.Cron.ContainerRetention)
+			require.Equal(t, "cron", cfg.Global.Cron.DefaultProfile)
+		})
+	}
+}
+
+func TestLoad_WatchDeployCooldown(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", config: "stacks_dir: stacks\n", want: 0},
+		{name: "set", config: "watch:\n  deploy_cooldown: 90s\n", want: 90 * time.Second},
+		{name: "invalid", config: "watch:\n  deploy_cooldown: soon\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(tt.config), 0o644))
+
+			cfg, err := LoadForCLI(repo)
+			if tt.wantErr {
+				require.ErrorContains(t, err, "watch.deploy_cooldown")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.Global.Watch.Cooldown())
+		})
+	}
+}
+
+func TestLoad_WatchIgnore(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+	config := "watch:\n  ignore: [\"**/logs/**\", \"*.db\"]\nremote_stacks_dir: stacks/.stackr-repos\ncron:\n  logs_dir: logs/cron\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(config), 0o644))
+
+	cfg, err := LoadForCLI(repo)
+	require.NoError(t, err)
+	// The cron logs dir is outside the stacks dir, so it needs no pattern
+	require.Equal(t, []string{"**/logs/**", "*.db", ".stackr-repos"}, cfg.WatchIgnore())
+
+	cfg.Global.Cron.LogsDir = filepath.Join(cfg.StacksDir, "_logs")
+	require.Equal(t, []string{"**/logs/**", "*.db", ".stackr-repos", "_logs"}, cfg.WatchIgnore())
+
+	for config, wantErr := range map[string]string{
+		"watch:\n  ignore: [\"\"]\n":       "watch.ignore contains an empty pattern",
+		"watch:\n  ignore: [\"logs/[\"]\n": `watch.ignore: invalid pattern "logs/["`,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(config), 0o644))
+		_, err := LoadForCLI(repo)
+		require.ErrorContains(t, err, wantErr)
+	}
+}
+
+func TestLoad_PoolNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		pools   string
+		wantErr string
+	}{
+		{name: "valid", pools: "    ssd: /mnt/ssd\n    fast_disk_2: /mnt/fast\n"},
+		{name: "lowercase is uppercased", pools: "    hdd: /mnt/hdd\n"},
+		{name: "space", pools: "    fast disk: /mnt/fast\n", wantErr: `invalid pool name "fast disk"`},
+		{name: "dash", pools: "    fast-disk: /mnt/fast\n", wantErr: `invalid pool name "fast-disk"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+			config := "paths:\n  pools:\n" + tt.pools
+			require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(config), 0o644))
+
+			_, err := LoadForCLI(repo)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestLoad_BackupConfigDirs(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirs    string
+		want    []string
+		wantErr string
+	}{
+		{name: "default", dirs: "", want: DefaultBackupConfigDirs},
+		{name: "custom", dirs: "  config_dirs: [data, certs/live]\n", want: []string{"data", "certs/live"}},
+		{name: "empty entry", dirs: "  config_dirs: [data, \"\"]\n", wantErr: "backup.config_dirs contains an empty entry"},
+		{name: "absolute", dirs: "  config_dirs: [/etc]\n", wantErr: `"/etc" must be a path inside the stack directory`},
+		{name: "parent", dirs: "  config_dirs: [../other]\n", wantErr: `"../other" must be a path inside the stack directory`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+			config := "backup:\n" + tt.dirs
+			require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(config), 0o644))
+
+			cfg, err := LoadForCLI(repo)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.Global.Backup.Dirs())
+		})
+	}
+}
+
+func TestLoad_EnvMask(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte("env:\n  mask:\n    - DATABASE_URL\n    - \"*_DSN\"\n"), 0o644))
+
+	cfg, err := LoadForCLI(repo)
+	require.NoError(t, err)
+	require.Equal(t, []string{"DATABASE_URL", "*_DSN"}, cfg.Global.Env.Mask)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte("env:\n  mask:\n    - \"[BAD\"\n"), 0o644))
+	_, err = LoadForCLI(repo)
+	require.ErrorContains(t, err, "env.mask")
+}
+
+func TestLoad_DeployTokens(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte("http:\n  tokens:\n    web-ci: [web, web-worker]\n    ops-ci: [\"*\"]\n"), 0o644))
+
+	cfg, err := LoadForCLI(repo)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"web-ci": {"web", "web-worker"}, "ops-ci": {"*"}}, cfg.DeployTokens)
+
+	for _, bad := range []string{
+		"http:\n  tokens:\n    web-ci: []\n",
+		"http:\n  tokens:\n    \"web ci\": [web]\n",
+		"http:\n  tokens:\n    web-ci: [\"\"]\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(bad), 0o644))
+		_, err = LoadForCLI(repo)
+		require.ErrorContains(t, err, "http.tokens", bad)
+	}
+}
+
+func TestLoad_PullPolicy(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+
+	cfg, err := LoadForCLI(repo)
+	require.NoError(t, err)
+	require.Equal(t, PullAlways, cfg.Global.ImagePullPolicy())
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte("pull_policy: missing\n"), 0o644))
+	cfg, err = LoadForCLI(repo)
+	require.NoError(t, err)
+	require.Equal(t, PullMissing, cfg.Global.ImagePullPolicy())
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte("pull_policy: sometimes\n"), 0o644))
+	_, err = LoadForCLI(repo)
+	require.ErrorContains(t, err, "pull_policy must be always, missing or never")
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyRename describes a deprecated .stackr.yaml key and its replacement.
+type keyRename struct {
+	section string // Parent mapping key, "" for top level
+	from    string
+	to      string
+}
+
+// deprecatedKeys lists every key rename upgrade-config knows how to apply.
+var deprecatedKeys = []keyRename{
+	{section: "cron", from: "container_retention", to: "docker_container_retention"},
+}
+
+// UpgradeConfig rewrites deprecated keys in a .stackr.yaml document to their
+// current names, keeping comments. It returns the new document and a
+// description of each change; with no changes the input is returned as is.
+func UpgradeConfig(content []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return content, nil, nil
+	}
+	root := doc.Content[0]
+
+	var changes []string
+	for _, rename := range deprecatedKeys {
+		parent := root
+		if rename.section != "" {
+			parent = mappingValue(root, rename.section)
+			if parent == nil || parent.Kind != yaml.MappingNode {
+				continue
+			}
+		}
+
+		oldKey := mappingKey(parent, rename.from)
+		if oldKey == nil {
+			continue
+		}
+		name := qualifiedKey(rename.section, rename.from)
+		newName := qualifiedKey(rename.section, rename.to)
+		if mappingKey(parent, rename.to) != nil {
+			removeMappingKey(parent, rename.from)
+			changes = append(changes, fmt.Sprintf("removed %s (superseded by %s)", name, newName))
+			continue
+		}
+		oldKey.Value = rename.to
+		changes = append(changes, fmt.Sprintf("renamed %s to %s", name, newName))
+	}
+
+	if len(changes) == 0 {
+		return content, nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), changes, nil
+}
+
+// UpgradeConfigFile applies UpgradeConfig to the file at path in place. A
+// missing file is not an error.
+func UpgradeConfigFile(path string, dryRun bool) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	upgraded, changes, err := UpgradeConfig(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(changes) == 0 || dryRun {
+		return changes, nil
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".stackr.yaml.*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(upgraded); err != nil {
+		_ = tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return changes, nil
+}
+
+func qualifiedKey(section, key string) string {
+	return strings.TrimPrefix(section+"."+key, ".")
+}
+
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgradeConfigRenamesContainerRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".stackr.yaml")
+	original := `# Stackr config
+stacks_dir: stacks
+cron:
+  profile: cron
+  container_retention: 3 # keep a few
+`
+	require.NoError(t, os.WriteFile(path, []byte(original), 0o600))
+
+	changes, err := UpgradeConfigFile(path, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"renamed cron.container_retention to cron.docker_container_retention"}, changes)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	content := string(data)
+	require.Contains(t, content, "docker_container_retention: 3 # keep a few")
+	require.Contains(t, content, "# Stackr config")
+	require.NotContains(t, content, "\n  container_retention")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// The migrated file loads the value under the current key
+	t.Setenv("STACKR_CONFIG_FILE", path)
+	cfg, _, err := loadGlobalConfig(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Equal(t, 3, cfg.Cron.ContainerRetention)
+
+	// Running again is a no-op
+	changes, err = UpgradeConfigFile(path, false)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestUpgradeConfigDropsDeprecatedKeyWhenBothPresent(t *testing.T) {
+	upgraded, changes, err := UpgradeConfig([]byte("cron:\n  container_retention: 3\n  docker_container_retention: 7\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"removed cron.container_retention (superseded by cron.docker_container_retention)"}, changes)
+	require.Equal(t, "cron:\n  docker_container_retention: 7\n", string(upgraded))
+}
+
+func TestUpgradeConfigDryRunLeavesFileUntouched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".stackr.yaml")
+	original := "cron:\n  container_retention: 3\n"
+	require.NoError(t, os.WriteFile(path, []byte(original), 0o644))
+
+	changes, err := UpgradeConfigFile(path, true)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, original, string(data))
+}
@@ -12,16 +12,69 @@ import (
 
 // RemoteStackConfig defines a remote Git repository for a stack
 type RemoteStackConfig struct {
-	URL     string        `yaml:"url"`
-	Branch  string        `yaml:"branch"`
-	Path    string        `yaml:"path"`    // Subdirectory within repo (optional)
-	Release ReleaseConfig `yaml:"release"`
+	URL       string        `yaml:"url"`
+	Branch    string        `yaml:"branch"`
+	Path      string        `yaml:"path"`        // Subdirectory within repo (optional)
+	SSHKey    string        `yaml:"ssh_key"`     // SSH private key path, relative to the repo root (optional)
+	SSHKeyEnv string        `yaml:"ssh_key_env"` // Env var holding the SSH private key path (optional)
+	TokenEnv  string        `yaml:"token_env"`   // Env var holding an HTTPS access token (optional)
+	Release   ReleaseConfig `yaml:"release"`
+	// CloneDepth limits the initial clone history (0 = full clone, default 1)
+	CloneDepth *int `yaml:"clone_depth"`
+}
+
+// Depth returns the configured clone depth, defaulting to a shallow clone of 1.
+func (r *RemoteStackConfig) Depth() int {
+	if r.CloneDepth == nil {
+		return 1
+	}
+	return *r.CloneDepth
 }
 
 // ReleaseConfig defines how to resolve the version to deploy
 type ReleaseConfig struct {
-	Type string `yaml:"type"` // "tag" or "commit"
+	Type string `yaml:"type"` // "tag", "commit" or "branch"
 	Ref  string `yaml:"ref"`  // Can contain ${VAR} references
+	// Refs overrides Ref per profile (e.g. prod: ${PROD_VERSION})
+	Refs map[string]string `yaml:"refs"`
+}
+
+// RefFor returns the ref to deploy for profile, falling back to Ref when the
+// profile has no entry in Refs.
+func (r ReleaseConfig) RefFor(profile string) (string, error) {
+	if ref, ok := r.Refs[profile]; ok && profile != "" {
+		return ref, nil
+	}
+	if r.Ref == "" {
+		if profile == "" {
+			return "", fmt.Errorf("release.refs is set but no profile is active and release.ref is empty")
+		}
+		return "", fmt.Errorf("release.refs has no entry for profile %q and release.ref is empty", profile)
+	}
+	return r.Ref, nil
+}
+
+// validate checks the release type, that a ref is configured and that no
+// refs entry is blank.
+func (r ReleaseConfig) validate() error {
+	if r.Type == "" {
+		return fmt.Errorf("remote_repo.release.type is required (must be 'tag', 'commit' or 'branch')")
+	}
+	if r.Type != "tag" && r.Type != "commit" && r.Type != "branch" {
+		return fmt.Errorf("remote_repo.release.type must be 'tag', 'commit' or 'branch', got: %s", r.Type)
+	}
+	if r.Ref == "" && len(r.Refs) == 0 {
+		return fmt.Errorf("remote_repo.release.ref (or release.refs) is required")
+	}
+	for profile, ref := range r.Refs {
+		if strings.TrimSpace(profile) == "" {
+			return fmt.Errorf("remote_repo.release.refs contains an empty profile name")
+		}
+		if strings.TrimSpace(ref) == "" {
+			return fmt.Errorf("remote_repo.release.refs.%s is empty", profile)
+		}
+	}
+	return nil
 }
 
 // RemoteStackDefinition is the content of stacks/{name}/stackr.yaml
@@ -31,7 +84,7 @@ type RemoteStackDefinition struct {
 
 // DeploymentStackrConfig holds stackr-specific settings from .stackr-deployment.yaml
 type DeploymentStackrConfig struct {
-	Release string `yaml:"release"` // "tag" or "commit" — overrides stackr-repo.yml release.type if set
+	Release string `yaml:"release"` // "tag", "commit" or "branch" — overrides stackr-repo.yml release.type if set
 }
 
 // DeploymentConfig is the content of .stackr-deployment.yaml in remote repo
@@ -62,14 +115,11 @@ func LoadRemoteStackDefinition(stacksDir, stackName string) (*RemoteStackDefinit
 	if def.RemoteRepo.URL == "" {
 		return nil, fmt.Errorf("remote_repo.url is required")
 	}
-	if def.RemoteRepo.Release.Type == "" {
-		return nil, fmt.Errorf("remote_repo.release.type is required (must be 'tag' or 'commit')")
+	if err := def.RemoteRepo.Release.validate(); err != nil {
+		return nil, err
 	}
-	if def.RemoteRepo.Release.Type != "tag" && def.RemoteRepo.Release.Type != "commit" {
-		return nil, fmt.Errorf("remote_repo.release.type must be 'tag' or 'commit', got: %s", def.RemoteRepo.Release.Type)
-	}
-	if def.RemoteRepo.Release.Ref == "" {
-		return nil, fmt.Errorf("remote_repo.release.ref is required")
+	if err := expandRemoteEnv(&def.RemoteRepo); err != nil {
+		return nil, err
 	}
 
 	// Set defaults
@@ -109,6 +159,46 @@ func LoadDeploymentConfig(remoteRepoPath string) (*DeploymentConfig, error) {
 	return &cfg, nil
 }
 
+// expandRemoteEnv resolves ${VAR} references in the url and refs of r from
+// the process environment at load. The url must resolve fully; refs keep any
+// variable that is not set so ResolveVersionRef can take it from the stack's
+// .env later.
+func expandRemoteEnv(r *RemoteStackConfig) error {
+	r.URL = expandProcessEnv(r.URL)
+	if match := envVarPattern.FindStringSubmatch(r.URL); match != nil {
+		return fmt.Errorf("remote_repo.url: environment variable %s is not defined", match[1])
+	}
+	r.Release.Ref = expandProcessEnv(r.Release.Ref)
+	for profile, ref := range r.Release.Refs {
+		r.Release.Refs[profile] = expandProcessEnv(ref)
+	}
+	return nil
+}
+
+// expandProcessEnv replaces each ${VAR} in s that is set in the process
+// environment with its value and leaves the rest as-is.
+func expandProcessEnv(s string) string {
+	return expandEnvFunc(s, os.LookupEnv)
+}
+
+// ExpandEnv replaces each ${VAR} in s that is defined in envVars with its
+// value and leaves the rest as-is.
+func ExpandEnv(s string, envVars map[string]string) string {
+	return expandEnvFunc(s, func(name string) (string, bool) {
+		value, ok := envVars[name]
+		return value, ok
+	})
+}
+
+func expandEnvFunc(s string, lookup func(string) (string, bool)) string {
+	return envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if value, ok := lookup(envVarPattern.FindStringSubmatch(match)[1]); ok {
+			return value
+		}
+		return match
+	})
+}
+
 // ResolveVersionRef resolves ${VAR} references in version ref
 func ResolveVersionRef(ref string, envVars map[string]string) (string, error) {
 	if ref == "" {
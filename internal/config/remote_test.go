@@ -90,11 +90,27 @@ remote_repo:
 remote_repo:
   url: git@github.com:org/app.git
   release:
-    type: branch
+    type: release
     ref: v1.0.0
 `,
 			wantErr: true,
 		},
+		{
+			name:      "branch release type",
+			stackName: "tracking",
+			content: `
+remote_repo:
+  url: git@github.com:org/app.git
+  release:
+    type: branch
+    ref: ${APP_BRANCH}
+`,
+			wantErr: false,
+			validate: func(t *testing.T, def *RemoteStackDefinition) {
+				require.Equal(t, "branch", def.RemoteRepo.Release.Type)
+				require.Equal(t, "${APP_BRANCH}", def.RemoteRepo.Release.Ref)
+			},
+		},
 		{
 			name:      "missing release ref",
 			stackName: "bad4",
@@ -103,6 +119,38 @@ remote_repo:
   url: git@github.com:org/app.git
   release:
     type: tag
+`,
+			wantErr: true,
+		},
+		{
+			name:      "profile refs without scalar ref",
+			stackName: "profiled",
+			content: `
+remote_repo:
+  url: git@github.com:org/app.git
+  release:
+    type: tag
+    refs:
+      prod: ${PROD_VERSION}
+      staging: ${STAGING_VERSION}
+`,
+			wantErr: false,
+			validate: func(t *testing.T, def *RemoteStackDefinition) {
+				require.Empty(t, def.RemoteRepo.Release.Ref)
+				require.Equal(t, map[string]string{"prod": "${PROD_VERSION}", "staging": "${STAGING_VERSION}"}, def.RemoteRepo.Release.Refs)
+			},
+		},
+		{
+			name:      "empty profile ref",
+			stackName: "bad5",
+			content: `
+remote_repo:
+  url: git@github.com:org/app.git
+  release:
+    type: tag
+    ref: v1.0.0
+    refs:
+      prod: ""
 `,
 			wantErr: true,
 		},
@@ -284,3 +332,50 @@ func TestResolveVersionRef(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoteStackConfigDepth(t *testing.T) {
+	require.Equal(t, 1, (&RemoteStackConfig{}).Depth())
+
+	zero := 0
+	require.Equal(t, 0, (&RemoteStackConfig{CloneDepth: &zero}).Depth())
+}
+
+func TestReleaseConfigRefFor(t *testing.T) {
+	release := ReleaseConfig{
+		Ref:  "${MYAPP_VERSION}",
+		Refs: map[string]string{"prod": "${PROD_VERSION}", "staging": "${STAGING_VERSION}"},
+	}
+	envVars := map[string]string{"MYAPP_VERSION": "v1.0.0", "PROD_VERSION": "v1.2.0", "STAGING_VERSION": "v1.3.0-rc1"}
+
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{profile: "prod", want: "v1.2.0"},
+		{profile: "staging", want: "v1.3.0-rc1"},
+		{profile: "dev", want: "v1.0.0"},
+		{profile: "", want: "v1.0.0"},
+	}
+	for _, tt := range tests {
+		ref, err := release.RefFor(tt.profile)
+		require.NoError(t, err)
+		got, err := ResolveVersionRef(ref, envVars)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, got, "profile %q", tt.profile)
+	}
+
+	// Without a scalar fallback, an unlisted profile is an error
+	release.Ref = ""
+	_, err := release.RefFor("dev")
+	require.ErrorContains(t, err, `no entry for profile "dev"`)
+	_, err = release.RefFor("")
+	require.Error(t, err)
+}
+
+func TestExpandEnv(t *testing.T) {
+	envVars := map[string]string{"CRON": "0 3 * * *", "EMPTY": ""}
+	require.Equal(t, "0 3 * * *", ExpandEnv("${CRON}", envVars))
+	require.Equal(t, "a--b", ExpandEnv("a-${EMPTY}-b", envVars))
+	require.Equal(t, "${MISSING} 0 3 * * *", ExpandEnv("${MISSING} ${CRON}", envVars))
+	require.Equal(t, "$CRON", ExpandEnv("$CRON", envVars))
+}
@@ -14,6 +14,16 @@ type StackLocalConfig struct {
 	RemoteRepo   *RemoteStackConfig `yaml:"remote_repo"`
 	ComposeFiles []string           `yaml:"compose_files"`
 	Env          map[string]string  `yaml:"env"`
+	// ProjectDirectory overrides compose's --project-directory. Relative paths
+	// resolve against the primary compose file's directory.
+	ProjectDirectory string `yaml:"project_directory"`
+	// Profiles limits the stack to runs with one of these profiles active;
+	// `all` skips it otherwise. Empty means always included.
+	Profiles []string `yaml:"profiles"`
+
+	// composeFilesSet records whether compose_files was given explicitly;
+	// otherwise local stacks get DetectComposeFiles.
+	composeFilesSet bool
 }
 
 // DefaultStackLocalConfig returns a StackLocalConfig with sensible defaults:
@@ -28,12 +38,20 @@ func DefaultStackLocalConfig() StackLocalConfig {
 // LoadStackLocalConfig loads the per-stack config from stackr/config.yaml inside the
 // given stack directory. If that file does not exist it falls back to the legacy
 // stackr-repo.yml. When neither file exists the returned config uses defaults
-// (local stack, single docker-compose.yml, no env overrides).
+// (local stack, no env overrides). Local stacks that don't list compose_files
+// get the files found by DetectComposeFiles.
 func LoadStackLocalConfig(stackDir string) (*StackLocalConfig, error) {
 	// Try new path first: stackr/config.yaml
 	newPath := filepath.Join(stackDir, "stackr", "config.yaml")
 	if data, err := os.ReadFile(newPath); err == nil {
-		return parseStackLocalConfig(data, newPath)
+		cfg, err := parseStackLocalConfig(data, newPath)
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.IsRemote() && !cfg.composeFilesSet {
+			cfg.ComposeFiles = DetectComposeFiles(stackDir)
+		}
+		return cfg, nil
 	}
 
 	// Fallback to legacy stackr-repo.yml
@@ -44,9 +62,46 @@ func LoadStackLocalConfig(stackDir string) (*StackLocalConfig, error) {
 
 	// Neither file exists — return defaults (local stack)
 	cfg := DefaultStackLocalConfig()
+	cfg.ComposeFiles = DetectComposeFiles(stackDir)
 	return &cfg, nil
 }
 
+// composeFileNames are the primary compose file names docker compose looks
+// for, in its order of preference, each with its override file.
+var composeFileNames = []struct{ base, override string }{
+	{"docker-compose.yml", "docker-compose.override.yml"},
+	{"docker-compose.yaml", "docker-compose.override.yaml"},
+	{"compose.yaml", "compose.override.yaml"},
+	{"compose.yml", "compose.override.yml"},
+}
+
+// ComposeFileNames returns the primary compose file names looked for in a
+// stack directory without compose_files, in order of preference.
+func ComposeFileNames() []string {
+	names := make([]string, len(composeFileNames))
+	for i, n := range composeFileNames {
+		names[i] = n.base
+	}
+	return names
+}
+
+// DetectComposeFiles returns the compose files of a stack that does not list
+// compose_files: the first primary file found in stackDir plus its override
+// file when present. It falls back to docker-compose.yml.
+func DetectComposeFiles(stackDir string) []string {
+	for _, names := range composeFileNames {
+		if _, err := os.Stat(filepath.Join(stackDir, names.base)); err != nil {
+			continue
+		}
+		files := []string{names.base}
+		if _, err := os.Stat(filepath.Join(stackDir, names.override)); err == nil {
+			files = append(files, names.override)
+		}
+		return files
+	}
+	return []string{"docker-compose.yml"}
+}
+
 func parseStackLocalConfig(data []byte, path string) (*StackLocalConfig, error) {
 	var cfg StackLocalConfig
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
@@ -60,7 +115,8 @@ func parseStackLocalConfig(data []byte, path string) (*StackLocalConfig, error)
 		applyRemoteDefaults(cfg.RemoteRepo)
 	}
 
-	if len(cfg.ComposeFiles) == 0 {
+	cfg.composeFilesSet = len(cfg.ComposeFiles) > 0
+	if !cfg.composeFilesSet {
 		cfg.ComposeFiles = []string{"docker-compose.yml"}
 	}
 	if cfg.Env == nil {
@@ -92,16 +148,16 @@ func validateRemoteRepo(r *RemoteStackConfig) error {
 	if r.URL == "" {
 		return fmt.Errorf("remote_repo.url is required")
 	}
-	if r.Release.Type == "" {
-		return fmt.Errorf("remote_repo.release.type is required (must be 'tag' or 'commit')")
+	if err := r.Release.validate(); err != nil {
+		return err
 	}
-	if r.Release.Type != "tag" && r.Release.Type != "commit" {
-		return fmt.Errorf("remote_repo.release.type must be 'tag' or 'commit', got: %s", r.Release.Type)
+	if r.SSHKey != "" && r.SSHKeyEnv != "" {
+		return fmt.Errorf("remote_repo.ssh_key and remote_repo.ssh_key_env cannot both be set")
 	}
-	if r.Release.Ref == "" {
-		return fmt.Errorf("remote_repo.release.ref is required")
+	if r.CloneDepth != nil && *r.CloneDepth < 0 {
+		return fmt.Errorf("remote_repo.clone_depth must be 0 (full clone) or positive, got: %d", *r.CloneDepth)
 	}
-	return nil
+	return expandRemoteEnv(r)
 }
 
 func applyRemoteDefaults(r *RemoteStackConfig) {
@@ -182,6 +182,66 @@ remote_repo:
 	require.Contains(t, err.Error(), "remote_repo.url is required")
 }
 
+func TestLoadStackLocalConfig_RemoteSSHKeyConflict(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "stackr"), 0o755))
+
+	content := `
+remote_repo:
+  url: git@github.com:org/app.git
+  ssh_key: keys/app
+  ssh_key_env: APP_DEPLOY_KEY
+  release:
+    type: tag
+    ref: v1.0.0
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stackr", "config.yaml"), []byte(content), 0o644))
+
+	_, err := LoadStackLocalConfig(dir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot both be set")
+}
+
+func TestLoadStackLocalConfig_ExpandsProcessEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "stackr"), 0o755))
+	t.Setenv("REPO_URL", "git@github.com:org/app.git")
+	t.Setenv("APP_CHANNEL", "stable")
+
+	content := `
+remote_repo:
+  url: ${REPO_URL}
+  release:
+    type: tag
+    ref: ${APP_CHANNEL}-${APP_VERSION}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stackr", "config.yaml"), []byte(content), 0o644))
+
+	cfg, err := LoadStackLocalConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, "git@github.com:org/app.git", cfg.RemoteRepo.URL)
+	// Unset vars stay for ResolveVersionRef to take from .env
+	require.Equal(t, "stable-${APP_VERSION}", cfg.RemoteRepo.Release.Ref)
+}
+
+func TestLoadStackLocalConfig_RemoteURLUndefinedVar(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "stackr"), 0o755))
+
+	content := `
+remote_repo:
+  url: ${STACKR_TEST_UNSET_REPO_URL}
+  release:
+    type: tag
+    ref: v1.0.0
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stackr", "config.yaml"), []byte(content), 0o644))
+
+	_, err := LoadStackLocalConfig(dir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "STACKR_TEST_UNSET_REPO_URL is not defined")
+}
+
 func TestDefaultStackLocalConfig(t *testing.T) {
 	cfg := DefaultStackLocalConfig()
 	require.Nil(t, cfg.RemoteRepo)
@@ -189,3 +249,37 @@ func TestDefaultStackLocalConfig(t *testing.T) {
 	require.NotNil(t, cfg.Env)
 	require.Empty(t, cfg.Env)
 }
+
+func TestLoadStackLocalConfig_DetectsComposeFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []string
+		config string
+		want   []string
+	}{
+		{name: "base only", files: []string{"docker-compose.yml"}, want: []string{"docker-compose.yml"}},
+		{name: "base and override", files: []string{"docker-compose.yml", "docker-compose.override.yml"}, want: []string{"docker-compose.yml", "docker-compose.override.yml"}},
+		{name: "compose.yaml", files: []string{"compose.yaml", "compose.override.yaml"}, want: []string{"compose.yaml", "compose.override.yaml"}},
+		{name: "docker-compose.yml preferred", files: []string{"compose.yaml", "docker-compose.yml"}, want: []string{"docker-compose.yml"}},
+		{name: "none", want: []string{"docker-compose.yml"}},
+		{name: "config without compose_files", files: []string{"docker-compose.yml", "docker-compose.override.yml"}, config: "env:\n  A: b\n", want: []string{"docker-compose.yml", "docker-compose.override.yml"}},
+		{name: "explicit compose_files win", files: []string{"docker-compose.yml", "docker-compose.override.yml"}, config: "compose_files:\n  - docker-compose.yml\n", want: []string{"docker-compose.yml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, f), []byte("services: {}\n"), 0o644))
+			}
+			if tt.config != "" {
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, "stackr"), 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "stackr", "config.yaml"), []byte(tt.config), 0o644))
+			}
+
+			cfg, err := LoadStackLocalConfig(dir)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cfg.ComposeFiles)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package cronjobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	cron "github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/runner"
+	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
+)
+
+const backupScheduleLabel = "stackr.backup.schedule"
+
+// backupJob backs up a whole stack on the schedule declared by any of its
+// services via backupScheduleLabel.
+type backupJob struct {
+	Stack    string
+	Schedule string
+}
+
+func discoverBackupJobs(cfg config.Config, logger *slog.Logger) ([]backupJob, error) {
+	stacks, err := stackcmd.DiscoverStacks(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover stacks: %w", err)
+	}
+
+	var jobs []backupJob
+	for _, stack := range stacks {
+		composePath := stack.PrimaryComposePath()
+		if composePath == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(composePath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", composePath, err)
+		}
+
+		var parsed composeFile
+		if err := yaml.Unmarshal(content, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", composePath, err)
+		}
+
+		var schedules []string
+		for _, service := range parsed.Services {
+			if schedule := strings.TrimSpace(service.Labels[backupScheduleLabel]); schedule != "" && !slices.Contains(schedules, schedule) {
+				schedules = append(schedules, schedule)
+			}
+		}
+
+		switch len(schedules) {
+		case 0:
+			continue
+		case 1:
+			jobs = append(jobs, backupJob{Stack: stack.Name, Schedule: schedules[0]})
+		default:
+			slices.Sort(schedules)
+			logger.Warn("conflicting backup schedules, not scheduling backups", "stack", stack.Name, "label", backupScheduleLabel, "values", schedules)
+		}
+	}
+
+	return jobs, nil
+}
+
+// scheduleBackupsLocked registers the backup jobs with c. Each stack's backup
+// is validated with a dry run first; stacks that fail it are not scheduled.
+func (s *Scheduler) scheduleBackupsLocked(c *cron.Cron) error {
+	for _, job := range s.backups {
+		jobCfg := job
+
+		if _, err := scheduleParser.Parse(jobCfg.Schedule); err != nil {
+			return fmt.Errorf("invalid backup schedule for stack=%s: %w", jobCfg.Stack, err)
+		}
+
+		if err := s.runBackup(jobCfg, true); err != nil {
+			s.log().Warn("scheduled backup validation failed, not scheduling", "stack", jobCfg.Stack, "error", err)
+			continue
+		}
+
+		if _, err := c.AddFunc(jobCfg.Schedule, func() {
+			s.log().Info("scheduled backup started", "stack", jobCfg.Stack, "status", "started")
+			if err := s.runBackup(jobCfg, false); err != nil {
+				s.log().Error("scheduled backup failed", "stack", jobCfg.Stack, "status", "failed", "error", err)
+				return
+			}
+			s.log().Info("scheduled backup finished", "stack", jobCfg.Stack, "status", "succeeded")
+		}); err != nil {
+			return fmt.Errorf("failed to schedule backup for stack=%s: %w", jobCfg.Stack, err)
+		}
+
+		s.log().Info("scheduled backup", "stack", jobCfg.Stack, "schedule", jobCfg.Schedule)
+	}
+	return nil
+}
+
+// runBackup runs the regular backup path for the job's stack.
+func (s *Scheduler) runBackup(job backupJob, dryRun bool) error {
+	ctx, cancel := context.WithTimeout(s.jobContext(), runner.CommandTimeout)
+	defer cancel()
+
+	manager, err := stackcmd.NewManager(s.Config())
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+	return manager.Run(ctx, stackcmd.Options{
+		Stacks: []string{job.Stack},
+		Backup: true,
+		DryRun: dryRun,
+	})
+}
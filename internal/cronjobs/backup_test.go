@@ -0,0 +1,120 @@
+package cronjobs
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func writeStack(t *testing.T, stacksDir, name, compose string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, name), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, name, "docker-compose.yml"), []byte(compose), 0o644))
+}
+
+func TestDiscoverBackupJobs(t *testing.T) {
+	stacksDir := t.TempDir()
+	writeStack(t, stacksDir, "db", `
+services:
+  postgres:
+    image: postgres
+    labels:
+      - stackr.backup.schedule=@daily
+  exporter:
+    image: exporter
+`)
+	writeStack(t, stacksDir, "web", `
+services:
+  nginx:
+    image: nginx
+`)
+	writeStack(t, stacksDir, "conflict", `
+services:
+  a:
+    labels:
+      stackr.backup.schedule: "@daily"
+  b:
+    labels:
+      stackr.backup.schedule: "@weekly"
+`)
+
+	jobs, err := discoverBackupJobs(config.Config{StacksDir: stacksDir}, slog.Default())
+	require.NoError(t, err)
+	require.Equal(t, []backupJob{{Stack: "db", Schedule: "@daily"}}, jobs)
+}
+
+func TestSchedulerSchedulesLabeledBackups(t *testing.T) {
+	// docker stub for the container cleanup run on start
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	writeStack(t, stacksDir, "db", `
+services:
+  postgres:
+    image: postgres
+    labels:
+      - stackr.backup.schedule=0 3 * * *
+`)
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: stacksDir,
+		Global: config.GlobalConfig{
+			Paths: config.PathsConfig{BackupDir: "./backups"},
+		},
+	}
+
+	s, err := New(cfg)
+	require.NoError(t, err)
+	require.Equal(t, []backupJob{{Stack: "db", Schedule: "0 3 * * *"}}, s.backups)
+
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	// The backup plus the periodic container cleanup
+	require.Len(t, s.cron.Entries(), 2)
+	require.NoDirExists(t, filepath.Join(root, "backups"), "validation must be a dry run")
+}
+
+func TestSchedulerSkipsBackupsFailingValidation(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	writeStack(t, stacksDir, "db", `
+services:
+  postgres:
+    labels:
+      - stackr.backup.schedule=@daily
+`)
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+
+	// A backup dir inside the stacks dir is rejected by the backup path
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: stacksDir,
+		Global: config.GlobalConfig{
+			Paths: config.PathsConfig{BackupDir: "./stacks/backups"},
+		},
+	}
+
+	s, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	require.Len(t, s.cron.Entries(), 1, "only the container cleanup should be scheduled")
+}
@@ -0,0 +1,114 @@
+package cronjobs
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistorySize is how many runs are kept per stack+service.
+	DefaultHistorySize = 20
+	// historyOutputLimit caps the output kept per run, keeping its tail.
+	historyOutputLimit = 4096
+)
+
+// JobRun records one cron job execution.
+type JobRun struct {
+	Stack      string    `json:"stack"`
+	Service    string    `json:"service"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	ExitStatus string    `json:"exit_status"`
+	Output     string    `json:"output,omitempty"`
+}
+
+// JobHistory keeps the last runs of every job in a ring buffer per
+// stack+service. A nil JobHistory records nothing.
+type JobHistory struct {
+	mu   sync.Mutex
+	size int
+	runs map[string]*runRing
+}
+
+// runRing holds up to len(runs) runs; next is where the next one goes.
+type runRing struct {
+	runs []JobRun
+	next int
+	full bool
+}
+
+// NewJobHistory returns a history keeping size runs per job.
+func NewJobHistory(size int) *JobHistory {
+	return &JobHistory{
+		size: max(size, 1),
+		runs: make(map[string]*runRing),
+	}
+}
+
+// Record adds a run, evicting the job's oldest run when its buffer is full.
+func (h *JobHistory) Record(run JobRun) {
+	if h == nil {
+		return
+	}
+	run.Output = truncateOutput(run.Output)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := run.Stack + "/" + run.Service
+	ring, ok := h.runs[key]
+	if !ok {
+		ring = &runRing{runs: make([]JobRun, h.size)}
+		h.runs[key] = ring
+	}
+	ring.runs[ring.next] = run
+	ring.next = (ring.next + 1) % len(ring.runs)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// Runs returns every recorded run, newest first.
+func (h *JobHistory) Runs() []JobRun {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var runs []JobRun
+	for _, ring := range h.runs {
+		if ring.full {
+			runs = append(runs, ring.runs...)
+		} else {
+			runs = append(runs, ring.runs[:ring.next]...)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	return runs
+}
+
+// newJobRun builds the history entry of a finished run.
+func newJobRun(started time.Time, result CronResult) JobRun {
+	return JobRun{
+		Stack:      result.Stack,
+		Service:    result.Service,
+		StartedAt:  started,
+		DurationMS: result.Duration.Milliseconds(),
+		Success:    result.Success,
+		ExitStatus: result.ExitSummary,
+		Output:     result.Output,
+	}
+}
+
+func truncateOutput(output string) string {
+	if len(output) <= historyOutputLimit {
+		return output
+	}
+	return "..." + output[len(output)-historyOutputLimit:]
+}
@@ -0,0 +1,29 @@
+package cronjobs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobHistoryKeepsLastRunsPerJob(t *testing.T) {
+	history := NewJobHistory(2)
+	start := time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		history.Record(JobRun{Stack: "db", Service: "backup", StartedAt: start.Add(time.Duration(i) * time.Hour), ExitStatus: "exit status 0"})
+	}
+	history.Record(JobRun{Stack: "web", Service: "ping", StartedAt: start.Add(90 * time.Minute), Output: strings.Repeat("x", historyOutputLimit+10)})
+
+	runs := history.Runs()
+	require.Len(t, runs, 3, "the oldest backup run is evicted")
+	require.Equal(t, start.Add(2*time.Hour), runs[0].StartedAt)
+	require.Equal(t, "ping", runs[1].Service)
+	require.Equal(t, start.Add(time.Hour), runs[2].StartedAt)
+	require.Len(t, runs[1].Output, historyOutputLimit+3, "output is truncated to its tail")
+
+	var nilHistory *JobHistory
+	nilHistory.Record(JobRun{Stack: "db"})
+	require.Empty(t, nilHistory.Runs())
+}
@@ -0,0 +1,125 @@
+package cronjobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+// notifyAttempts is how many times a notification is posted before giving up.
+const notifyAttempts = 3
+
+// notifyRetryDelay is the wait before the first retry; it doubles after each
+// failed attempt. A var so tests can shorten it.
+var notifyRetryDelay = 2 * time.Second
+
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// notification is the JSON body posted to cron.notify_url. Text and Content
+// repeat the summary in the fields Slack and Discord webhooks display.
+type notification struct {
+	Stack   string `json:"stack"`
+	Service string `json:"service"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+func newNotification(res CronResult) notification {
+	n := notification{
+		Stack:   res.Stack,
+		Service: res.Service,
+		Status:  "succeeded",
+		Output:  truncateOutput(res.Output),
+	}
+	if !res.Success {
+		n.Status = "failed"
+		n.Error = res.ExitSummary
+		if n.Error == "" && res.Err != nil {
+			n.Error = res.Err.Error()
+		}
+	}
+	n.Text = fmt.Sprintf("stackr cron job %s/%s %s", res.Stack, res.Service, n.Status)
+	if n.Error != "" {
+		n.Text += ": " + n.Error
+	}
+	n.Content = n.Text
+	return n
+}
+
+// notify posts a finished run to cron.notify_url in the background: always
+// for failures, and for successes when cron.notify_on_success is set. A
+// notification that can't be delivered is only logged.
+func (s *Scheduler) notify(cfg config.CronConfig, res CronResult) {
+	if cfg.NotifyURL == "" || (res.Success && !cfg.NotifyOnSuccess) {
+		return
+	}
+	n := newNotification(res)
+	s.notifying.Add(1)
+	go func() {
+		defer s.notifying.Done()
+		// Not the jobs context: a job interrupted by Stop is still reported
+		if err := sendNotification(context.Background(), cfg.NotifyURL, n); err != nil {
+			s.log().Warn("failed to notify cron result", "stack", n.Stack, "service", n.Service, "status", n.Status, "error", err)
+		}
+	}()
+}
+
+// sendNotification posts n to target, retrying with backoff on errors and
+// non-2xx responses.
+func sendNotification(ctx context.Context, target string, n notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	delay := notifyRetryDelay
+	var lastErr error
+	for attempt := 1; attempt <= notifyAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		lastErr = postNotification(ctx, target, body)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", notifyAttempts, lastErr)
+}
+
+func postNotification(ctx context.Context, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid notify url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		// Drop the URL from the error, webhook URLs usually carry a secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
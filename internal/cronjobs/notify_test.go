@@ -0,0 +1,114 @@
+package cronjobs
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestCronFailureNotification(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  job:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@daily
+`), 0o644))
+
+	binDir := t.TempDir()
+	failMarker := filepath.Join(binDir, "fail")
+	script := "#!/bin/sh\n" +
+		"case \"$*\" in *\" run \"*) if [ -f \"" + failMarker + "\" ]; then echo 'job crashed' >&2; exit 3; fi ;; esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	oldDelay := notifyRetryDelay
+	notifyRetryDelay = time.Millisecond
+	t.Cleanup(func() { notifyRetryDelay = oldDelay })
+
+	// The webhook fails its first request to exercise the retry
+	var mu sync.Mutex
+	var received []notification
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var n notification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		received = append(received, n)
+	}))
+	defer srv.Close()
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	cfg.Global.Cron.NotifyURL = srv.URL
+	jobs, err := discoverJobs(cfg, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	s := &Scheduler{cfg: cfg, history: NewJobHistory(DefaultHistorySize)}
+
+	// Successful runs are not reported by default
+	require.True(t, s.executeInternal(jobs[0], nil).Success)
+	s.notifying.Wait()
+	require.Zero(t, requests)
+
+	require.NoError(t, os.WriteFile(failMarker, nil, 0o644))
+	require.False(t, s.executeInternal(jobs[0], nil).Success)
+	s.notifying.Wait()
+	require.Equal(t, 2, requests)
+	require.Len(t, received, 1)
+	require.Equal(t, "myapp", received[0].Stack)
+	require.Equal(t, "job", received[0].Service)
+	require.Equal(t, "failed", received[0].Status)
+	require.Equal(t, "exit status 3: job crashed", received[0].Error)
+	require.Contains(t, received[0].Output, "job crashed")
+	require.Equal(t, "stackr cron job myapp/job failed: exit status 3: job crashed", received[0].Text)
+
+	require.NoError(t, os.Remove(failMarker))
+	cfg.Global.Cron.NotifyOnSuccess = true
+	s.cfg = cfg
+	require.True(t, s.executeInternal(jobs[0], nil).Success)
+	s.notifying.Wait()
+	require.Len(t, received, 2)
+	require.Equal(t, "succeeded", received[1].Status)
+	require.Empty(t, received[1].Error)
+}
+
+func TestSendNotificationGivesUp(t *testing.T) {
+	oldDelay := notifyRetryDelay
+	notifyRetryDelay = time.Millisecond
+	t.Cleanup(func() { notifyRetryDelay = oldDelay })
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := sendNotification(t.Context(), srv.URL, notification{Stack: "myapp", Service: "job", Status: "failed"})
+	require.ErrorContains(t, err, "502 Bad Gateway")
+	require.Equal(t, notifyAttempts, requests)
+}
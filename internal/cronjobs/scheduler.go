@@ -2,38 +2,87 @@ package cronjobs
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/joho/godotenv"
 	cron "github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
 
 	"github.com/jamestiberiuskirk/stackr/internal/compose"
 	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/metrics"
 	"github.com/jamestiberiuskirk/stackr/internal/runner"
 	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
+	"github.com/jamestiberiuskirk/stackr/internal/stacklock"
 )
 
 const (
 	scheduleLabel    = "stackr.cron.schedule"
 	runOnDeployLabel = "stackr.cron.run_on_deploy"
+	enabledLabel     = "stackr.cron.enabled"
+	userLabel        = "stackr.cron.user"
+	timezoneLabel    = "stackr.cron.timezone"
+	timeoutLabel     = "stackr.cron.timeout"
+	modeLabel        = "stackr.cron.mode"
 )
 
+// Cron execution modes, set with the stackr.cron.mode label.
+const (
+	// modeRun starts a one-off container with "docker compose run".
+	modeRun = "run"
+	// modeUp starts the service itself with "docker compose up --no-deps",
+	// so it runs exactly as the stack defines it.
+	modeUp = "up"
+)
+
+// scheduleParser parses job and backup schedules: five-field expressions,
+// descriptors such as @every 30s, and six-field expressions whose first
+// field is the second, for jobs that need to run more than once a minute.
+var scheduleParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// userPattern matches the user[:group] forms docker run accepts, by name or id.
+var userPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*(:[A-Za-z0-9_][A-Za-z0-9_.-]*)?$`)
+
 type Scheduler struct {
-	mu   sync.Mutex
-	cron *cron.Cron
-	jobs []cronJob
-	cfg  config.Config
+	mu      sync.Mutex
+	cron    *cron.Cron
+	jobs    []cronJob
+	backups []backupJob
+	// cfg has its own lock because runs read it while Reload holds mu
+	cfgMu   sync.RWMutex
+	cfg     config.Config
+	history *JobHistory
+	// slots limits concurrent runs to cron.max_concurrent; nil is unlimited
+	slots   chan struct{}
+	metrics *metrics.Metrics
+	// stackLocks keeps runs from overlapping deploys of the same stack; runs
+	// of one stack share its lock
+	stackLocks *stacklock.Locks
+	logger     *slog.Logger
+	// notifying tracks notifications still being sent
+	notifying sync.WaitGroup
+
+	// jobsCtx is the parent of every run's context; Stop cancels it to
+	// interrupt running jobs. It has its own lock because runs start while
+	// Stop holds mu waiting for them.
+	ctxMu      sync.Mutex
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
 }
 
 type cronJob struct {
@@ -42,7 +91,44 @@ type cronJob struct {
 	Schedule     string
 	Profile      string
 	RunOnDeploy  bool
+	Enabled      bool
 	ComposeFiles []string
+	ProjectDir   string
+	User         string
+	// Timezone is the IANA zone the schedule runs in; empty means local time.
+	Timezone string
+	// Timeout bounds a run; zero means runner.CommandTimeout.
+	Timeout time.Duration
+	// Mode is modeRun or modeUp; empty means modeRun.
+	Mode string
+}
+
+// timeout returns the job's run timeout, defaulting to runner.CommandTimeout.
+func (j cronJob) timeout() time.Duration {
+	if j.Timeout > 0 {
+		return j.Timeout
+	}
+	return runner.CommandTimeout
+}
+
+// spec returns the schedule passed to cron, prefixed with the job's timezone.
+func (j cronJob) spec() string {
+	if j.Timezone == "" {
+		return j.Schedule
+	}
+	return "CRON_TZ=" + j.Timezone + " " + j.Schedule
+}
+
+// CronResult describes the outcome of one cron job execution.
+type CronResult struct {
+	Stack       string
+	Service     string
+	Success     bool
+	Duration    time.Duration
+	ExitSummary string
+	Err         error
+	// Output is what the job wrote to stdout and stderr
+	Output string
 }
 
 type composeFile struct {
@@ -55,15 +141,58 @@ type composeService struct {
 }
 
 func New(cfg config.Config) (*Scheduler, error) {
-	jobs, err := discoverJobs(cfg)
+	return NewWithLogger(cfg, slog.Default())
+}
+
+// NewWithLogger is New with the scheduler, including job discovery, logging
+// to logger.
+func NewWithLogger(cfg config.Config, logger *slog.Logger) (*Scheduler, error) {
+	jobs, err := discoverJobs(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	backups, err := discoverBackupJobs(cfg, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Scheduler{
-		jobs: jobs,
-		cfg:  cfg,
-	}, nil
+	s := &Scheduler{
+		jobs:    jobs,
+		backups: backups,
+		cfg:     cfg,
+		history: NewJobHistory(DefaultHistorySize),
+		logger:  logger,
+	}
+	if n := cfg.Global.Cron.MaxConcurrent; n > 0 {
+		s.slots = make(chan struct{}, n)
+	}
+	return s, nil
+}
+
+// SetMetrics makes the scheduler count its job runs in m.
+func (s *Scheduler) SetMetrics(m *metrics.Metrics) {
+	if s == nil {
+		return
+	}
+	s.metrics = m
+}
+
+// SetStackLocks makes job runs wait for anything else holding their stack's
+// lock in l, such as a deploy.
+func (s *Scheduler) SetStackLocks(l *stacklock.Locks) {
+	if s == nil {
+		return
+	}
+	s.stackLocks = l
+}
+
+// History returns the recent runs of the scheduler's jobs, newest first.
+func (s *Scheduler) History() []JobRun {
+	if s == nil {
+		return nil
+	}
+	return s.history.Runs()
 }
 
 func (s *Scheduler) Start() error {
@@ -78,6 +207,13 @@ func (s *Scheduler) Start() error {
 		return nil
 	}
 
+	// Runs after a Stop get a fresh context
+	s.ctxMu.Lock()
+	if s.jobsCtx != nil && s.jobsCtx.Err() != nil {
+		s.jobsCtx, s.cancelJobs = nil, nil
+	}
+	s.ctxMu.Unlock()
+
 	return s.startLocked()
 }
 
@@ -85,30 +221,92 @@ func (s *Scheduler) Reload() error {
 	if s == nil {
 		return nil
 	}
+	return s.ReloadConfig(s.Config())
+}
+
+// ReloadConfig rediscovers the jobs with cfg and reschedules them. If the
+// jobs can't be discovered the scheduler keeps its previous config and jobs.
+// cron.max_concurrent only takes effect on a restart.
+func (s *Scheduler) ReloadConfig(cfg config.Config) error {
+	if s == nil {
+		return nil
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	jobs, err := discoverJobs(s.cfg)
+	jobs, err := discoverJobs(cfg, s.log())
 	if err != nil {
 		return err
 	}
 
+	backups, err := discoverBackupJobs(cfg, s.log())
+	if err != nil {
+		return err
+	}
+	// Checked before the running jobs are stopped, so a bad schedule keeps them
+	for _, backup := range backups {
+		if _, err := scheduleParser.Parse(backup.Schedule); err != nil {
+			return fmt.Errorf("invalid backup schedule for stack=%s: %w", backup.Stack, err)
+		}
+	}
+
 	if s.cron != nil {
 		ctx := s.cron.Stop()
 		<-ctx.Done()
 		s.cron = nil
 	}
 
+	s.cfgMu.Lock()
+	s.cfg = cfg
+	s.cfgMu.Unlock()
+
 	s.jobs = jobs
+	s.backups = backups
 	return s.startLocked()
 }
 
+// log returns the scheduler's logger, slog.Default() if it has none.
+func (s *Scheduler) log() *slog.Logger {
+	if s.logger == nil {
+		return slog.Default()
+	}
+	return s.logger
+}
+
+// cronLogger adapts a slog.Logger to the cron library's logger; its
+// informational messages are only logged at debug level.
+type cronLogger struct {
+	logger *slog.Logger
+}
+
+func (l cronLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Debug("cron: "+msg, keysAndValues...)
+}
+
+func (l cronLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.logger.Error("cron: "+msg, append(keysAndValues, "error", err)...)
+}
+
+// Config returns the config the scheduler currently runs with, which
+// ReloadConfig replaces.
+func (s *Scheduler) Config() config.Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
+}
+
 func (s *Scheduler) Stop() {
 	if s == nil {
 		return
 	}
 
+	s.ctxMu.Lock()
+	if s.cancelJobs != nil {
+		s.cancelJobs()
+	}
+	s.ctxMu.Unlock()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -121,107 +319,251 @@ func (s *Scheduler) Stop() {
 	s.cron = nil
 }
 
+// jobContext returns the context runs derive theirs from. It is cancelled
+// by Stop and renewed by the next Start, so runs that begin while the
+// scheduler is stopping are cancelled too.
+func (s *Scheduler) jobContext() context.Context {
+	s.ctxMu.Lock()
+	defer s.ctxMu.Unlock()
+	if s.jobsCtx == nil {
+		s.jobsCtx, s.cancelJobs = context.WithCancel(context.Background())
+	}
+	return s.jobsCtx
+}
+
 func (s *Scheduler) startLocked() error {
-	if len(s.jobs) == 0 {
-		log.Printf("no cron-enabled services detected")
+	if len(s.jobs) == 0 && len(s.backups) == 0 {
+		s.log().Info("no cron-enabled services detected")
 		return nil
 	}
 
-	logger := cron.PrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	c := cron.New(cron.WithParser(parser), cron.WithChain(cron.SkipIfStillRunning(logger)))
+	logger := cronLogger{s.log()}
+	c := cron.New(cron.WithParser(scheduleParser), cron.WithLogger(logger), cron.WithChain(cron.SkipIfStillRunning(logger)))
 
 	for _, job := range s.jobs {
 		jobCfg := job
 
 		// Skip jobs with empty schedule (manual-only)
 		if jobCfg.Schedule == "" {
-			log.Printf("manual-only cron job registered stack=%s service=%s", jobCfg.Stack, jobCfg.Service)
+			s.log().Info("manual-only cron job registered", "stack", jobCfg.Stack, "service", jobCfg.Service)
+			continue
+		}
+
+		// Skip disabled jobs (still available for manual runs)
+		if !jobCfg.Enabled {
+			s.log().Info("cron job disabled via "+enabledLabel, "stack", jobCfg.Stack, "service", jobCfg.Service)
 			continue
 		}
 
-		if _, err := parser.Parse(jobCfg.Schedule); err != nil {
+		if _, err := scheduleParser.Parse(jobCfg.spec()); err != nil {
 			return fmt.Errorf("invalid cron schedule for stack=%s service=%s: %w", jobCfg.Stack, jobCfg.Service, err)
 		}
 
-		if _, err := c.AddFunc(jobCfg.Schedule, func() { s.execute(jobCfg) }); err != nil {
+		if _, err := c.AddFunc(jobCfg.spec(), func() { s.execute(jobCfg) }); err != nil {
 			return fmt.Errorf("failed to schedule cron job for stack=%s service=%s: %w", jobCfg.Stack, jobCfg.Service, err)
 		}
 
-		log.Printf("scheduled cron job stack=%s service=%s schedule=%q", jobCfg.Stack, jobCfg.Service, jobCfg.Schedule)
+		s.log().Info("scheduled cron job", "stack", jobCfg.Stack, "service", jobCfg.Service, "schedule", jobCfg.spec())
 
 		if jobCfg.RunOnDeploy {
 			go func(j cronJob) {
-				log.Printf("run-on-deploy cron job triggered stack=%s service=%s", j.Stack, j.Service)
+				s.log().Info("run-on-deploy cron job triggered", "stack", j.Stack, "service", j.Service)
 				s.execute(j)
 			}(jobCfg)
 		}
 	}
 
+	if err := s.scheduleBackupsLocked(c); err != nil {
+		return err
+	}
+
 	c.Start()
 	s.cron = c
 
 	// Run cleanup immediately on startup
 	go func() {
-		if err := CleanupOldContainers(s.cfg.Global.Cron.ContainerRetention); err != nil {
-			log.Printf("cron container cleanup failed: %v", err)
+		if err := CleanupOldContainers(s.log(), s.Config().Global.Cron.ContainerRetention); err != nil {
+			s.log().Error("cron container cleanup failed", "error", err)
 		}
 	}()
 
 	// Schedule periodic cleanup (every 6 hours)
 	if _, err := c.AddFunc("0 */6 * * *", func() {
-		if err := CleanupOldContainers(s.cfg.Global.Cron.ContainerRetention); err != nil {
-			log.Printf("cron container cleanup failed: %v", err)
+		if err := CleanupOldContainers(s.log(), s.Config().Global.Cron.ContainerRetention); err != nil {
+			s.log().Error("cron container cleanup failed", "error", err)
 		}
 	}); err != nil {
-		log.Printf("failed to schedule cleanup job: %v", err)
+		s.log().Error("failed to schedule cleanup job", "error", err)
 	}
 
-	log.Printf("cron scheduler started with %d job(s)", len(s.jobs))
+	s.log().Info("cron scheduler started", "jobs", len(s.jobs), "backups", len(s.backups))
 	return nil
 }
 
 // ExecuteJobManually finds and executes a specific cron job by stack and service name
 // If customCmd is provided, it overrides the default command from the compose file
 func ExecuteJobManually(cfg config.Config, stack, service string, customCmd []string) error {
-	jobs, err := discoverJobs(cfg)
+	logger := slog.Default()
+	jobs, err := discoverJobs(cfg, logger)
 	if err != nil {
 		return fmt.Errorf("failed to discover jobs: %w", err)
 	}
 
-	// Find the matching job
-	var targetJob *cronJob
-	for _, job := range jobs {
-		if job.Stack == stack && job.Service == service {
-			targetJob = &job
-			break
-		}
-	}
-
+	targetJob := findJob(jobs, stack, service)
 	if targetJob == nil {
 		return fmt.Errorf("cron job not found: stack=%s service=%s (make sure service has stackr.cron.schedule label)", stack, service)
 	}
 
 	// Create a temporary scheduler just to execute this one job
 	s := &Scheduler{
-		cfg: cfg,
+		cfg:    cfg,
+		logger: logger,
 	}
 
 	if len(customCmd) > 0 {
-		log.Printf("manually executing cron job with custom command: stack=%s service=%s cmd=%v", stack, service, customCmd)
+		logger.Info("manually executing cron job with custom command", "stack", stack, "service", service, "cmd", customCmd)
 	} else {
-		log.Printf("manually executing cron job: stack=%s service=%s", stack, service)
+		logger.Info("manually executing cron job", "stack", stack, "service", service)
+	}
+	result := s.executeWithCommand(*targetJob, customCmd)
+	// Let the notification go out before the process exits
+	s.notifying.Wait()
+	if !result.Success {
+		return fmt.Errorf("cron job stack=%s service=%s failed after %s: %s", stack, service, result.Duration.Round(time.Millisecond), result.ExitSummary)
+	}
+	return nil
+}
+
+// JobInfo describes a cron-labeled service, as listed by run-cron --list.
+type JobInfo struct {
+	Stack   string `json:"stack"`
+	Service string `json:"service"`
+	// Schedule is empty for jobs that only run manually or on deploy
+	Schedule    string `json:"schedule"`
+	Timezone    string `json:"timezone,omitempty"`
+	Profile     string `json:"profile,omitempty"`
+	RunOnDeploy bool   `json:"run_on_deploy"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// ListJobs returns the cron jobs of every stack, sorted by stack and service.
+// Services with invalid labels are skipped with a warning, as they are when
+// scheduling.
+func ListJobs(cfg config.Config) ([]JobInfo, error) {
+	jobs, err := discoverJobs(cfg, slog.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover jobs: %w", err)
+	}
+
+	infos := make([]JobInfo, 0, len(jobs))
+	for _, job := range jobs {
+		infos = append(infos, JobInfo{
+			Stack:       job.Stack,
+			Service:     job.Service,
+			Schedule:    job.Schedule,
+			Timezone:    job.Timezone,
+			Profile:     job.Profile,
+			RunOnDeploy: job.RunOnDeploy,
+			Enabled:     job.Enabled,
+		})
+	}
+	slices.SortFunc(infos, func(a, b JobInfo) int {
+		return cmp.Or(cmp.Compare(a.Stack, b.Stack), cmp.Compare(a.Service, b.Service))
+	})
+	return infos, nil
+}
+
+// ErrJobNotFound is returned by RunJob for an unknown stack and service.
+var ErrJobNotFound = errors.New("cron job not found")
+
+// RunJob runs a cron job now, like ExecuteJobManually, and returns the
+// recorded run. If customCmd is provided it overrides the job's command.
+// Disabled jobs can be run too.
+func (s *Scheduler) RunJob(stack, service string, customCmd []string) (JobRun, error) {
+	if s == nil {
+		return JobRun{}, errors.New("cron scheduler is not running")
+	}
+
+	jobs, err := discoverJobs(s.Config(), s.log())
+	if err != nil {
+		return JobRun{}, fmt.Errorf("failed to discover jobs: %w", err)
+	}
+	job := findJob(jobs, stack, service)
+	if job == nil {
+		return JobRun{}, fmt.Errorf("%w: stack=%s service=%s", ErrJobNotFound, stack, service)
+	}
+
+	s.log().Info("running cron job on request", "stack", stack, "service", service)
+	started := time.Now()
+	result := s.executeWithCommand(*job, customCmd)
+	run := newJobRun(started, result)
+	run.Output = truncateOutput(run.Output)
+	return run, nil
+}
+
+// readLabelEnv reads the env file used to resolve ${VAR} in cron labels. A
+// missing file yields an empty map.
+func readLabelEnv(path string) (map[string]string, error) {
+	if path == "" {
+		return map[string]string{}, nil
+	}
+	values, err := godotenv.Read(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// stackLabelEnv returns globalEnv with the optional stacks/<stack>/.env
+// merged over it, like the env a stack's compose files are run with.
+func stackLabelEnv(cfg config.Config, stack string, globalEnv map[string]string) (map[string]string, error) {
+	stackEnv, err := readLabelEnv(filepath.Join(cfg.StacksDir, stack, ".env"))
+	if err != nil {
+		return nil, err
+	}
+	if len(stackEnv) == 0 {
+		return globalEnv, nil
+	}
+	env := maps.Clone(globalEnv)
+	maps.Copy(env, stackEnv)
+	return env, nil
+}
+
+// expandLabels resolves ${VAR} references in label values, the way compose
+// would when it runs the service. Unknown vars are left as-is.
+func expandLabels(labels compose.LabelMap, env map[string]string) compose.LabelMap {
+	expanded := make(compose.LabelMap, len(labels))
+	for k, v := range labels {
+		expanded[k] = config.ExpandEnv(v, env)
+	}
+	return expanded
+}
+
+// findJob returns the job matching stack and service, or nil if none does.
+// Disabled jobs are returned too so they remain manually runnable.
+func findJob(jobs []cronJob, stack, service string) *cronJob {
+	for i := range jobs {
+		if jobs[i].Stack == stack && jobs[i].Service == service {
+			return &jobs[i]
+		}
 	}
-	s.executeWithCommand(*targetJob, customCmd)
 	return nil
 }
 
-func discoverJobs(cfg config.Config) ([]cronJob, error) {
+func discoverJobs(cfg config.Config, logger *slog.Logger) ([]cronJob, error) {
 	stacks, err := stackcmd.DiscoverStacks(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover stacks: %w", err)
 	}
 
+	globalEnv, err := readLabelEnv(cfg.EnvFile)
+	if err != nil {
+		return nil, err
+	}
+
 	var jobs []cronJob
 	for _, stack := range stacks {
 		composePath := stack.PrimaryComposePath()
@@ -242,9 +584,16 @@ func discoverJobs(cfg config.Config) ([]cronJob, error) {
 			return nil, fmt.Errorf("failed to parse %s: %w", composePath, err)
 		}
 
+		env, err := stackLabelEnv(cfg, stack.Name, globalEnv)
+		if err != nil {
+			return nil, err
+		}
+
 		for serviceName, service := range parsed.Services {
+			jobLogger := logger.With("stack", stack.Name, "service", serviceName)
+			labels := expandLabels(service.Labels, env)
 			// Check if service has cron schedule label (value can be empty for manual-only)
-			schedule, hasLabel := service.Labels[scheduleLabel]
+			schedule, hasLabel := labels[scheduleLabel]
 			if !hasLabel {
 				continue
 			}
@@ -256,22 +605,88 @@ func discoverJobs(cfg config.Config) ([]cronJob, error) {
 			}
 
 			runOnDeploy := false
-			if raw := strings.TrimSpace(service.Labels[runOnDeployLabel]); raw != "" {
+			if raw := strings.TrimSpace(labels[runOnDeployLabel]); raw != "" {
 				parsedBool, parseErr := strconv.ParseBool(raw)
 				if parseErr != nil {
-					log.Printf("invalid %s value for stack=%s service=%s: %q", runOnDeployLabel, stack.Name, serviceName, raw)
+					jobLogger.Warn("invalid cron label value, ignoring it", "label", runOnDeployLabel, "value", raw)
 				} else {
 					runOnDeploy = parsedBool
 				}
 			}
 
+			enabled := true
+			if raw := strings.TrimSpace(labels[enabledLabel]); raw != "" {
+				parsedBool, parseErr := strconv.ParseBool(raw)
+				if parseErr != nil {
+					jobLogger.Warn("invalid cron label value, ignoring it", "label", enabledLabel, "value", raw)
+				} else {
+					enabled = parsedBool
+				}
+			}
+
+			// An invalid user must not fall back to running as root
+			user := strings.TrimSpace(labels[userLabel])
+			if user != "" && !userPattern.MatchString(user) {
+				jobLogger.Warn("invalid cron label value (expected uid[:gid]), skipping job", "label", userLabel, "value", user)
+				continue
+			}
+
+			// An invalid timezone only affects this job, which runs in local time
+			timezone := strings.TrimSpace(labels[timezoneLabel])
+			if timezone != "" {
+				if _, err := time.LoadLocation(timezone); err != nil {
+					jobLogger.Warn("invalid cron label value, using local time", "label", timezoneLabel, "value", timezone)
+					timezone = ""
+				}
+			}
+
+			mode := strings.TrimSpace(labels[modeLabel])
+			switch mode {
+			case "", modeRun:
+				mode = modeRun
+			case modeUp:
+				// up runs the service's own user; an explicit one can't be applied
+				if user != "" {
+					jobLogger.Warn(fmt.Sprintf("%s cannot be combined with %s=%s, skipping job", userLabel, modeLabel, modeUp))
+					continue
+				}
+			default:
+				jobLogger.Warn(fmt.Sprintf("invalid cron label value (expected %s or %s), using %s", modeRun, modeUp, modeRun), "label", modeLabel, "value", mode)
+				mode = modeRun
+			}
+
+			// A bad schedule would otherwise stop the whole scheduler from starting
+			if schedule != "" {
+				spec := cronJob{Schedule: schedule, Timezone: timezone}.spec()
+				if _, err := scheduleParser.Parse(spec); err != nil {
+					jobLogger.Warn("invalid cron label value, skipping job", "label", scheduleLabel, "value", schedule, "error", err)
+					continue
+				}
+			}
+
+			var timeout time.Duration
+			if raw := strings.TrimSpace(labels[timeoutLabel]); raw != "" {
+				parsed, parseErr := time.ParseDuration(raw)
+				if parseErr != nil || parsed <= 0 {
+					jobLogger.Warn("invalid cron label value, using the default", "label", timeoutLabel, "value", raw, "default", runner.CommandTimeout)
+				} else {
+					timeout = parsed
+				}
+			}
+
 			jobs = append(jobs, cronJob{
 				Stack:        stack.Name,
 				Service:      serviceName,
 				Schedule:     schedule,
 				Profile:      profile,
 				RunOnDeploy:  runOnDeploy,
+				Enabled:      enabled,
 				ComposeFiles: stack.ComposePaths,
+				ProjectDir:   stack.ProjectDir,
+				User:         user,
+				Timezone:     timezone,
+				Timeout:      timeout,
+				Mode:         mode,
 			})
 		}
 	}
@@ -280,27 +695,68 @@ func discoverJobs(cfg config.Config) ([]cronJob, error) {
 }
 
 // executeWithCommand executes a cron job with an optional custom command
-func (s *Scheduler) executeWithCommand(job cronJob, customCmd []string) {
-	s.executeInternal(job, customCmd)
+func (s *Scheduler) executeWithCommand(job cronJob, customCmd []string) CronResult {
+	return s.executeInternal(job, customCmd)
 }
 
 func (s *Scheduler) execute(job cronJob) {
 	s.executeInternal(job, nil)
 }
 
-func (s *Scheduler) executeInternal(job cronJob, customCmd []string) {
-	ctx, cancel := context.WithTimeout(context.Background(), runner.CommandTimeout)
+// executeInternal runs a job, logging its progress, and reports the outcome,
+// which is also added to the job history.
+func (s *Scheduler) executeInternal(job cronJob, customCmd []string) (res CronResult) {
+	logger := s.log().With("stack", job.Stack, "service", job.Service)
+
+	// Wait for a slot before the timeout starts
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			logger.Info("cron job waiting for a free slot", "max_concurrent", cap(s.slots))
+			s.slots <- struct{}{}
+		}
+		defer func() { <-s.slots }()
+	}
+
+	// Wait for a deploy of the stack to finish, also before the timeout starts
+	unlock, ok := s.stackLocks.TryRLock(job.Stack)
+	if !ok {
+		logger.Info("cron job waiting for a deploy of its stack to finish")
+		var err error
+		if unlock, err = s.stackLocks.RLock(s.jobContext(), job.Stack); err != nil {
+			return CronResult{Stack: job.Stack, Service: job.Service, Err: err, ExitSummary: "cancelled while waiting for a deploy"}
+		}
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(s.jobContext(), job.timeout())
 	defer cancel()
 
+	started := time.Now()
+	defer func() {
+		s.history.Record(newJobRun(started, res))
+		s.metrics.CronFinished(job.Stack, job.Service, res.Success)
+		s.notify(s.Config().Global.Cron, res)
+	}()
+	result := CronResult{Stack: job.Stack, Service: job.Service}
+	fail := func(err error, summary string) CronResult {
+		result.Duration = time.Since(started)
+		result.Err = err
+		result.ExitSummary = summary
+		return result
+	}
+
+	cfg := s.Config()
+
 	// Create separate log file writers for build and exec (if enabled)
 	var logWriters *CronLogWriters
-	if s.cfg.Global.Cron.EnableFileLogs {
-		logsDir := filepath.Join(s.cfg.RepoRoot, s.cfg.Global.Cron.LogsDir)
+	if cfg.Global.Cron.EnableFileLogs {
+		logsDir := filepath.Join(cfg.RepoRoot, cfg.Global.Cron.LogsDir)
 		var err error
 		logWriters, err = CreateCronLogWriters(logsDir, job.Stack, job.Service)
 		if err != nil {
-			log.Printf("failed to create log files for stack=%s service=%s: %v",
-				job.Stack, job.Service, err)
+			logger.Warn("failed to create cron log files", "error", err)
 			// Continue without file logging (fail gracefully)
 			logWriters = nil
 		}
@@ -322,9 +778,8 @@ func (s *Scheduler) executeInternal(job cronJob, customCmd []string) {
 
 	// Phase 1: Pull/build if needed
 	if err := s.ensureImage(ctx, job, logWriters); err != nil {
-		log.Printf("cron job image preparation failed stack=%s service=%s: %v",
-			job.Stack, job.Service, err)
-		return
+		logger.Error("cron job image preparation failed", "status", "failed", "error", err)
+		return fail(err, "image preparation failed: "+err.Error())
 	}
 
 	// Write header to exec log
@@ -350,28 +805,20 @@ func (s *Scheduler) executeInternal(job cronJob, customCmd []string) {
 		stderrWriter = io.MultiWriter(&stderr, logWriters.ExecLog)
 	}
 
-	manager, err := stackcmd.NewManagerWithWriters(s.cfg, stdoutWriter, stderrWriter)
+	manager, err := stackcmd.NewManagerWithWriters(cfg, stdoutWriter, stderrWriter)
 	if err != nil {
-		log.Printf("cron job failed to create manager stack=%s service=%s: %v",
-			job.Stack, job.Service, err)
-		return
+		logger.Error("cron job failed to create manager", "status", "failed", "error", err)
+		return fail(err, err.Error())
+	}
+
+	if job.Mode == modeUp && len(customCmd) > 0 {
+		err := fmt.Errorf("cannot override the command of %s: %s=%s runs the service as defined", job.Service, modeLabel, modeUp)
+		return fail(err, err.Error())
 	}
 
 	// Generate deterministic container name and REMOVE --rm flag
 	containerName := GenerateContainerName(job.Stack, job.Service)
-	composeArgs := []string{"docker", "compose"}
-	for _, f := range job.ComposeFiles {
-		composeArgs = append(composeArgs, "--file", f)
-	}
-	if profile := strings.TrimSpace(job.Profile); profile != "" {
-		composeArgs = append(composeArgs, "--profile", profile)
-	}
-	// CHANGED: Add --name flag, REMOVE --rm flag, add --quiet to suppress operational logs
-	composeArgs = append(composeArgs, "run", "--quiet-pull", "--name", containerName, job.Service)
-	// Append custom command if provided
-	if len(customCmd) > 0 {
-		composeArgs = append(composeArgs, customCmd...)
-	}
+	composeArgs := runArgs(job, containerName, customCmd)
 
 	opts := stackcmd.Options{
 		Stacks:      []string{job.Stack},
@@ -379,27 +826,81 @@ func (s *Scheduler) executeInternal(job cronJob, customCmd []string) {
 		VarsCommand: composeArgs,
 	}
 
-	log.Printf("cron job started stack=%s service=%s container=%s",
-		job.Stack, job.Service, containerName)
+	logger.Info("cron job started", "status", "started", "container", containerName, "timeout", job.timeout())
 
-	if err := manager.Run(ctx, opts); err != nil {
+	err = manager.Run(ctx, opts)
+	result.Output = stdout.String() + stderr.String()
+	if err != nil {
 		if logWriters != nil {
 			_, _ = fmt.Fprintf(logWriters.ExecLog, "\n\n=== ERROR ===\n%s\n", stderr.String())
-			log.Printf("cron job failed stack=%s service=%s log_file=%s", job.Stack, job.Service, logWriters.ExecLogPath)
+			logger.Error("cron job failed", "status", "failed", "error", err, "log_file", logWriters.ExecLogPath)
 		} else {
-			log.Printf("cron job failed stack=%s service=%s", job.Stack, job.Service)
+			logger.Error("cron job failed", "status", "failed", "error", err)
 		}
-		return
+		return fail(err, exitSummary(err, stderr.String()))
 	}
 
 	// Success - no need to log output, it's in the log files
-	log.Printf("cron job finished stack=%s service=%s", job.Stack, job.Service)
+	logger.Info("cron job finished", "status", "succeeded", "duration", time.Since(started))
+	result.Success = true
+	result.Duration = time.Since(started)
+	result.ExitSummary = "exit status 0"
+	return result
+}
+
+// exitSummary describes a failed run by its error and the last line it wrote
+// to stderr, which usually says why it failed.
+func exitSummary(err error, stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return fmt.Sprintf("%v: %s", err, last)
+	}
+	return err.Error()
+}
+
+// runArgs builds the "docker compose run" command for a job, or in modeUp
+// "docker compose up --no-deps" for just its service, exiting with the
+// service's exit code.
+func runArgs(job cronJob, containerName string, customCmd []string) []string {
+	composeArgs := []string{"docker", "compose"}
+	if job.ProjectDir != "" {
+		composeArgs = append(composeArgs, "--project-directory", job.ProjectDir)
+	}
+	if job.Stack != "" {
+		composeArgs = append(composeArgs, "-p", compose.ProjectName(job.Stack))
+	}
+	for _, f := range job.ComposeFiles {
+		composeArgs = append(composeArgs, "--file", f)
+	}
+	if profile := strings.TrimSpace(job.Profile); profile != "" {
+		composeArgs = append(composeArgs, "--profile", profile)
+	}
+	if job.Mode == modeUp {
+		return append(composeArgs, "up", "--no-deps", "--quiet-pull", "--no-log-prefix", "--exit-code-from", job.Service, job.Service)
+	}
+	// CHANGED: Add --name flag, REMOVE --rm flag, add --quiet to suppress operational logs
+	composeArgs = append(composeArgs, "run", "--quiet-pull", "--name", containerName)
+	if job.User != "" {
+		composeArgs = append(composeArgs, "--user", job.User)
+	}
+	composeArgs = append(composeArgs, job.Service)
+	// Append custom command if provided
+	if len(customCmd) > 0 {
+		composeArgs = append(composeArgs, customCmd...)
+	}
+	return composeArgs
 }
 
 // ensureImage runs docker compose pull to ensure image is available
 // Logs output to build log file
 func (s *Scheduler) ensureImage(ctx context.Context, job cronJob, logWriters *CronLogWriters) error {
 	pullArgs := []string{"docker", "compose"}
+	if job.ProjectDir != "" {
+		pullArgs = append(pullArgs, "--project-directory", job.ProjectDir)
+	}
+	if job.Stack != "" {
+		pullArgs = append(pullArgs, "-p", compose.ProjectName(job.Stack))
+	}
 	for _, f := range job.ComposeFiles {
 		pullArgs = append(pullArgs, "--file", f)
 	}
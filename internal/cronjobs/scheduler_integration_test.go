@@ -3,6 +3,7 @@
 package cronjobs
 
 import (
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -114,7 +115,7 @@ func TestCleanupOldContainers(t *testing.T) {
 	}
 
 	// Run cleanup with retention=3
-	err := CleanupOldContainers(3)
+	err := CleanupOldContainers(slog.Default(), 3)
 	require.NoError(t, err)
 
 	// Count remaining containers using Docker API
@@ -1,13 +1,21 @@
 package cronjobs
 
 import (
+	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/runner"
+	"github.com/jamestiberiuskirk/stackr/internal/stacklock"
 )
 
 func TestDiscoverJobsParsesScheduleProfileAndRunOnDeploy(t *testing.T) {
@@ -30,7 +38,7 @@ services:
 	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
 
 	cfg := config.Config{StacksDir: stacksDir}
-	jobs, err := discoverJobs(cfg)
+	jobs, err := discoverJobs(cfg, slog.Default())
 	require.NoError(t, err)
 
 	require.Len(t, jobs, 2)
@@ -77,12 +85,50 @@ services:
 	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
 
 	cfg := config.Config{StacksDir: stacksDir}
-	jobs, err := discoverJobs(cfg)
+	jobs, err := discoverJobs(cfg, slog.Default())
 	require.NoError(t, err)
 	require.Len(t, jobs, 1)
 	require.False(t, jobs[0].RunOnDeploy)
 }
 
+func TestListJobsSortsAcrossStacks(t *testing.T) {
+	stacksDir := t.TempDir()
+	stacks := map[string]string{
+		"web": `
+services:
+  scraper:
+    profiles: ["scraper"]
+    labels:
+      - stackr.cron.schedule=0 1 * * *
+      - stackr.cron.run_on_deploy=true
+  app:
+    image: nginx
+  cleanup:
+    labels:
+      - stackr.cron.schedule=@hourly
+      - stackr.cron.enabled=false
+`,
+		"db": `
+services:
+  backup:
+    labels:
+      stackr.cron.schedule: ""
+`,
+	}
+	for stack, compose := range stacks {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, stack), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, stack, "docker-compose.yml"), []byte(compose), 0o644))
+	}
+
+	jobs, err := ListJobs(config.Config{StacksDir: stacksDir})
+	require.NoError(t, err)
+	require.Equal(t, []JobInfo{
+		{Stack: "db", Service: "backup", Enabled: true},
+		{Stack: "web", Service: "cleanup", Schedule: "@hourly"},
+		{Stack: "web", Service: "scraper", Schedule: "0 1 * * *", Profile: "scraper", RunOnDeploy: true, Enabled: true},
+	}, jobs)
+}
+
 func TestSchedulerStartStopLifecycle(t *testing.T) {
 	t.Run("NoJobs", func(t *testing.T) {
 		s := &Scheduler{
@@ -128,7 +174,7 @@ func TestDiscoverJobsEdgeCases(t *testing.T) {
 	t.Run("EmptyStacksDir", func(t *testing.T) {
 		stacksDir := t.TempDir()
 		cfg := config.Config{StacksDir: stacksDir}
-		jobs, err := discoverJobs(cfg)
+		jobs, err := discoverJobs(cfg, slog.Default())
 		require.NoError(t, err)
 		require.Empty(t, jobs)
 	})
@@ -137,7 +183,7 @@ func TestDiscoverJobsEdgeCases(t *testing.T) {
 		stacksDir := t.TempDir()
 		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "nocompose"), 0o755))
 		cfg := config.Config{StacksDir: stacksDir}
-		jobs, err := discoverJobs(cfg)
+		jobs, err := discoverJobs(cfg, slog.Default())
 		require.NoError(t, err)
 		require.Empty(t, jobs)
 	})
@@ -152,14 +198,14 @@ func TestDiscoverJobsEdgeCases(t *testing.T) {
 			0o644,
 		))
 		cfg := config.Config{StacksDir: stacksDir}
-		_, err := discoverJobs(cfg)
+		_, err := discoverJobs(cfg, slog.Default())
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to parse")
 	})
 
 	t.Run("NonExistentStacksDir", func(t *testing.T) {
 		cfg := config.Config{StacksDir: "/tmp/nonexistent-stackr-test-dir"}
-		_, err := discoverJobs(cfg)
+		_, err := discoverJobs(cfg, slog.Default())
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to read stacks dir")
 	})
@@ -169,8 +215,538 @@ func TestDiscoverJobsEdgeCases(t *testing.T) {
 		// Create a regular file (not a dir) in stacks dir
 		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "not-a-stack.txt"), []byte("hello"), 0o644))
 		cfg := config.Config{StacksDir: stacksDir}
-		jobs, err := discoverJobs(cfg)
+		jobs, err := discoverJobs(cfg, slog.Default())
 		require.NoError(t, err)
 		require.Empty(t, jobs)
 	})
 }
+
+func TestDisabledJobIsNotScheduledButManuallyRunnable(t *testing.T) {
+	stacksDir := t.TempDir()
+	stackDir := filepath.Join(stacksDir, "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+
+	compose := `
+services:
+  active:
+    labels:
+      - stackr.cron.schedule=@daily
+  paused:
+    labels:
+      - stackr.cron.schedule=@hourly
+      - stackr.cron.enabled=false
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
+
+	// Keep the startup container cleanup away from any real docker daemon
+	t.Setenv("PATH", t.TempDir())
+
+	cfg := config.Config{StacksDir: stacksDir}
+	cfg.Global.Cron.ContainerRetention = 5
+	jobs, err := discoverJobs(cfg, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	paused := findJob(jobs, "myapp", "paused")
+	require.NotNil(t, paused, "disabled job should still be discovered for manual runs")
+	require.False(t, paused.Enabled)
+	require.True(t, findJob(jobs, "myapp", "active").Enabled)
+
+	s := &Scheduler{cfg: cfg, jobs: jobs}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	// One entry for the active job plus the periodic container cleanup
+	require.Len(t, s.cron.Entries(), 2)
+}
+
+func TestDiscoverJobsCronUser(t *testing.T) {
+	stacksDir := t.TempDir()
+	stackDir := filepath.Join(stacksDir, "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+
+	compose := `
+services:
+  backup:
+    labels:
+      - stackr.cron.schedule=0 2 * * *
+      - stackr.cron.user=1000:1000
+  bad:
+    labels:
+      - stackr.cron.schedule=0 3 * * *
+      - stackr.cron.user=1000:1000 --privileged
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
+
+	jobs, err := discoverJobs(config.Config{StacksDir: stacksDir}, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1, "job with an invalid user must be skipped")
+	require.Equal(t, "backup", jobs[0].Service)
+	require.Equal(t, "1000:1000", jobs[0].User)
+
+	args := runArgs(jobs[0], "myapp-backup-1", []string{"/app/run.sh"})
+	require.Equal(t, []string{"docker", "compose", "--project-directory", stackDir, "-p", "myapp"}, args[:6])
+	require.Equal(t, []string{"run", "--quiet-pull", "--name", "myapp-backup-1", "--user", "1000:1000", "backup", "/app/run.sh"},
+		args[len(args)-8:])
+}
+
+func TestDiscoverJobsCronTimezone(t *testing.T) {
+	stacksDir := t.TempDir()
+	stackDir := filepath.Join(stacksDir, "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+
+	compose := `
+services:
+  london:
+    labels:
+      - stackr.cron.schedule=0 2 * * *
+      - stackr.cron.timezone=Europe/London
+  bogus:
+    labels:
+      - stackr.cron.schedule=0 2 * * *
+      - stackr.cron.timezone=Mars/Olympus_Mons
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
+
+	jobs, err := discoverJobs(config.Config{StacksDir: stacksDir}, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 2, "an invalid timezone must not drop the job")
+
+	london := findJob(jobs, "myapp", "london")
+	require.NotNil(t, london)
+	require.Equal(t, "Europe/London", london.Timezone)
+	require.Equal(t, "CRON_TZ=Europe/London 0 2 * * *", london.spec())
+
+	bogus := findJob(jobs, "myapp", "bogus")
+	require.NotNil(t, bogus)
+	require.Empty(t, bogus.Timezone)
+	require.Equal(t, "0 2 * * *", bogus.spec())
+
+	// In British Summer Time 2am London is 01:00 UTC
+	schedule, err := scheduleParser.Parse(london.spec())
+	require.NoError(t, err)
+	next := schedule.Next(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2026, 7, 1, 1, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestDiscoverJobsSecondsSchedules(t *testing.T) {
+	stacksDir := t.TempDir()
+	stackDir := filepath.Join(stacksDir, "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+
+	compose := `
+services:
+  poller:
+    labels:
+      - stackr.cron.schedule=*/30 * * * * *
+  ticker:
+    labels:
+      - stackr.cron.schedule=@every 15s
+  nightly:
+    labels:
+      - stackr.cron.schedule=0 2 * * *
+  broken:
+    labels:
+      - stackr.cron.schedule=* * * * * * *
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
+
+	jobs, err := discoverJobs(config.Config{StacksDir: stacksDir}, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 3, "a job with an invalid schedule must be skipped")
+	require.Nil(t, findJob(jobs, "myapp", "broken"))
+
+	start := time.Date(2026, 7, 1, 0, 0, 10, 0, time.UTC)
+	for service, want := range map[string]time.Time{
+		"poller":  time.Date(2026, 7, 1, 0, 0, 30, 0, time.UTC),
+		"ticker":  time.Date(2026, 7, 1, 0, 0, 25, 0, time.UTC),
+		"nightly": time.Date(2026, 7, 1, 2, 0, 0, 0, time.UTC),
+	} {
+		job := findJob(jobs, "myapp", service)
+		require.NotNil(t, job, service)
+		schedule, err := scheduleParser.Parse(job.spec())
+		require.NoError(t, err, service)
+		require.Equal(t, want, schedule.Next(start), service)
+	}
+}
+
+func TestDiscoverJobsResolvesLabelEnv(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	stackDir := filepath.Join(stacksDir, "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	envFile := filepath.Join(root, ".env")
+	require.NoError(t, os.WriteFile(envFile, []byte("BACKUP_CRON=0 3 * * *\nRUN_BACKUP_ON_DEPLOY=true\nCRON_TZ_NAME=UTC\n"), 0o644))
+	// The stack's own .env overrides the global one
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, ".env"), []byte("CRON_TZ_NAME=Europe/London\n"), 0o644))
+
+	compose := `
+services:
+  backup:
+    labels:
+      - stackr.cron.schedule=${BACKUP_CRON}
+      - stackr.cron.run_on_deploy=${RUN_BACKUP_ON_DEPLOY}
+      - stackr.cron.timezone=${CRON_TZ_NAME}
+  unresolved:
+    labels:
+      - stackr.cron.schedule=${UNDEFINED_CRON}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
+
+	jobs, err := discoverJobs(config.Config{StacksDir: stacksDir, EnvFile: envFile}, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1, "a schedule that doesn't resolve must be skipped")
+
+	job := findJob(jobs, "myapp", "backup")
+	require.NotNil(t, job)
+	require.Equal(t, "0 3 * * *", job.Schedule)
+	require.True(t, job.RunOnDeploy)
+	require.Equal(t, "Europe/London", job.Timezone)
+
+	schedule, err := scheduleParser.Parse(job.spec())
+	require.NoError(t, err)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	start := time.Date(2026, 1, 10, 12, 0, 0, 0, london)
+	require.Equal(t, time.Date(2026, 1, 11, 3, 0, 0, 0, london), schedule.Next(start))
+}
+
+func TestRunArgsWithoutUser(t *testing.T) {
+	args := runArgs(cronJob{Service: "job", ComposeFiles: []string{"/s/docker-compose.yml"}}, "c1", nil)
+	require.Equal(t, []string{"docker", "compose", "--file", "/s/docker-compose.yml", "run", "--quiet-pull", "--name", "c1", "job"}, args)
+}
+
+func TestDiscoverJobsCronMode(t *testing.T) {
+	stacksDir := t.TempDir()
+	stackDir := filepath.Join(stacksDir, "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+
+	compose := `
+services:
+  default:
+    labels:
+      - stackr.cron.schedule=0 1 * * *
+  oneoff:
+    labels:
+      - stackr.cron.schedule=0 2 * * *
+      - stackr.cron.mode=run
+  networked:
+    labels:
+      - stackr.cron.schedule=0 3 * * *
+      - stackr.cron.mode=up
+  bogus:
+    labels:
+      - stackr.cron.schedule=0 4 * * *
+      - stackr.cron.mode=exec
+  asuser:
+    labels:
+      - stackr.cron.schedule=0 5 * * *
+      - stackr.cron.mode=up
+      - stackr.cron.user=1000
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(compose), 0o644))
+
+	jobs, err := discoverJobs(config.Config{StacksDir: stacksDir}, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 4, "an up job with a user must be skipped")
+	require.Nil(t, findJob(jobs, "myapp", "asuser"))
+
+	for service, want := range map[string][]string{
+		"default":   {"run", "--quiet-pull", "--name", "c1", "default"},
+		"oneoff":    {"run", "--quiet-pull", "--name", "c1", "oneoff"},
+		"bogus":     {"run", "--quiet-pull", "--name", "c1", "bogus"},
+		"networked": {"up", "--no-deps", "--quiet-pull", "--no-log-prefix", "--exit-code-from", "networked", "networked"},
+	} {
+		job := findJob(jobs, "myapp", service)
+		require.NotNil(t, job, service)
+		args := runArgs(*job, "c1", nil)
+		require.Equal(t, want, args[len(args)-len(want):], service)
+	}
+}
+
+func TestExecuteInternalReportsResult(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  job:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@daily
+`), 0o644))
+
+	// docker stub whose "run" fails while the fail marker exists
+	binDir := t.TempDir()
+	failMarker := filepath.Join(binDir, "fail")
+	script := "#!/bin/sh\n" +
+		"case \"$*\" in *\" run \"*) if [ -f \"" + failMarker + "\" ]; then echo 'job crashed' >&2; exit 3; fi ;; esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	jobs, err := discoverJobs(cfg, slog.Default())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	s := &Scheduler{cfg: cfg, history: NewJobHistory(DefaultHistorySize)}
+
+	result := s.executeInternal(jobs[0], nil)
+	require.True(t, result.Success)
+	require.NoError(t, result.Err)
+	require.Equal(t, "myapp", result.Stack)
+	require.Equal(t, "job", result.Service)
+	require.Positive(t, result.Duration)
+
+	require.NoError(t, os.WriteFile(failMarker, nil, 0o644))
+	result = s.executeInternal(jobs[0], nil)
+	require.False(t, result.Success)
+	require.Error(t, result.Err)
+	require.Equal(t, "exit status 3: job crashed", result.ExitSummary)
+
+	runs := s.History()
+	require.Len(t, runs, 2)
+	require.False(t, runs[0].Success)
+	require.Equal(t, "exit status 3: job crashed", runs[0].ExitStatus)
+	require.Contains(t, runs[0].Output, "job crashed")
+	require.True(t, runs[1].Success)
+	require.Equal(t, "myapp", runs[1].Stack)
+	require.Equal(t, "job", runs[1].Service)
+
+	err = ExecuteJobManually(cfg, "myapp", "job", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "job crashed")
+}
+
+func TestCronJobTimeout(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  ping:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=* * * * *
+      - stackr.cron.timeout=200ms
+  nightly:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@daily
+      - stackr.cron.timeout=forever
+`), 0o644))
+
+	// docker stub whose "run" hangs
+	binDir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *\" run \"*) exec sleep 5 ;; esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	jobs, err := discoverJobs(cfg, slog.Default())
+	require.NoError(t, err)
+
+	ping := findJob(jobs, "myapp", "ping")
+	require.NotNil(t, ping)
+	require.Equal(t, 200*time.Millisecond, ping.timeout())
+
+	nightly := findJob(jobs, "myapp", "nightly")
+	require.NotNil(t, nightly, "an invalid timeout must not drop the job")
+	require.Equal(t, runner.CommandTimeout, nightly.timeout())
+
+	s := &Scheduler{cfg: cfg}
+	result := s.executeInternal(*ping, nil)
+	require.False(t, result.Success)
+	require.Less(t, result.Duration, 4*time.Second, "the job must be stopped at its own timeout")
+}
+
+func TestStopCancelsRunningJobs(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  export:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@daily
+`), 0o644))
+
+	// docker stub whose "run" hangs
+	binDir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *\" run \"*) exec sleep 5 ;; esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	s, err := New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+
+	jobCtx := s.jobContext()
+	results := make(chan CronResult, 1)
+	go func() { results <- s.executeInternal(s.jobs[0], nil) }()
+	time.Sleep(200 * time.Millisecond)
+
+	s.Stop()
+	require.ErrorIs(t, jobCtx.Err(), context.Canceled)
+	select {
+	case result := <-results:
+		require.False(t, result.Success)
+		require.Less(t, result.Duration, 4*time.Second, "Stop must interrupt the running job")
+	case <-time.After(4 * time.Second):
+		t.Fatal("running job was not cancelled by Stop")
+	}
+
+	require.NoError(t, s.Start())
+	defer s.Stop()
+	require.NoError(t, s.jobContext().Err(), "a restarted scheduler runs jobs again")
+}
+
+func TestCronMaxConcurrent(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  a:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@hourly
+  b:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@hourly
+  c:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@hourly
+  d:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@hourly
+`), 0o644))
+
+	// docker stub whose "run" logs how many runs are in flight
+	binDir := t.TempDir()
+	running := filepath.Join(binDir, "running")
+	counts := filepath.Join(binDir, "counts")
+	require.NoError(t, os.MkdirAll(running, 0o755))
+	script := "#!/bin/sh\n" +
+		"case \"$*\" in *\" run \"*)\n" +
+		"  touch " + running + "/$$\n" +
+		"  ls " + running + " | wc -l >> " + counts + "\n" +
+		"  sleep 0.3\n" +
+		"  rm " + running + "/$$\n" +
+		";; esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	cfg.Global.Cron.MaxConcurrent = 2
+	s, err := New(cfg)
+	require.NoError(t, err)
+	require.Len(t, s.jobs, 4)
+
+	results := make([]CronResult, len(s.jobs))
+	var wg sync.WaitGroup
+	for i, job := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = s.executeInternal(job, nil)
+		}()
+	}
+	wg.Wait()
+	for _, result := range results {
+		require.True(t, result.Success)
+	}
+
+	data, err := os.ReadFile(counts)
+	require.NoError(t, err)
+	lines := strings.Fields(string(data))
+	require.Len(t, lines, 4)
+	for _, line := range lines {
+		n, err := strconv.Atoi(line)
+		require.NoError(t, err)
+		require.LessOrEqual(t, n, 2, "no more than cron.max_concurrent jobs may run at once")
+	}
+}
+
+func TestCronWaitsForStackLock(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "myapp")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  export:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@daily
+`), 0o644))
+
+	// docker stub that logs every call
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := "#!/bin/sh\necho \"$@\" >> \"" + logPath + "\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	s, err := New(cfg)
+	require.NoError(t, err)
+	require.Len(t, s.jobs, 1)
+	locks := stacklock.New()
+	s.SetStackLocks(locks)
+
+	// Stands in for a deploy of the stack
+	unlock, ok := locks.TryLock("myapp")
+	require.True(t, ok)
+
+	results := make(chan CronResult, 1)
+	go func() { results <- s.executeInternal(s.jobs[0], nil) }()
+	select {
+	case <-results:
+		t.Fatal("cron job ran while its stack was locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoFileExists(t, logPath)
+
+	unlock()
+	select {
+	case result := <-results:
+		require.True(t, result.Success)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cron job did not run after its stack was unlocked")
+	}
+	require.FileExists(t, logPath)
+
+	// A deploy can take the lock again once the run is over
+	unlock, ok = locks.TryLock("myapp")
+	require.True(t, ok)
+	unlock()
+}
@@ -0,0 +1,193 @@
+package fsutil
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TarGzDir streams the tree under src into a gzip-compressed tarball at
+// dest, with paths relative to src. A non-zero since limits the archive to
+// files and symlinks modified after it (incremental backups). It returns
+// the number of files and symlinks archived; dest is removed on failure.
+func TarGzDir(src, dest string, since time.Time) (archived int, err error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(dest)
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		// Incremental archives only hold changed entries; tar recreates
+		// their parent directories on extraction
+		if d.IsDir() && !since.IsZero() {
+			return nil
+		}
+		if !d.IsDir() && !since.IsZero() && !info.ModTime().After(since) {
+			return nil
+		}
+
+		var link string
+		if d.Type()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			if err := copyInto(tw, path); err != nil {
+				return err
+			}
+		}
+		archived++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+	return archived, out.Close()
+}
+
+func copyInto(w io.Writer, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// ExtractTarGz unpacks a tarball written by TarGzDir into dest, overwriting
+// existing files. Entries that would land outside dest are rejected. It
+// returns the number of files and symlinks extracted.
+func ExtractTarGz(src, dest string) (extracted int, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	root := filepath.Clean(dest)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return extracted, nil
+		}
+		if err != nil {
+			return extracted, err
+		}
+
+		target := filepath.Join(root, filepath.FromSlash(header.Name))
+		if target != root && !strings.HasPrefix(target, root+string(filepath.Separator)) {
+			return extracted, fmt.Errorf("archive entry %q escapes %s", header.Name, dest)
+		}
+		mode := header.FileInfo().Mode()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode.Perm()); err != nil {
+				return extracted, err
+			}
+			continue
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return extracted, err
+			}
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return extracted, err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return extracted, err
+			}
+		case tar.TypeReg:
+			if err := writeFrom(tr, target, mode.Perm()); err != nil {
+				return extracted, err
+			}
+		default:
+			continue
+		}
+		extracted++
+	}
+}
+
+func writeFrom(r io.Reader, path string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -1,10 +1,12 @@
 package fsutil
 
 import (
+	"errors"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // CopyDir recursively copies a directory tree from src to dest.
@@ -32,6 +34,10 @@ func CopyDir(src, dest string) error {
 			if err != nil {
 				return err
 			}
+			// Copying onto an existing tree (restore) replaces the old link
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
 			return os.Symlink(ref, target)
 		default:
 			return CopyFile(path, target, info.Mode())
@@ -65,3 +71,50 @@ func CopyFile(src, dest string, mode fs.FileMode) error {
 	}
 	return nil
 }
+
+// CopyDirModifiedSince copies only the files and symlinks under src modified
+// after since, recreating their parent directories in dest. It returns the
+// number of entries copied.
+func CopyDirModifiedSince(src, dest string, since time.Time) (int, error) {
+	copied := 0
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().After(since) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if d.Type()&os.ModeSymlink != 0 {
+			ref, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(ref, target); err != nil {
+				return err
+			}
+		} else if err := CopyFile(path, target, info.Mode()); err != nil {
+			return err
+		}
+		copied++
+		return nil
+	})
+	return copied, err
+}
@@ -0,0 +1,53 @@
+package fsutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// CopyOwnership gives every path under dest the uid/gid of its counterpart
+// under src. Paths missing from dest are skipped, so it can follow
+// CopyDirModifiedSince. Changing owners needs privileges: paths that cannot
+// be changed for lack of them are counted in skipped rather than failing the
+// walk. Running it again is harmless.
+func CopyOwnership(src, dest string) (skipped int, err error) {
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if _, err := os.Lstat(target); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+
+		if err := os.Lchown(target, int(stat.Uid), int(stat.Gid)); err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				skipped++
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+	return skipped, err
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -14,14 +15,18 @@ const OperationTimeout = 5 * time.Minute
 
 // Client wraps git operations for a specific repository
 type Client struct {
-	repoPath string
+	repoPath   string
+	sshKeyPath string
+	token      string
 }
 
 // CloneOptions configures git clone behavior
 type CloneOptions struct {
-	URL    string
-	Branch string
-	Depth  int // Shallow clone depth (0 = full clone)
+	URL        string
+	Branch     string
+	Depth      int    // Shallow clone depth (0 = full clone)
+	SSHKeyPath string // Private key used for SSH remotes (optional)
+	Token      string // Access token used for HTTPS remotes (optional)
 }
 
 // CheckoutOptions configures git checkout behavior
@@ -50,6 +55,71 @@ func NewClient(repoPath string) *Client {
 	return &Client{repoPath: repoPath}
 }
 
+// WithSSHKey returns a copy of the client that authenticates SSH remotes with
+// the given private key on clone, fetch and pull.
+func (c *Client) WithSSHKey(keyPath string) *Client {
+	clone := *c
+	clone.sshKeyPath = keyPath
+	return &clone
+}
+
+// WithToken returns a copy of the client that authenticates HTTPS remotes
+// with the given access token on clone, fetch and pull.
+func (c *Client) WithToken(token string) *Client {
+	clone := *c
+	clone.token = token
+	return &clone
+}
+
+// tokenHelper is a credential helper that answers every lookup with the token
+// from the environment. It is installed through GIT_CONFIG_* variables so the
+// token never appears in the command line or the repo's .git/config.
+const tokenHelper = `!f() { test "$1" = get && echo username=x-access-token && echo "password=$STACKR_GIT_TOKEN"; }; f`
+
+// remoteCommand builds a git command that talks to the remote. When keyPath is
+// set, GIT_SSH_COMMAND pins ssh to that identity; when token is set, HTTPS
+// credentials come from tokenHelper instead of any configured helper.
+func remoteCommand(ctx context.Context, keyPath, token string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if keyPath == "" && token == "" {
+		return cmd
+	}
+	cmd.Env = os.Environ()
+	if keyPath != "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+sshCommand(keyPath))
+	}
+	if token != "" {
+		cmd.Env = append(cmd.Env,
+			"STACKR_GIT_TOKEN="+token,
+			"GIT_TERMINAL_PROMPT=0",
+			// An empty helper resets the list so only tokenHelper is asked
+			"GIT_CONFIG_COUNT=2",
+			"GIT_CONFIG_KEY_0=credential.helper",
+			"GIT_CONFIG_VALUE_0=",
+			"GIT_CONFIG_KEY_1=credential.helper",
+			"GIT_CONFIG_VALUE_1="+tokenHelper,
+		)
+	}
+	return cmd
+}
+
+func sshCommand(keyPath string) string {
+	quoted := "'" + strings.ReplaceAll(keyPath, "'", `'\''`) + "'"
+	return "ssh -i " + quoted + " -o IdentitiesOnly=yes"
+}
+
+// redact keeps the key path (ssh echoes it on failure) and the token out of
+// error output.
+func redact(output, keyPath, token string) string {
+	if keyPath != "" {
+		output = strings.ReplaceAll(output, keyPath, "<ssh-key>")
+	}
+	if token != "" {
+		output = strings.ReplaceAll(output, token, "<token>")
+	}
+	return output
+}
+
 // withTimeout returns a context with OperationTimeout applied.
 // If the parent context already has an earlier deadline, that is preserved.
 func withTimeout(parent context.Context) (context.Context, context.CancelFunc) {
@@ -76,7 +146,7 @@ func Clone(ctx context.Context, destination string, opts CloneOptions) error {
 	// Add URL and destination
 	args = append(args, opts.URL, destination)
 
-	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd := remoteCommand(ctx, opts.SSHKeyPath, opts.Token, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -85,8 +155,8 @@ func Clone(ctx context.Context, destination string, opts CloneOptions) error {
 		return &GitError{
 			Operation: "clone",
 			Command:   fmt.Sprintf("git %s", strings.Join(args, " ")),
-			Stdout:    stdout.String(),
-			Stderr:    stderr.String(),
+			Stdout:    redact(stdout.String(), opts.SSHKeyPath, opts.Token),
+			Stderr:    redact(stderr.String(), opts.SSHKeyPath, opts.Token),
 			ExitCode:  cmd.ProcessState.ExitCode(),
 		}
 	}
@@ -99,7 +169,7 @@ func (c *Client) Pull(ctx context.Context) error {
 	ctx, cancel := withTimeout(ctx)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "git", "-C", c.repoPath, "pull")
+	cmd := remoteCommand(ctx, c.sshKeyPath, c.token, "-C", c.repoPath, "pull")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -108,6 +178,55 @@ func (c *Client) Pull(ctx context.Context) error {
 		return &GitError{
 			Operation: "pull",
 			Command:   fmt.Sprintf("git -C %s pull", c.repoPath),
+			Stdout:    redact(stdout.String(), c.sshKeyPath, c.token),
+			Stderr:    redact(stderr.String(), c.sshKeyPath, c.token),
+			ExitCode:  cmd.ProcessState.ExitCode(),
+		}
+	}
+
+	return nil
+}
+
+// FetchBranch fetches branch from origin into its remote-tracking ref. Clones
+// are single-branch, so a plain fetch would not bring in other branches.
+func (c *Client) FetchBranch(ctx context.Context, branch string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	refspec := fmt.Sprintf("+%s:refs/remotes/origin/%s", branch, branch)
+	cmd := remoteCommand(ctx, c.sshKeyPath, c.token, "-C", c.repoPath, "fetch", "origin", refspec)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return &GitError{
+			Operation: "fetch",
+			Command:   fmt.Sprintf("git -C %s fetch origin %s", c.repoPath, refspec),
+			Stdout:    redact(stdout.String(), c.sshKeyPath, c.token),
+			Stderr:    redact(stderr.String(), c.sshKeyPath, c.token),
+			ExitCode:  cmd.ProcessState.ExitCode(),
+		}
+	}
+
+	return nil
+}
+
+// CheckoutBranch checks out branch, creating or resetting it to the fetched
+// origin/<branch>
+func (c *Client) CheckoutBranch(ctx context.Context, branch string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "-C", c.repoPath, "checkout", "-B", branch, "origin/"+branch)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return &GitError{
+			Operation: "checkout",
+			Command:   fmt.Sprintf("git -C %s checkout -B %s origin/%s", c.repoPath, branch, branch),
 			Stdout:    stdout.String(),
 			Stderr:    stderr.String(),
 			ExitCode:  cmd.ProcessState.ExitCode(),
@@ -130,7 +249,7 @@ func (c *Client) Fetch(ctx context.Context) error {
 	ctx, cancel := withTimeout(ctx)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "git", "-C", c.repoPath, "fetch", "--tags")
+	cmd := remoteCommand(ctx, c.sshKeyPath, c.token, "-C", c.repoPath, "fetch", "--tags")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -139,8 +258,8 @@ func (c *Client) Fetch(ctx context.Context) error {
 		return &GitError{
 			Operation: "fetch",
 			Command:   fmt.Sprintf("git -C %s fetch --tags", c.repoPath),
-			Stdout:    stdout.String(),
-			Stderr:    stderr.String(),
+			Stdout:    redact(stdout.String(), c.sshKeyPath, c.token),
+			Stderr:    redact(stderr.String(), c.sshKeyPath, c.token),
 			ExitCode:  cmd.ProcessState.ExitCode(),
 		}
 	}
@@ -163,7 +282,7 @@ func (c *Client) unshallow(ctx context.Context) error {
 	ctx, cancel := withTimeout(ctx)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "git", "-C", c.repoPath, "fetch", "--unshallow")
+	cmd := remoteCommand(ctx, c.sshKeyPath, c.token, "-C", c.repoPath, "fetch", "--unshallow")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -172,8 +291,8 @@ func (c *Client) unshallow(ctx context.Context) error {
 		return &GitError{
 			Operation: "fetch --unshallow",
 			Command:   fmt.Sprintf("git -C %s fetch --unshallow", c.repoPath),
-			Stdout:    stdout.String(),
-			Stderr:    stderr.String(),
+			Stdout:    redact(stdout.String(), c.sshKeyPath, c.token),
+			Stderr:    redact(stderr.String(), c.sshKeyPath, c.token),
 			ExitCode:  cmd.ProcessState.ExitCode(),
 		}
 	}
@@ -249,6 +368,30 @@ func (c *Client) CurrentCommit(ctx context.Context) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// Describe returns "git describe --tags" for HEAD: the nearest tag, with the
+// commit count and abbreviated hash appended when HEAD is past it
+func (c *Client) Describe(ctx context.Context) (string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "-C", c.repoPath, "describe", "--tags")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", &GitError{
+			Operation: "describe",
+			Command:   fmt.Sprintf("git -C %s describe --tags", c.repoPath),
+			Stdout:    stdout.String(),
+			Stderr:    stderr.String(),
+			ExitCode:  cmd.ProcessState.ExitCode(),
+		}
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
 // IsClean returns true if the working directory has no uncommitted changes
 func (c *Client) IsClean(ctx context.Context) (bool, error) {
 	ctx, cancel := withTimeout(ctx)
@@ -2,10 +2,14 @@ package git
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -226,3 +230,98 @@ func runGitCommand(ctx context.Context, name string, args ...string) error {
 	cmd := exec.CommandContext(ctx, name, args...)
 	return cmd.Run()
 }
+
+func TestDescribe(t *testing.T) {
+	testRepo := filepath.Join(t.TempDir(), "source")
+	require.NoError(t, os.MkdirAll(testRepo, 0o755))
+
+	ctx := context.Background()
+	require.NoError(t, runGitCommand(ctx, "git", "-C", testRepo, "init"))
+	require.NoError(t, runGitCommand(ctx, "git", "-C", testRepo, "config", "user.email", "test@test.com"))
+	require.NoError(t, runGitCommand(ctx, "git", "-C", testRepo, "config", "user.name", "Test User"))
+	require.NoError(t, os.WriteFile(filepath.Join(testRepo, "README.md"), []byte("test"), 0o644))
+	require.NoError(t, runGitCommand(ctx, "git", "-C", testRepo, "add", "."))
+	require.NoError(t, runGitCommand(ctx, "git", "-C", testRepo, "commit", "-m", "initial"))
+
+	client := NewClient(testRepo)
+	_, err := client.Describe(ctx)
+	var gitErr *GitError
+	require.ErrorAs(t, err, &gitErr, "describe without tags should fail")
+
+	require.NoError(t, runGitCommand(ctx, "git", "-C", testRepo, "tag", "v1.2.0"))
+	described, err := client.Describe(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "v1.2.0", described)
+
+	require.NoError(t, os.WriteFile(filepath.Join(testRepo, "README.md"), []byte("changed"), 0o644))
+	require.NoError(t, runGitCommand(ctx, "git", "-C", testRepo, "commit", "-am", "second"))
+	described, err = client.Describe(ctx)
+	require.NoError(t, err)
+	require.Regexp(t, `^v1\.2\.0-1-g[0-9a-f]+$`, described)
+}
+
+func TestCloneUsesSSHKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyPath := filepath.Join(tmpDir, "keys", "deploy_key")
+	argsLog := filepath.Join(tmpDir, "ssh-args")
+
+	// Fake ssh records how git invoked it and fails, echoing the key path
+	binDir := filepath.Join(tmpDir, "bin")
+	require.NoError(t, os.MkdirAll(binDir, 0o755))
+	script := "#!/bin/sh\necho \"$@\" > " + argsLog + "\necho \"Load key $2: not accessible\" >&2\nexit 255\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "ssh"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	err := Clone(context.Background(), filepath.Join(tmpDir, "dest"), CloneOptions{
+		URL:        "ssh://git@example.invalid/org/repo.git",
+		SSHKeyPath: keyPath,
+	})
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), keyPath)
+
+	args, readErr := os.ReadFile(argsLog)
+	require.NoError(t, readErr)
+	require.Contains(t, string(args), "-i "+keyPath+" -o IdentitiesOnly=yes")
+}
+
+func TestRemoteCommandEnv(t *testing.T) {
+	cmd := remoteCommand(context.Background(), "", "", "fetch")
+	require.Nil(t, cmd.Env, "no key should inherit the environment untouched")
+
+	client := NewClient("/repo").WithSSHKey("/keys/it's key")
+	cmd = remoteCommand(context.Background(), client.sshKeyPath, client.token, "fetch")
+	require.Contains(t, cmd.Env, `GIT_SSH_COMMAND=ssh -i '/keys/it'\''s key' -o IdentitiesOnly=yes`)
+
+	client = NewClient("/repo").WithToken("ghp_s3cret")
+	cmd = remoteCommand(context.Background(), client.sshKeyPath, client.token, "fetch")
+	require.Contains(t, cmd.Env, "STACKR_GIT_TOKEN=ghp_s3cret")
+	require.NotContains(t, strings.Join(cmd.Args, " "), "ghp_s3cret")
+}
+
+func TestCloneUsesToken(t *testing.T) {
+	token := "ghp_s3cret"
+	auths := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			auths <- r.Header.Get("Authorization")
+			http.Error(w, "repository not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	err := Clone(context.Background(), filepath.Join(t.TempDir(), "dest"), CloneOptions{
+		URL:   srv.URL + "/org/repo.git",
+		Token: token,
+	})
+	require.Error(t, err)
+
+	require.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("x-access-token:"+token)), <-auths)
+
+	var gitErr *GitError
+	require.ErrorAs(t, err, &gitErr)
+	require.NotContains(t, gitErr.Command, token)
+	require.NotContains(t, err.Error(), token)
+}
@@ -0,0 +1,41 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jamestiberiuskirk/stackr/internal/audit"
+)
+
+// newAuditEntry describes who asked for a deploy: the webhook signature when
+// one was verified, otherwise the SHA-256 of the bearer token so the log
+// never holds a usable credential.
+func (h *Handler) newAuditEntry(r *http.Request, stack, tag string) audit.Entry {
+	entry := audit.Entry{
+		Time:     time.Now().UTC(),
+		Stack:    stack,
+		Tag:      tag,
+		Auth:     "token",
+		SourceIP: sourceIP(r),
+	}
+	if r.Header.Get(signatureHeader) != "" && h.config().WebhookSecret != "" {
+		entry.Auth = "webhook"
+		return entry
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	sum := sha256.Sum256([]byte(token))
+	entry.TokenSHA = hex.EncodeToString(sum[:])
+	return entry
+}
+
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
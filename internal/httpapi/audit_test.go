@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/audit"
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/runner"
+)
+
+func TestDeployAppendsAuditLog(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "web"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "web", "docker-compose.yml"), []byte("services:\n  app:\n    image: web\n"), 0o644))
+
+	cfg := config.Config{Token: "s3cret-token", RepoRoot: root, StacksDir: stacksDir}
+	cfg.Global.Audit.Log = ".stackr/audit.jsonl"
+	h := New(cfg, nil, nil, BuildInfo{}, nil, nil)
+	h.deploy = func(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*runner.Result, error) {
+		if tag == "v2.0.0" {
+			return nil, errors.New("pull failed")
+		}
+		return runner.NewResult(stack, tag, ""), nil
+	}
+
+	deploy := func(tag string) {
+		req := httptest.NewRequest(http.MethodPost, "/deploy", strings.NewReader(`{"stack":"web","tag":"`+tag+`"}`))
+		req.Header.Set("Authorization", "Bearer s3cret-token")
+		req.RemoteAddr = "203.0.113.7:51234"
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	deploy("v1.0.0")
+	deploy("v2.0.0")
+
+	data, err := os.ReadFile(filepath.Join(root, ".stackr", "audit.jsonl"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "s3cret-token", "the token must only be stored hashed")
+
+	var entries []audit.Entry
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var entry audit.Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 2, "one line per deploy")
+
+	sum := sha256.Sum256([]byte("s3cret-token"))
+	for _, entry := range entries {
+		require.Equal(t, "web", entry.Stack)
+		require.Equal(t, "token", entry.Auth)
+		require.Equal(t, hex.EncodeToString(sum[:]), entry.TokenSHA)
+		require.Equal(t, "203.0.113.7", entry.SourceIP)
+		require.False(t, entry.Time.IsZero())
+	}
+	require.Equal(t, "v1.0.0", entries[0].Tag)
+	require.Equal(t, "succeeded", entries[0].Result)
+	require.Equal(t, "v2.0.0", entries[1].Tag)
+	require.Equal(t, "failed", entries[1].Result)
+	require.Equal(t, "pull failed", entries[1].Error)
+}
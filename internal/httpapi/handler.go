@@ -1,35 +1,92 @@
 package httpapi
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/jamestiberiuskirk/stackr/internal/audit"
 	"github.com/jamestiberiuskirk/stackr/internal/compose"
 	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/cronjobs"
 	"github.com/jamestiberiuskirk/stackr/internal/runner"
+	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
 	"gopkg.in/yaml.v3"
 )
 
-var semverPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+(-[a-zA-Z0-9._-]+)?$`)
-
 const autoDeployLabel = "stackr.deploy.auto"
 
+// signatureHeader carries the GitHub style "sha256=<hex hmac>" of the body.
+const signatureHeader = "X-Hub-Signature-256"
+
 type Handler struct {
-	cfg    config.Config
-	runner *runner.Runner
-	mux    *http.ServeMux
+	cfg      config.Config
+	build    BuildInfo
+	runner   *runner.Runner
+	deploy   deployFunc
+	rollback rollbackFunc
+	jobs     *jobStore
+	audit    *audit.Log
+	cron     *cronjobs.Scheduler
+	metrics  http.Handler
+	mux      *http.ServeMux
+	// cfgMu guards cfg and audit, which token rotation and /admin/reload
+	// replace
+	cfgMu sync.RWMutex
+	// reloadMu serializes /admin/reload, which applies the new config
+	// without holding cfgMu
+	reloadMu sync.Mutex
+	// loadConfig is config.Load outside of tests.
+	loadConfig func(repoRoot string) (config.Config, error)
+	logger     *slog.Logger
+	// jobsCtx is cancelled by Shutdown; jobsWG tracks the async deploys
+	// running with it
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
+	jobsWG     sync.WaitGroup
+}
+
+// BuildInfo identifies the running daemon build; GET /version reports it.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
 }
 
+// versionResponse is the GET /version response.
+type versionResponse struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	Date       string `json:"date"`
+	ConfigPath string `json:"config_path"`
+}
+
+// deployFunc runs a deployment; it is runner.Runner.Deploy outside of tests.
+type deployFunc func(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*runner.Result, error)
+
+// rollbackFunc rolls a stack back; it is runner.Runner.Rollback outside of
+// tests.
+type rollbackFunc func(ctx context.Context, stack string, stackCfg config.StackConfig) (*runner.Result, error)
+
 type composeFile struct {
 	Services map[string]composeService `yaml:"services"`
 }
@@ -38,17 +95,72 @@ type composeService struct {
 	Labels compose.LabelMap `yaml:"labels"`
 }
 
+type rotateTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// stackSummary is one entry of the GET /stacks response.
+type stackSummary struct {
+	Name           string             `json:"name"`
+	Type           stackcmd.StackType `json:"type"`
+	HasCompose     bool               `json:"has_compose"`
+	ConfiguredRef  string             `json:"configured_ref,omitempty"`
+	CurrentVersion string             `json:"current_version,omitempty"`
+	Error          string             `json:"error,omitempty"`
+}
+
+type cronRunRequest struct {
+	Stack   string   `json:"stack"`
+	Service string   `json:"service"`
+	Command []string `json:"command"`
+}
+
+type rollbackRequest struct {
+	Stack string `json:"stack"`
+}
+
 type deployRequest struct {
 	Stack    string `json:"stack"`
 	Tag      string `json:"tag"`
 	ImageTag string `json:"image_tag"`
 }
 
-func New(cfg config.Config, runner *runner.Runner) http.Handler {
-	h := &Handler{cfg: cfg, runner: runner}
+// New returns the stackrd API. GET /metrics serves registry, and is only
+// routed when registry is non-nil. A nil logger logs to slog.Default().
+func New(cfg config.Config, runner *runner.Runner, scheduler *cronjobs.Scheduler, build BuildInfo, registry *prometheus.Registry, logger *slog.Logger) *Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	h := &Handler{
+		cfg:        cfg,
+		build:      build,
+		runner:     runner,
+		deploy:     runner.Deploy,
+		rollback:   runner.Rollback,
+		jobs:       newJobStore(jobTTL),
+		jobsCtx:    jobsCtx,
+		cancelJobs: cancelJobs,
+		audit:      audit.New(cfg.Global.Audit.LogPath(cfg.RepoRoot)),
+		cron:       scheduler,
+		loadConfig: config.Load,
+		logger:     logger,
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", h.handleHealth)
+	mux.HandleFunc("/version", h.handleVersion)
 	mux.HandleFunc("/deploy", h.handleDeploy)
+	mux.HandleFunc("/deploy/status/", h.handleDeployStatus)
+	mux.HandleFunc("/rollback", h.handleRollback)
+	mux.HandleFunc("/stacks", h.handleStacks)
+	mux.HandleFunc("/cron/history", h.handleCronHistory)
+	mux.HandleFunc("/cron/run", h.handleCronRun)
+	mux.HandleFunc("/admin/token/rotate", h.handleRotateToken)
+	mux.HandleFunc("/admin/reload", h.handleReload)
+	if registry != nil {
+		h.metrics = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+		mux.HandleFunc("/metrics", h.handleMetrics)
+	}
 	h.mux = mux
 	return h
 }
@@ -57,6 +169,20 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.mux.ServeHTTP(w, r)
 }
 
+// config returns the current config; /admin/reload may replace it.
+func (h *Handler) config() config.Config {
+	h.cfgMu.RLock()
+	defer h.cfgMu.RUnlock()
+	return h.cfg
+}
+
+// auditLog returns the audit log of the current config.
+func (h *Handler) auditLog() *audit.Log {
+	h.cfgMu.RLock()
+	defer h.cfgMu.RUnlock()
+	return h.audit
+}
+
 func (h *Handler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.Header().Set("Allow", http.MethodGet)
@@ -74,12 +200,19 @@ func (h *Handler) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !h.authorize(r.Header.Get("Authorization")) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read request body"})
+		return
+	}
+
+	allowed, ok := h.authorizeDeploy(r.Header, body)
+	if !ok {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
 		return
 	}
 
-	payload, err := decodeDeployRequest(r.Body)
+	payload, err := decodeDeployRequest(bytes.NewReader(body))
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
@@ -91,6 +224,11 @@ func (h *Handler) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !allowsStack(allowed, stackName) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "token is not allowed to deploy this stack"})
+		return
+	}
+
 	if err := h.ensureStackExists(stackName); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
@@ -124,31 +262,517 @@ func (h *Handler) handleDeploy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate tag: must be "latest" or semver format (v1.2.3 or v1.2.3-prerelease)
-	if tag != "latest" && !semverPattern.MatchString(tag) {
+	if !stackcmd.ValidTag(tag) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "tag must be 'latest' or semver format (v1.2.3 or v1.2.3-prerelease)"})
 		return
 	}
 
-	result, err := h.runner.Deploy(r.Context(), stackName, stackCfg, tag)
+	async, err := parseAsync(r)
 	if err != nil {
-		var cmdErr *runner.CommandError
-		if errors.As(err, &cmdErr) {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{
-				"error":     cmdErr.Msg,
-				"exit_code": fmt.Sprintf("%d", cmdErr.Code),
-				"stdout":    strings.TrimSpace(cmdErr.Stdout),
-				"stderr":    strings.TrimSpace(cmdErr.Stderr),
-			})
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	entry := h.newAuditEntry(r, stackName, tag)
+	if async {
+		job, err := h.jobs.create(stackName, tag)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to create deploy job: %v", err)})
 			return
 		}
+		h.logger.Info("queued async deployment", "job", job.ID, "stack", stackName, "tag", tag, "status", "queued")
+		entry.JobID = job.ID
+		h.jobsWG.Add(1)
+		go h.runDeployJob(job.ID, stackName, stackCfg, tag, entry)
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
 
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	result, err := h.deploy(r.Context(), stackName, stackCfg, tag)
+	h.auditDeploy(entry, err)
+	if err != nil {
+		writeDeployError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeDeployError responds to a failed deploy with status 500, including
+// the command output when the deploy command itself failed.
+func writeDeployError(w http.ResponseWriter, err error) {
+	var cmdErr *runner.CommandError
+	if errors.As(err, &cmdErr) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"error":     cmdErr.Msg,
+			"exit_code": fmt.Sprintf("%d", cmdErr.Code),
+			"stdout":    strings.TrimSpace(cmdErr.Stdout),
+			"stderr":    strings.TrimSpace(cmdErr.Stderr),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
+// handleRollback redeploys the tag a stack ran before its last successful
+// deploy. It is authorized like /deploy and responds with the deploy result,
+// whose tag is the one rolled back to.
+func (h *Handler) handleRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read request body"})
+		return
+	}
+
+	allowed, ok := h.authorizeDeploy(r.Header, body)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	var payload rollbackRequest
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	stackName := strings.TrimSpace(payload.Stack)
+	if stackName == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "stack is required"})
+		return
+	}
+
+	if !allowsStack(allowed, stackName) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "token is not allowed to deploy this stack"})
+		return
+	}
+
+	if err := h.ensureStackExists(stackName); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	enabled, err := h.isAutoDeployEnabled(stackName)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to check auto-deploy status: %v", err)})
+		return
+	}
+	if !enabled {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "auto-deployment is disabled for this stack"})
+		return
+	}
+
+	stackCfg := config.StackConfig{
+		TagEnv: strings.ToUpper(stackName) + "_IMAGE_TAG",
+		Args:   []string{stackName, "update"},
+	}
+
+	result, err := h.rollback(r.Context(), stackName, stackCfg)
+	if errors.Is(err, runner.ErrNoPreviousTag) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	entry := h.newAuditEntry(r, stackName, "")
+	if result != nil {
+		entry.Tag = result.Tag
+	}
+	h.auditDeploy(entry, err)
+	if err != nil {
+		writeDeployError(w, err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, result)
 }
 
+// parseAsync reads the optional async query parameter of /deploy.
+func parseAsync(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("async")
+	if value == "" {
+		return false, nil
+	}
+	async, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("async must be true or false")
+	}
+	return async, nil
+}
+
+// runDeployJob runs an async deploy detached from the request, recording its
+// outcome in the job store. The job is marked running once the deploy leaves
+// the runner's queue; Shutdown cancels it.
+func (h *Handler) runDeployJob(id, stack string, stackCfg config.StackConfig, tag string, entry audit.Entry) {
+	defer h.jobsWG.Done()
+
+	ctx := runner.WithStarted(h.jobsCtx, func() { h.jobs.start(id) })
+	result, err := h.deploy(ctx, stack, stackCfg, tag)
+	h.auditDeploy(entry, err)
+	if err != nil {
+		h.logger.Error("async deployment failed", "job", id, "stack", stack, "tag", tag, "status", "failed", "error", err)
+		h.jobs.finish(id, err.Error(), "")
+		return
+	}
+
+	h.jobs.finish(id, "", result.Stdout)
+}
+
+// Shutdown cancels the async deploys that are still queued or running and
+// waits for them to return, so a cancelled deploy still restores the .env it
+// changed. It gives up once ctx is done.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.cancelJobs()
+
+	done := make(chan struct{})
+	go func() {
+		h.jobsWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("async deploys still running: %w", ctx.Err())
+	}
+}
+
+// auditDeploy records the outcome of a deploy in the audit log. A failed
+// write is logged but does not fail the deploy.
+func (h *Handler) auditDeploy(entry audit.Entry, deployErr error) {
+	entry.Result = "succeeded"
+	if deployErr != nil {
+		entry.Result = "failed"
+		entry.Error = deployErr.Error()
+	}
+	if err := h.auditLog().Record(entry); err != nil {
+		h.logger.Warn("failed to write audit log", "stack", entry.Stack, "tag", entry.Tag, "error", err)
+	}
+}
+
+func (h *Handler) handleDeployStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	// Anyone allowed to deploy a stack may follow its jobs
+	allowed, ok := h.authorizeDeploy(r.Header, nil)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/deploy/status/")
+	job, ok := h.jobs.get(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	if !allowsStack(allowed, job.Stack) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "token is not allowed to deploy this stack"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleStacks lists the discovered stacks, with the sync status of remote
+// stacks, so clients can see what is deployed without shell access.
+func (h *Handler) handleStacks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !h.authorize(r.Header.Get("Authorization")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	cfg := h.config()
+	stacks, err := stackcmd.DiscoverStacks(cfg)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	summaries := make([]stackSummary, 0, len(stacks))
+	for _, stack := range stacks {
+		summary := stackSummary{
+			Name:       stack.Name,
+			Type:       stack.Type,
+			HasCompose: fileExists(stack.PrimaryComposePath()),
+		}
+		if stack.Type == stackcmd.StackTypeRemote {
+			status, err := stackcmd.GetRemoteStackStatus(cfg, stack.Name)
+			if err != nil {
+				summary.Error = err.Error()
+			} else {
+				summary.ConfiguredRef = status.ConfiguredRef
+				summary.CurrentVersion = status.CurrentVersion
+				summary.Error = status.Error
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// handleMetrics serves the Prometheus metrics, behind the bearer token when
+// http.metrics_require_token is set.
+func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if h.config().Global.HTTP.MetricsRequireToken && !h.authorize(r.Header.Get("Authorization")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	h.metrics.ServeHTTP(w, r)
+}
+
+// handleVersion reports the daemon build and the config file it loaded.
+func (h *Handler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !h.authorize(r.Header.Get("Authorization")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, versionResponse{
+		Version:    h.build.Version,
+		Commit:     h.build.Commit,
+		Date:       h.build.Date,
+		ConfigPath: h.config().Global.Path,
+	})
+}
+
+// handleCronHistory lists the recent cron job runs, newest first.
+func (h *Handler) handleCronHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !h.authorize(r.Header.Get("Authorization")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	runs := h.cron.History()
+	if runs == nil {
+		runs = []cronjobs.JobRun{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// handleCronRun runs a cron job synchronously and returns its run record.
+// A failed job is reported with status 500.
+func (h *Handler) handleCronRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !h.authorize(r.Header.Get("Authorization")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	var payload cronRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	payload.Stack = strings.TrimSpace(payload.Stack)
+	payload.Service = strings.TrimSpace(payload.Service)
+	if payload.Service == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "stack and service are required"})
+		return
+	}
+	if err := validateStackName(payload.Stack); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if h.cron == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "cron scheduler is not running"})
+		return
+	}
+
+	run, err := h.cron.RunJob(payload.Stack, payload.Service, payload.Command)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, cronjobs.ErrJobNotFound) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if !run.Success {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, run)
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func (h *Handler) handleRotateToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !h.authorize(r.Header.Get("Authorization")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	var payload rotateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+
+	newToken := strings.TrimSpace(payload.Token)
+	if newToken == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token is required"})
+		return
+	}
+	if strings.ContainsAny(newToken, " \t\r\n") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "token must not contain whitespace"})
+		return
+	}
+
+	h.cfgMu.Lock()
+	defer h.cfgMu.Unlock()
+
+	if h.cfg.TokenFile != "" {
+		if err := writeTokenFile(h.cfg.TokenFile, newToken); err != nil {
+			h.logger.Error("failed to persist rotated token", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to persist token"})
+			return
+		}
+	}
+
+	h.cfg.Token = newToken
+	h.logger.Info("API token rotated")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReload reloads .stackr.yaml and the STACKR_* settings and applies
+// them to the API, the runner and the cron scheduler, without a restart. A
+// config that fails to load or whose cron jobs can't be discovered is
+// rejected and the old one stays in use, as is one that moves stacks_dir,
+// which the daemon's watcher is bound to. The listen address only changes on
+// a restart.
+func (h *Handler) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !h.authorize(r.Header.Get("Authorization")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+		return
+	}
+
+	// Reloading cron waits for running jobs, so cfgMu is only taken for the
+	// final swap; requests keep being served meanwhile
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	current := h.config()
+	cfg, err := h.loadConfig(current.RepoRoot)
+	if err != nil {
+		h.logger.Warn("config reload rejected", "error", err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("failed to load config: %v", err)})
+		return
+	}
+	if cfg.StacksDir != current.StacksDir {
+		h.logger.Warn("config reload rejected", "error", "stacks_dir changed", "stacks_dir", cfg.StacksDir)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "stacks_dir changes need a restart"})
+		return
+	}
+
+	if err := h.cron.ReloadConfig(cfg); err != nil {
+		h.logger.Warn("config reload rejected", "error", err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("failed to reload cron jobs: %v", err)})
+		return
+	}
+	if h.runner != nil {
+		h.runner.SetConfig(cfg)
+	}
+
+	h.cfgMu.Lock()
+	// A token rotated without a token file only lives in memory; keep it
+	if cfg.TokenFile == "" && h.cfg.TokenFile == "" {
+		cfg.Token = h.cfg.Token
+	}
+	h.cfg = cfg
+	h.audit = audit.New(cfg.Global.Audit.LogPath(cfg.RepoRoot))
+	h.cfgMu.Unlock()
+
+	h.logger.Info("config reloaded", "path", cfg.Global.Path)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "config_path": cfg.Global.Path})
+}
+
+// writeTokenFile atomically replaces the token file so a crash mid-write
+// never leaves the daemon without a readable token on restart.
+func writeTokenFile(path, token string) error {
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".stackr-token-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.WriteString(token + "\n"); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
 func decodeDeployRequest(body io.Reader) (deployRequest, error) {
 	payload := deployRequest{}
 	data, err := io.ReadAll(body)
@@ -172,7 +796,61 @@ func (h *Handler) authorize(header string) bool {
 	}
 
 	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
-	return subtle.ConstantTimeCompare([]byte(token), []byte(h.cfg.Token)) == 1
+
+	expected := h.config().Token
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// allStacks is the deploy scope of the admin token and of signed webhooks.
+var allStacks = []string{"*"}
+
+// authorizeDeploy accepts a valid webhook signature when a webhook secret is
+// configured and the request is signed, and a bearer token otherwise. It
+// returns the stacks the caller may deploy: all of them for the admin token,
+// the configured ones for a scoped deploy token.
+func (h *Handler) authorizeDeploy(header http.Header, body []byte) ([]string, bool) {
+	cfg := h.config()
+	if signature := header.Get(signatureHeader); signature != "" && cfg.WebhookSecret != "" {
+		return allStacks, verifySignature(cfg.WebhookSecret, signature, body)
+	}
+	auth := header.Get("Authorization")
+	if h.authorize(auth) {
+		return allStacks, true
+	}
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return nil, false
+	}
+	token = strings.TrimSpace(token)
+	// Compare against every token so timing doesn't reveal which one matched
+	var allowed []string
+	for candidate, stacks := range cfg.DeployTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(candidate)) == 1 {
+			allowed = stacks
+		}
+	}
+	return allowed, allowed != nil
+}
+
+// allowsStack reports whether a deploy scope includes stack.
+func allowsStack(allowed []string, stack string) bool {
+	return slices.Contains(allowed, "*") || slices.Contains(allowed, stack)
+}
+
+// verifySignature checks a "sha256=<hex>" HMAC-SHA256 of body in constant time.
+func verifySignature(secret, signature string, body []byte) bool {
+	sum, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
@@ -184,7 +862,8 @@ func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	}
 
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		log.Printf("failed to write JSON response: %v", err)
+		// Usually a client that went away; not tied to a handler's logger
+		slog.Warn("failed to write JSON response", "error", err)
 	}
 }
 
@@ -207,7 +886,7 @@ func (h *Handler) ensureStackExists(name string) error {
 		return err
 	}
 
-	stackDir := filepath.Join(h.cfg.StacksDir, name)
+	stackDir := filepath.Join(h.config().StacksDir, name)
 	info, err := os.Stat(stackDir)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -220,10 +899,10 @@ func (h *Handler) ensureStackExists(name string) error {
 	}
 
 	// Accept any recognized stack marker:
-	// - docker-compose.yml (local)
+	// - a compose file (local, docker-compose.yml or compose.yaml)
 	// - stackr/config.yaml (new unified config)
 	// - stackr-repo.yml (legacy remote)
-	composePath := filepath.Join(stackDir, "docker-compose.yml")
+	composePath := filepath.Join(stackDir, config.DetectComposeFiles(stackDir)[0])
 	newCfgPath := filepath.Join(stackDir, "stackr", "config.yaml")
 	legacyDefPath := filepath.Join(stackDir, "stackr-repo.yml")
 	if _, err := os.Stat(composePath); err != nil {
@@ -240,7 +919,8 @@ func (h *Handler) ensureStackExists(name string) error {
 // Returns false if ANY service has stackr.deploy.auto=false (or env var resolving to false)
 // Defaults to true if label is not present
 func (h *Handler) isAutoDeployEnabled(stackName string) (bool, error) {
-	stackDir := filepath.Join(h.cfg.StacksDir, stackName)
+	cfg := h.config()
+	stackDir := filepath.Join(cfg.StacksDir, stackName)
 	localCfg, err := config.LoadStackLocalConfig(stackDir)
 	if err != nil {
 		return false, fmt.Errorf("failed to load stack config: %w", err)
@@ -253,9 +933,9 @@ func (h *Handler) isAutoDeployEnabled(stackName string) (bool, error) {
 	// For remote stacks the compose file lives in the cloned repo dir.
 	var composePath string
 	if localCfg.IsRemote() {
-		remoteRepoDir := h.cfg.Global.RemoteStacksDir
+		remoteRepoDir := cfg.Global.RemoteStacksDir
 		if !filepath.IsAbs(remoteRepoDir) {
-			remoteRepoDir = filepath.Join(h.cfg.RepoRoot, remoteRepoDir)
+			remoteRepoDir = filepath.Join(cfg.RepoRoot, remoteRepoDir)
 		}
 		baseDir := filepath.Join(remoteRepoDir, stackName)
 		if localCfg.RemoteRepo.Path != "" && localCfg.RemoteRepo.Path != "." {
@@ -278,7 +958,7 @@ func (h *Handler) isAutoDeployEnabled(stackName string) (bool, error) {
 	// Load .env file for variable resolution
 	envVars, err := h.loadEnvFile()
 	if err != nil {
-		log.Printf("warning: failed to load .env file for auto-deploy check: %v", err)
+		h.logger.Warn("failed to load .env file for auto-deploy check", "stack", stackName, "error", err)
 		envVars = make(map[string]string)
 	}
 
@@ -296,13 +976,13 @@ func (h *Handler) isAutoDeployEnabled(stackName string) (bool, error) {
 		// Parse as boolean
 		enabled, err := strconv.ParseBool(resolvedValue)
 		if err != nil {
-			log.Printf("warning: invalid %s value for stack=%s service=%s: %q, treating as disabled",
-				autoDeployLabel, stackName, serviceName, resolvedValue)
+			h.logger.Warn("invalid label value, treating as disabled",
+				"stack", stackName, "service", serviceName, "label", autoDeployLabel, "value", resolvedValue)
 			return false, nil
 		}
 
 		if !enabled {
-			log.Printf("auto-deployment disabled for stack=%s service=%s", stackName, serviceName)
+			h.logger.Info("auto-deployment disabled", "stack", stackName, "service", serviceName)
 			return false, nil
 		}
 	}
@@ -312,9 +992,10 @@ func (h *Handler) isAutoDeployEnabled(stackName string) (bool, error) {
 
 // loadEnvFile reads the .env file and returns a map of environment variables
 func (h *Handler) loadEnvFile() (map[string]string, error) {
-	envPath := h.cfg.EnvFile
+	cfg := h.config()
+	envPath := cfg.EnvFile
 	if envPath == "" {
-		envPath = filepath.Join(h.cfg.RepoRoot, ".env")
+		envPath = filepath.Join(cfg.RepoRoot, ".env")
 	}
 
 	content, err := os.ReadFile(envPath)
@@ -46,7 +46,7 @@ func setupHTTPTest(t *testing.T, opts ...testutil.RepoOption) (*httptest.Server,
 	cfg.Token = testToken
 
 	r := runner.New(cfg)
-	handler := New(cfg, r)
+	handler := New(cfg, r, nil, BuildInfo{}, nil, nil)
 	server := httptest.NewServer(handler)
 	t.Cleanup(server.Close)
 
@@ -209,7 +209,7 @@ func TestDeployEndpointCreatesStack(t *testing.T) {
 	cfg.Token = testToken
 
 	r := runner.New(cfg)
-	handler := New(cfg, r)
+	handler := New(cfg, r, nil, BuildInfo{}, nil, nil)
 	server := httptest.NewServer(handler)
 	t.Cleanup(server.Close)
 
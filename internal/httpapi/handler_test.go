@@ -1,13 +1,27 @@
 package httpapi
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 
+	"github.com/jamestiberiuskirk/stackr/internal/audit"
 	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/cronjobs"
+	"github.com/jamestiberiuskirk/stackr/internal/metrics"
+	"github.com/jamestiberiuskirk/stackr/internal/runner"
 )
 
 func TestIsAutoDeployEnabled(t *testing.T) {
@@ -143,7 +157,7 @@ services:
 				EnvFile:   envPath,
 			}
 
-			h := &Handler{cfg: cfg}
+			h := &Handler{cfg: cfg, logger: slog.Default()}
 			enabled, err := h.isAutoDeployEnabled(tt.stackName)
 
 			if tt.wantErr {
@@ -216,7 +230,7 @@ func TestLoadEnvFile(t *testing.T) {
 				EnvFile:  envPath,
 			}
 
-			h := &Handler{cfg: cfg}
+			h := &Handler{cfg: cfg, logger: slog.Default()}
 			vars, err := h.loadEnvFile()
 
 			if tt.wantErr {
@@ -288,7 +302,7 @@ func TestEnsureStackExists(t *testing.T) {
 	emptyStack := filepath.Join(stacksDir, "empty")
 	require.NoError(t, os.MkdirAll(emptyStack, 0o755))
 
-	h := &Handler{cfg: config.Config{StacksDir: stacksDir}}
+	h := &Handler{cfg: config.Config{StacksDir: stacksDir}, logger: slog.Default()}
 
 	t.Run("local stack succeeds", func(t *testing.T) {
 		require.NoError(t, h.ensureStackExists("valid"))
@@ -322,7 +336,7 @@ func TestEnsureStackExists(t *testing.T) {
 }
 
 func TestAuthorize(t *testing.T) {
-	h := &Handler{cfg: config.Config{Token: "correct-token"}}
+	h := &Handler{cfg: config.Config{Token: "correct-token"}, logger: slog.Default()}
 
 	tests := []struct {
 		name   string
@@ -350,9 +364,52 @@ func TestAuthorize(t *testing.T) {
 	}
 }
 
+func TestDeployWebhookSignature(t *testing.T) {
+	const secret = "webhook-secret"
+	body := `{"stack":"missing","tag":"v1.0.0"}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	deploy := func(cfg config.Config, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/deploy", strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		New(cfg, nil, nil, BuildInfo{}, nil, nil).ServeHTTP(rec, req)
+		return rec
+	}
+	cfg := config.Config{Token: "token", WebhookSecret: secret, StacksDir: t.TempDir()}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		headers map[string]string
+		want    int
+	}{
+		// Authorized requests get as far as the stack lookup
+		{name: "valid signature", cfg: cfg, headers: map[string]string{"X-Hub-Signature-256": valid}, want: http.StatusBadRequest},
+		{name: "bearer without signature", cfg: cfg, headers: map[string]string{"Authorization": "Bearer token"}, want: http.StatusBadRequest},
+		{name: "no secret falls back to bearer", cfg: config.Config{Token: "token", StacksDir: cfg.StacksDir}, headers: map[string]string{"X-Hub-Signature-256": "sha256=00", "Authorization": "Bearer token"}, want: http.StatusBadRequest},
+		{name: "invalid signature", cfg: cfg, headers: map[string]string{"X-Hub-Signature-256": "sha256=" + strings.Repeat("0", 64)}, want: http.StatusUnauthorized},
+		{name: "invalid signature not rescued by bearer", cfg: cfg, headers: map[string]string{"X-Hub-Signature-256": "sha256=00", "Authorization": "Bearer token"}, want: http.StatusUnauthorized},
+		{name: "signature without prefix", cfg: cfg, headers: map[string]string{"X-Hub-Signature-256": strings.TrimPrefix(valid, "sha256=")}, want: http.StatusUnauthorized},
+		{name: "signature not hex", cfg: cfg, headers: map[string]string{"X-Hub-Signature-256": "sha256=zz"}, want: http.StatusUnauthorized},
+		{name: "no credentials", cfg: cfg, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := deploy(tt.cfg, tt.headers)
+			require.Equal(t, tt.want, rec.Code, rec.Body.String())
+		})
+	}
+}
+
 func TestResolveEnvVars(t *testing.T) {
 	t.Helper()
-	h := &Handler{}
+	h := &Handler{logger: slog.Default()}
 	envVars := map[string]string{
 		"FOO": "bar",
 		"BAZ": "qux",
@@ -403,3 +460,398 @@ func TestResolveEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestRotateToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("old-token\n"), 0o600))
+
+	handler := New(config.Config{Token: "old-token", TokenFile: tokenFile}, nil, nil, BuildInfo{}, nil, nil)
+
+	rotate := func(auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/token/rotate", strings.NewReader(body))
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("rejects wrong token", func(t *testing.T) {
+		rec := rotate("Bearer nope", `{"token":"hijacked"}`)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("rejects empty new token", func(t *testing.T) {
+		rec := rotate("Bearer old-token", `{"token":"  "}`)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("rotates and persists", func(t *testing.T) {
+		rec := rotate("Bearer old-token", `{"token":"new-token"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		data, err := os.ReadFile(tokenFile)
+		require.NoError(t, err)
+		require.Equal(t, "new-token", strings.TrimSpace(string(data)))
+	})
+
+	t.Run("old token rejected, new token accepted", func(t *testing.T) {
+		rec := rotate("Bearer old-token", `{"token":"another"}`)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		require.False(t, handler.authorize("Bearer old-token"))
+		require.True(t, handler.authorize("Bearer new-token"))
+	})
+}
+
+func TestListStacks(t *testing.T) {
+	tmpDir := t.TempDir()
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "web"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "web", "docker-compose.yml"), []byte("services: {}\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "app"), 0o755))
+	repoDef := `
+remote_repo:
+  url: git@github.com:org/app.git
+  branch: main
+  release:
+    type: tag
+    ref: v1.2.0
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "app", "stackr-repo.yml"), []byte(repoDef), 0o644))
+
+	cfg := config.Config{Token: "secret", RepoRoot: tmpDir, StacksDir: stacksDir}
+	cfg.Global.RemoteStacksDir = ".stackr-repos"
+	handler := New(cfg, nil, nil, BuildInfo{}, nil, nil)
+
+	list := func(method, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/stacks", nil)
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, list(http.MethodGet, "Bearer nope").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, list(http.MethodPost, "Bearer secret").Code)
+
+	rec := list(http.MethodGet, "Bearer secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stacks []stackSummary
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stacks))
+	require.Equal(t, []stackSummary{
+		{Name: "app", Type: "remote", HasCompose: false, ConfiguredRef: "v1.2.0"},
+		{Name: "web", Type: "local", HasCompose: true},
+	}, stacks)
+}
+
+func TestCronHistory(t *testing.T) {
+	handler := New(config.Config{Token: "secret"}, nil, nil, BuildInfo{}, nil, nil)
+
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/cron/history", nil)
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, get("Bearer nope").Code)
+
+	rec := get("Bearer secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, "[]", rec.Body.String(), "no scheduler means no runs, not null")
+}
+
+func TestVersion(t *testing.T) {
+	cfg := config.Config{Token: "secret"}
+	cfg.Global.Path = "/srv/stackr_repo/.stackr.yaml"
+	handler := New(cfg, nil, nil, BuildInfo{Version: "v1.4.0", Commit: "abc1234", Date: "2026-01-02T03:04:05Z"}, nil, nil)
+
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/version", nil)
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, get("Bearer nope").Code)
+
+	rec := get("Bearer secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{
+		"version": "v1.4.0",
+		"commit": "abc1234",
+		"date": "2026-01-02T03:04:05Z",
+		"config_path": "/srv/stackr_repo/.stackr.yaml"
+	}`, rec.Body.String())
+}
+
+func TestCronRun(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "db")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  backup:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@daily
+`), 0o644))
+
+	// docker stub: "run" echoes its command and fails when asked to
+	binDir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *\" run \"*fail*) echo 'dump failed' >&2; exit 2 ;; *\" run \"*) echo 'dump ok' ;; esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		Token:     "secret",
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	scheduler, err := cronjobs.New(cfg)
+	require.NoError(t, err)
+	handler := New(cfg, nil, scheduler, BuildInfo{}, nil, nil)
+
+	run := func(auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/cron/run", strings.NewReader(body))
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusUnauthorized, run("Bearer nope", `{"stack":"db","service":"backup"}`).Code)
+	require.Equal(t, http.StatusBadRequest, run("Bearer secret", `{"stack":"db"}`).Code)
+	require.Equal(t, http.StatusNotFound, run("Bearer secret", `{"stack":"db","service":"vacuum"}`).Code)
+
+	rec := run("Bearer secret", `{"stack":"db","service":"backup"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result cronjobs.JobRun
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.True(t, result.Success)
+	require.Equal(t, "exit status 0", result.ExitStatus)
+	require.Contains(t, result.Output, "dump ok")
+
+	rec = run("Bearer secret", `{"stack":"db","service":"backup","command":["sh","-c","fail"]}`)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.False(t, result.Success)
+	require.Equal(t, "exit status 2: dump failed", result.ExitStatus)
+
+	require.Len(t, scheduler.History(), 2, "HTTP runs are recorded in the cron history")
+}
+
+func TestMetrics(t *testing.T) {
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "db")
+	require.NoError(t, os.MkdirAll(stackDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "docker-compose.yml"), []byte(`
+services:
+  backup:
+    image: busybox
+    labels:
+      - stackr.cron.schedule=@daily
+`), 0o644))
+
+	binDir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *\" run \"*fail*) exit 2 ;; esac\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		Token:     "secret",
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+	registry := prometheus.NewRegistry()
+	m := metrics.New(registry, cfg)
+	scheduler, err := cronjobs.New(cfg)
+	require.NoError(t, err)
+	scheduler.SetMetrics(m)
+
+	do := func(handler http.Handler, method, path, auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	handler := New(cfg, nil, scheduler, BuildInfo{}, registry, nil)
+	require.Equal(t, http.StatusOK, do(handler, http.MethodPost, "/cron/run", "Bearer secret", `{"stack":"db","service":"backup"}`).Code)
+	require.Equal(t, http.StatusInternalServerError, do(handler, http.MethodPost, "/cron/run", "Bearer secret", `{"stack":"db","service":"backup","command":["fail"]}`).Code)
+	m.DeployFinished("db", nil)
+
+	rec := do(handler, http.MethodGet, "/metrics", "", "")
+	require.Equal(t, http.StatusOK, rec.Code, "metrics are open by default")
+	require.Contains(t, rec.Body.String(), `stackr_cron_job_executions_total{service="backup",stack="db"} 2`)
+	require.Contains(t, rec.Body.String(), `stackr_cron_job_failures_total{service="backup",stack="db"} 1`)
+	require.Contains(t, rec.Body.String(), `stackr_deploys_total{stack="db",status="succeeded"} 1`)
+	require.Equal(t, http.StatusMethodNotAllowed, do(handler, http.MethodPost, "/metrics", "", "").Code)
+
+	cfg.Global.HTTP.MetricsRequireToken = true
+	handler = New(cfg, nil, scheduler, BuildInfo{}, registry, nil)
+	require.Equal(t, http.StatusUnauthorized, do(handler, http.MethodGet, "/metrics", "", "").Code)
+	require.Equal(t, http.StatusOK, do(handler, http.MethodGet, "/metrics", "Bearer secret", "").Code)
+
+	handler = New(cfg, nil, nil, BuildInfo{}, nil, nil)
+	require.Equal(t, http.StatusNotFound, do(handler, http.MethodGet, "/metrics", "Bearer secret", "").Code)
+}
+
+func TestDeployScopedTokens(t *testing.T) {
+	stacksDir := filepath.Join(t.TempDir(), "stacks")
+	for _, stack := range []string{"web", "api"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, stack), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, stack, "docker-compose.yml"), []byte("services:\n  app:\n    image: app\n"), 0o644))
+	}
+
+	cfg := config.Config{
+		Token:     "admin",
+		StacksDir: stacksDir,
+		DeployTokens: map[string][]string{
+			"web-ci": {"web"},
+			"ops-ci": {"*"},
+		},
+	}
+	h := New(cfg, nil, nil, BuildInfo{}, nil, nil)
+	var deployed []string
+	h.deploy = func(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*runner.Result, error) {
+		deployed = append(deployed, stack)
+		return runner.NewResult(stack, tag, ""), nil
+	}
+
+	do := func(method, path, token, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	deploy := func(token, stack string) int {
+		return do(http.MethodPost, "/deploy", token, `{"stack":"`+stack+`","tag":"v1.0.0"}`)
+	}
+
+	require.Equal(t, http.StatusOK, deploy("web-ci", "web"))
+	require.Equal(t, http.StatusForbidden, deploy("web-ci", "api"))
+	require.Equal(t, http.StatusForbidden, deploy("web-ci", "missing"), "an out of scope stack is refused before it is looked up")
+	require.Equal(t, http.StatusOK, deploy("ops-ci", "api"))
+	require.Equal(t, http.StatusOK, deploy("admin", "api"))
+	require.Equal(t, http.StatusUnauthorized, deploy("nope", "web"))
+	require.Equal(t, []string{"web", "api", "api"}, deployed)
+
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/stacks", "ops-ci", ""), "deploy tokens only work for /deploy")
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/stacks", "admin", ""))
+}
+
+func TestRollback(t *testing.T) {
+	stacksDir := filepath.Join(t.TempDir(), "stacks")
+	for _, stack := range []string{"web", "api"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, stack), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, stack, "docker-compose.yml"), []byte("services:\n  app:\n    image: app\n"), 0o644))
+	}
+
+	cfg := config.Config{
+		Token:        "admin",
+		StacksDir:    stacksDir,
+		DeployTokens: map[string][]string{"web-ci": {"web"}},
+	}
+	h := New(cfg, nil, nil, BuildInfo{}, nil, nil)
+	h.rollback = func(ctx context.Context, stack string, stackCfg config.StackConfig) (*runner.Result, error) {
+		require.Equal(t, strings.ToUpper(stack)+"_IMAGE_TAG", stackCfg.TagEnv)
+		if stack == "api" {
+			return nil, runner.ErrNoPreviousTag
+		}
+		return runner.NewResult(stack, "v1.1.0", ""), nil
+	}
+
+	do := func(method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/rollback", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "web-ci", `{"stack":"web"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result runner.Result
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.Equal(t, "v1.1.0", result.Tag)
+
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, "web-ci", `{"stack":"api"}`).Code)
+	require.Equal(t, http.StatusConflict, do(http.MethodPost, "admin", `{"stack":"api"}`).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "admin", `{}`).Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "nope", `{"stack":"web"}`).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "admin", "").Code)
+}
+
+func TestReloadConfig(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks", "web"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "stacks", "web", "docker-compose.yml"), []byte("services:\n  app:\n    image: app\n"), 0o644))
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, ".stackr.yaml"), []byte(content), 0o644))
+	}
+	writeConfig("http:\n  tokens:\n    old-ci: [web]\n")
+
+	t.Setenv("STACKR_TOKEN", "admin")
+	cfg, err := config.Load(repo)
+	require.NoError(t, err)
+	h := New(cfg, nil, nil, BuildInfo{}, nil, nil)
+	h.deploy = func(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*runner.Result, error) {
+		return runner.NewResult(stack, tag, ""), nil
+	}
+
+	do := func(path, token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	deploy := func(token string) int {
+		return do("/deploy", token, `{"stack":"web","tag":"v1.0.0"}`)
+	}
+
+	require.Equal(t, http.StatusOK, deploy("old-ci"))
+	require.Equal(t, http.StatusUnauthorized, deploy("new-ci"))
+
+	writeConfig("http:\n  tokens:\n    new-ci: [web]\n")
+	require.Equal(t, http.StatusUnauthorized, deploy("new-ci"), "config changes need a reload")
+	require.Equal(t, http.StatusUnauthorized, do("/admin/reload", "old-ci", ""), "deploy tokens cannot reload")
+	require.Equal(t, http.StatusOK, do("/admin/reload", "admin", ""))
+	require.Equal(t, http.StatusOK, deploy("new-ci"))
+	require.Equal(t, http.StatusUnauthorized, deploy("old-ci"))
+
+	// An invalid config is rejected and the current one stays in use
+	writeConfig("pull_policy: sometimes\n")
+	require.Equal(t, http.StatusBadRequest, do("/admin/reload", "admin", ""))
+	require.Equal(t, http.StatusOK, deploy("new-ci"))
+
+	// A rotated token survives a reload
+	writeConfig("")
+	require.Equal(t, http.StatusOK, do("/admin/token/rotate", "admin", `{"token":"rotated"}`))
+	require.Equal(t, http.StatusOK, do("/admin/reload", "rotated", ""))
+	require.Equal(t, http.StatusOK, do("/admin/reload", "rotated", ""))
+
+	// Deploys are audited to the reloaded audit log
+	writeConfig("audit:\n  log: reloaded-audit.jsonl\n")
+	require.Equal(t, http.StatusOK, do("/admin/reload", "rotated", ""))
+	require.Equal(t, http.StatusOK, deploy("rotated"))
+	entries, err := audit.Read(filepath.Join(repo, "reloaded-audit.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Moving stacks_dir needs a restart
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "other-stacks"), 0o755))
+	writeConfig("stacks_dir: other-stacks\n")
+	require.Equal(t, http.StatusBadRequest, do("/admin/reload", "rotated", ""))
+	require.Equal(t, filepath.Join(repo, "stacks"), h.config().StacksDir)
+}
@@ -0,0 +1,132 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// jobTTL is how long a finished async deploy stays queryable.
+const jobTTL = time.Hour
+
+type jobStatus string
+
+const (
+	jobQueued    jobStatus = "queued"
+	jobRunning   jobStatus = "running"
+	jobSucceeded jobStatus = "succeeded"
+	jobFailed    jobStatus = "failed"
+)
+
+// deployJob is the state of an async deploy, as returned by
+// GET /deploy/status/{id}.
+type deployJob struct {
+	ID     string    `json:"id"`
+	Stack  string    `json:"stack"`
+	Tag    string    `json:"tag"`
+	Status jobStatus `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	// Stdout is the output of a succeeded deploy. A failed deploy's output
+	// is not kept, it can contain secrets.
+	Stdout     string     `json:"stdout,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// jobStore keeps async deploy jobs in memory. Finished jobs are swept once
+// they are older than the TTL.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*deployJob
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+func newJobStore(ttl time.Duration) *jobStore {
+	return &jobStore{
+		jobs: make(map[string]*deployJob),
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// create registers a queued job and returns a copy of it.
+func (s *jobStore) create(stack, tag string) (deployJob, error) {
+	id, err := newJobID()
+	if err != nil {
+		return deployJob{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked()
+
+	job := &deployJob{
+		ID:        id,
+		Stack:     stack,
+		Tag:       tag,
+		Status:    jobQueued,
+		CreatedAt: s.now(),
+	}
+	s.jobs[id] = job
+	return *job, nil
+}
+
+// start marks a job as running, once its deploy has left the queue.
+func (s *jobStore) start(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		job.Status = jobRunning
+	}
+}
+
+// finish records the outcome of a job. A non-empty errMsg marks it failed.
+func (s *jobStore) finish(id, errMsg, stdout string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	job.Status = jobSucceeded
+	if errMsg != "" {
+		job.Status = jobFailed
+	}
+	job.Error = errMsg
+	job.Stdout = stdout
+	finished := s.now()
+	job.FinishedAt = &finished
+}
+
+// get returns a copy of the job with the given ID.
+func (s *jobStore) get(id string) (deployJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return deployJob{}, false
+	}
+	return *job, true
+}
+
+// sweepLocked drops finished jobs older than the TTL. Callers hold s.mu.
+func (s *jobStore) sweepLocked() {
+	cutoff := s.now().Add(-s.ttl)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
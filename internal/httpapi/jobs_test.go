@@ -0,0 +1,162 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/runner"
+)
+
+func TestJobStoreSweepsFinishedJobs(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newJobStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	done, err := store.create("web", "v1.0.0")
+	require.NoError(t, err)
+	store.finish(done.ID, "", "ok")
+
+	pending, err := store.create("api", "v1.0.0")
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+
+	_, ok := store.get(done.ID)
+	require.False(t, ok, "finished job past the TTL should be swept")
+
+	job, ok := store.get(pending.ID)
+	require.True(t, ok, "unfinished jobs are never swept")
+	require.Equal(t, jobQueued, job.Status)
+}
+
+func TestAsyncDeploy(t *testing.T) {
+	stacksDir := filepath.Join(t.TempDir(), "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "web"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "web", "docker-compose.yml"), []byte("services:\n  app:\n    image: web\n"), 0o644))
+
+	h := New(config.Config{Token: "secret", StacksDir: stacksDir}, nil, nil, BuildInfo{}, nil, nil)
+	// Stand in for waiting in the runner's queue and for the deploy itself
+	dequeue := make(chan struct{})
+	release := make(chan struct{})
+	h.deploy = func(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*runner.Result, error) {
+		<-dequeue
+		runner.NotifyStarted(ctx)
+		<-release
+		if tag == "v2.0.0" {
+			return nil, &runner.CommandError{Msg: "deployment failed for stack=web", Code: 1}
+		}
+		return runner.NewResult(stack, tag, "web: restarted"), nil
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	enqueue := func(tag string) string {
+		rec := do(http.MethodPost, "/deploy?async=true", `{"stack":"web","tag":"`+tag+`"}`)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var job deployJob
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+		require.NotEmpty(t, job.ID)
+		require.Equal(t, jobQueued, job.Status)
+		return job.ID
+	}
+	status := func(id string) deployJob {
+		rec := do(http.MethodGet, "/deploy/status/"+id, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var job deployJob
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+		return job
+	}
+
+	okID := enqueue("v1.0.0")
+	failID := enqueue("v2.0.0")
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, jobQueued, status(okID).Status, "a job waiting in the queue is not running yet")
+	close(dequeue)
+	require.Eventually(t, func() bool { return status(okID).Status == jobRunning }, time.Second, 10*time.Millisecond)
+	close(release)
+
+	require.Eventually(t, func() bool { return status(okID).Status == jobSucceeded }, time.Second, 10*time.Millisecond)
+	require.Equal(t, "web: restarted", status(okID).Stdout)
+
+	require.Eventually(t, func() bool { return status(failID).Status == jobFailed }, time.Second, 10*time.Millisecond)
+	failed := status(failID)
+	require.Equal(t, "deployment failed for stack=web", failed.Error)
+	require.Empty(t, failed.Stdout)
+	require.NotNil(t, failed.FinishedAt)
+
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/deploy/status/unknown", "").Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/deploy?async=maybe", `{"stack":"web","tag":"v1.0.0"}`).Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/deploy/status/"+okID, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestDeployStatusScopedTokens(t *testing.T) {
+	h := New(config.Config{
+		Token:        "admin",
+		DeployTokens: map[string][]string{"web-ci": {"web"}, "api-ci": {"api"}},
+	}, nil, nil, BuildInfo{}, nil, nil)
+	job, err := h.jobs.create("web", "v1.0.0")
+	require.NoError(t, err)
+
+	status := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/deploy/status/"+job.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, status("admin"))
+	require.Equal(t, http.StatusOK, status("web-ci"))
+	require.Equal(t, http.StatusForbidden, status("api-ci"))
+	require.Equal(t, http.StatusUnauthorized, status("nope"))
+}
+
+func TestShutdownCancelsAsyncDeploys(t *testing.T) {
+	stacksDir := filepath.Join(t.TempDir(), "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "web"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "web", "docker-compose.yml"), []byte("services:\n  app:\n    image: web\n"), 0o644))
+
+	h := New(config.Config{Token: "secret", StacksDir: stacksDir}, nil, nil, BuildInfo{}, nil, nil)
+	h.deploy = func(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*runner.Result, error) {
+		runner.NotifyStarted(ctx)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/deploy?async=true", strings.NewReader(`{"stack":"web","tag":"v1.0.0"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var job deployJob
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, h.Shutdown(ctx))
+
+	// The deploy returned before Shutdown did
+	finished, ok := h.jobs.get(job.ID)
+	require.True(t, ok)
+	require.Equal(t, jobFailed, finished.Status)
+	require.Contains(t, finished.Error, "context canceled")
+}
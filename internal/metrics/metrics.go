@@ -0,0 +1,100 @@
+// Package metrics defines the Prometheus metrics stackrd exposes on
+// GET /metrics.
+package metrics
+
+import (
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
+)
+
+// Deploy and cron results, used as the status label.
+const (
+	statusSucceeded = "succeeded"
+	statusFailed    = "failed"
+)
+
+// Metrics holds the counters updated by deploys and cron jobs. A nil
+// *Metrics records nothing.
+type Metrics struct {
+	deploys      *prometheus.CounterVec
+	cronRuns     *prometheus.CounterVec
+	cronFailures *prometheus.CounterVec
+}
+
+// New creates the metrics and registers them, along with the current
+// version of every remote stack, on reg.
+func New(reg prometheus.Registerer, cfg config.Config) *Metrics {
+	m := &Metrics{
+		deploys: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stackr_deploys_total",
+			Help: "Deploys run by stackrd, by stack and status (succeeded or failed).",
+		}, []string{"stack", "status"}),
+		cronRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stackr_cron_job_executions_total",
+			Help: "Cron job runs, by stack and service.",
+		}, []string{"stack", "service"}),
+		cronFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stackr_cron_job_failures_total",
+			Help: "Cron job runs that failed, by stack and service.",
+		}, []string{"stack", "service"}),
+	}
+	reg.MustRegister(m.deploys, m.cronRuns, m.cronFailures, remoteVersionCollector{cfg: cfg})
+	return m
+}
+
+// DeployFinished counts a deploy of stack that ended with err.
+func (m *Metrics) DeployFinished(stack string, err error) {
+	if m == nil {
+		return
+	}
+	status := statusSucceeded
+	if err != nil {
+		status = statusFailed
+	}
+	m.deploys.WithLabelValues(stack, status).Inc()
+}
+
+// CronFinished counts a run of the cron job for service in stack.
+func (m *Metrics) CronFinished(stack, service string, success bool) {
+	if m == nil {
+		return
+	}
+	m.cronRuns.WithLabelValues(stack, service).Inc()
+	if !success {
+		m.cronFailures.WithLabelValues(stack, service).Inc()
+	}
+}
+
+var remoteVersionDesc = prometheus.NewDesc(
+	"stackr_remote_stack_version",
+	"Checked out version of each cloned remote stack; always 1.",
+	[]string{"stack", "version"}, nil,
+)
+
+// remoteVersionCollector reports the version of each remote stack at scrape
+// time, so it is never stale after a sync outside stackrd.
+type remoteVersionCollector struct {
+	cfg config.Config
+}
+
+func (c remoteVersionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- remoteVersionDesc
+}
+
+func (c remoteVersionCollector) Collect(ch chan<- prometheus.Metric) {
+	statuses, err := stackcmd.ListRemoteStacks(c.cfg)
+	if err != nil {
+		log.Printf("metrics: failed to list remote stacks: %v", err)
+		return
+	}
+	for _, status := range statuses {
+		if status.CurrentVersion == "" {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(remoteVersionDesc, prometheus.GaugeValue, 1, status.Name, status.CurrentVersion)
+	}
+}
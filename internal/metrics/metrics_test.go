@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/git"
+)
+
+// scrape returns reg in the Prometheus text format.
+func scrape(t *testing.T, reg *prometheus.Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestCounters(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg, config.Config{StacksDir: t.TempDir()})
+
+	m.DeployFinished("app", nil)
+	m.DeployFinished("app", nil)
+	m.DeployFinished("app", errors.New("boom"))
+	m.CronFinished("app", "backup", true)
+	m.CronFinished("app", "backup", false)
+
+	out := scrape(t, reg)
+	require.Contains(t, out, `stackr_deploys_total{stack="app",status="succeeded"} 2`)
+	require.Contains(t, out, `stackr_deploys_total{stack="app",status="failed"} 1`)
+	require.Contains(t, out, `stackr_cron_job_executions_total{service="backup",stack="app"} 2`)
+	require.Contains(t, out, `stackr_cron_job_failures_total{service="backup",stack="app"} 1`)
+
+	var nilMetrics *Metrics
+	nilMetrics.DeployFinished("app", nil)
+	nilMetrics.CronFinished("app", "backup", false)
+}
+
+func TestRemoteStackVersion(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	for _, stack := range []string{"cloned", "pending"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, stack), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, stack, "stackr-repo.yml"),
+			[]byte("remote_repo:\n  url: https://example.com/repo.git\n  release:\n    type: tag\n    ref: v1.0.0\n"), 0o644))
+	}
+
+	repo := filepath.Join(root, ".stackr-repos", "cloned")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	ctx := context.Background()
+	require.NoError(t, git.RunGitCommand(ctx, repo, "init"))
+	require.NoError(t, git.RunGitCommand(ctx, repo, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "init"))
+	commit, err := git.NewClient(repo).CurrentCommit(ctx)
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	New(reg, config.Config{
+		RepoRoot:  root,
+		StacksDir: stacksDir,
+		Global:    config.GlobalConfig{RemoteStacksDir: ".stackr-repos"},
+	})
+
+	out := scrape(t, reg)
+	require.Contains(t, out, `stackr_remote_stack_version{stack="cloned",version="`+commit[:8]+`"} 1`)
+	require.NotContains(t, out, `stack="pending"`, "stacks that are not cloned have no version")
+}
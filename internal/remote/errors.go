@@ -68,8 +68,11 @@ func NewCheckoutError(stackName, ref, refType string, cause error) error {
 		"  2. The branch has been fetched (remote stacks use shallow clones)\n" +
 		"  3. The version in your .env file matches an actual release"
 
-	if refType == "tag" {
+	switch refType {
+	case "tag":
 		hint += "\n\nTo list available tags, run:\n  git ls-remote --tags <repo-url>"
+	case "branch":
+		hint += "\n\nTo list available branches, run:\n  git ls-remote --heads <repo-url>"
 	}
 
 	return &StackError{
@@ -80,6 +83,22 @@ func NewCheckoutError(stackName, ref, refType string, cause error) error {
 	}
 }
 
+// NewSSHKeyError creates an error for a deploy key that cannot be used
+func NewSSHKeyError(stackName, source string, cause error) error {
+	hint := fmt.Sprintf("The SSH key set by %s could not be used.\n", source) +
+		"Check that:\n" +
+		"  1. The key file exists on the host running stackr\n" +
+		"  2. Relative paths are resolved from the stackr repo root\n" +
+		"  3. The file is readable by the user running stackr"
+
+	return &StackError{
+		StackName: stackName,
+		Operation: "load ssh key",
+		Cause:     cause,
+		Hint:      hint,
+	}
+}
+
 // NewVersionRefError creates an error for version ref resolution failures
 func NewVersionRefError(stackName, ref, envVar string) error {
 	hint := fmt.Sprintf("The environment variable '%s' is not set in your .env file.\n", envVar) +
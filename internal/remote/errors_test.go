@@ -172,6 +172,16 @@ func TestErrorMessages_AreHelpful(t *testing.T) {
 			},
 			mustNotEmpty: true,
 		},
+		{
+			name: "ssh key error names the setting",
+			err:  NewSSHKeyError("test", "remote_repo.ssh_key", errors.New("key file does not exist")),
+			mustContain: []string{
+				"remote_repo.ssh_key",
+				"Check that:",
+				"readable",
+			},
+			mustNotEmpty: true,
+		},
 		{
 			name: "version ref error explains solution",
 			err:  NewVersionRefError("test", "${VAR}", "VAR"),
@@ -2,7 +2,9 @@ package remote
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -38,70 +40,143 @@ func NewManager(cfg config.Config) *Manager {
 // - Only changes version if needed (based on ref resolution)
 // - Gracefully degrades if git unreachable (uses cached version)
 func (m *Manager) EnsureRemoteStack(ctx context.Context, stackName string, envVars map[string]string) error {
+	src, err := m.resolveSource(stackName, envVars)
+	if err != nil {
+		return err
+	}
+	repo := src.repo
+
+	if !m.repoExists(src.repoRoot) {
+		// Clone the repository
+		log.Printf("cloning remote stack %s from %s", stackName, repo.URL)
+		if err := m.cloneRepo(ctx, repo.URL, repo.Branch, repo.Depth(), src.sshKeyPath, src.token, src.repoRoot); err != nil {
+			return NewCloneError(stackName, repo.URL, err)
+		}
+	}
+
+	// Create git client for this repo (always uses root, not subpath)
+	client := m.gitClientFunc(src.repoRoot)
+	if src.sshKeyPath != "" {
+		client = client.WithSSHKey(src.sshKeyPath)
+	}
+	if src.token != "" {
+		client = client.WithToken(src.token)
+	}
+
+	// Always try to pull latest changes (for .stackr-deployment.yaml updates)
+	// But be graceful if it fails (network issue, etc.)
+	if err := m.pullLatest(ctx, client, stackName); err != nil {
+		log.Printf("warning: failed to pull latest changes for %s: %v (using cached version)", stackName, err)
+		// Continue with cached version - this is the graceful degradation
+	}
+
+	// Check if we need to checkout a different version
+	releaseType := m.releaseType(stackName, repo)
+	if err := m.ensureCorrectVersion(ctx, client, stackName, src.ref, releaseType); err != nil {
+		return NewCheckoutError(stackName, src.ref, releaseType, err)
+	}
+
+	log.Printf("remote stack %s ready at version %s", stackName, src.ref)
+	return nil
+}
+
+// PlanRemoteStack describes the steps EnsureRemoteStack would take for the
+// stack, without running git or touching the filesystem. With forceClone an
+// existing clone is replaced by a fresh one.
+func (m *Manager) PlanRemoteStack(stackName string, envVars map[string]string, forceClone bool) ([]string, error) {
+	src, err := m.resolveSource(stackName, envVars)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []string
+	cloned := m.repoExists(src.repoRoot)
+	if cloned && forceClone {
+		plan = append(plan, fmt.Sprintf("remove existing clone %s", src.repoRoot))
+	}
+	if !cloned || forceClone {
+		plan = append(plan, fmt.Sprintf("clone %s (branch %s) into %s", src.repo.URL, src.repo.Branch, src.repoRoot))
+	}
+	plan = append(plan, "fetch and pull latest changes")
+	plan = append(plan, fmt.Sprintf("check out %s %s", m.releaseType(stackName, src.repo), src.ref))
+	return plan, nil
+}
+
+// remoteSource is a remote stack's repo config with its ref and credentials
+// resolved.
+type remoteSource struct {
+	repo       *config.RemoteStackConfig
+	ref        string
+	sshKeyPath string
+	token      string
+	// repoRoot is where the repo is cloned to
+	repoRoot string
+}
+
+func (m *Manager) resolveSource(stackName string, envVars map[string]string) (*remoteSource, error) {
 	// Load per-stack config (supports both stackr/config.yaml and legacy stackr-repo.yml)
 	stackDir := filepath.Join(m.cfg.StacksDir, stackName)
 	localCfg, err := config.LoadStackLocalConfig(stackDir)
 	if err != nil {
-		return fmt.Errorf("failed to load stack config: %w", err)
+		return nil, fmt.Errorf("failed to load stack config: %w", err)
 	}
 	if !localCfg.IsRemote() {
-		return fmt.Errorf("stack %q is not a remote stack", stackName)
+		return nil, fmt.Errorf("stack %q is not a remote stack", stackName)
 	}
 
 	repo := localCfg.RemoteRepo
 
-	// Resolve version ref from env vars
-	resolvedRef, err := config.ResolveVersionRef(repo.Release.Ref, envVars)
+	// Pick the active profile's ref, then resolve it from env vars
+	ref, err := repo.Release.RefFor(m.cfg.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("stack %s: %w", stackName, err)
+	}
+	resolvedRef, err := config.ResolveVersionRef(ref, envVars)
 	if err != nil {
 		// Extract the env var name from the ref pattern
-		ref := repo.Release.Ref
 		if strings.HasPrefix(ref, "${") && strings.HasSuffix(ref, "}") {
 			envVar := ref[2 : len(ref)-1]
-			return NewVersionRefError(stackName, ref, envVar)
+			return nil, NewVersionRefError(stackName, ref, envVar)
 		}
-		return fmt.Errorf("failed to resolve version ref: %w", err)
+		return nil, fmt.Errorf("failed to resolve version ref: %w", err)
 	}
 
-	// Determine repo root (where we clone to)
-	repoRoot := filepath.Join(m.remoteRepoDir, stackName)
-
-	// Check if repo exists
-	repoExists := m.repoExists(repoRoot)
-
-	if !repoExists {
-		// Clone the repository
-		log.Printf("cloning remote stack %s from %s", stackName, repo.URL)
-		if err := m.cloneRepo(ctx, repo.URL, repo.Branch, repoRoot); err != nil {
-			return NewCloneError(stackName, repo.URL, err)
+	sshKeyPath, err := resolveSSHKey(repo, envVars)
+	if err != nil {
+		return nil, fmt.Errorf("stack %s: %w", stackName, err)
+	}
+	if sshKeyPath != "" {
+		if !filepath.IsAbs(sshKeyPath) {
+			sshKeyPath = filepath.Join(m.cfg.RepoRoot, sshKeyPath)
+		}
+		if err := checkSSHKey(sshKeyPath); err != nil {
+			return nil, NewSSHKeyError(stackName, sshKeySource(repo), err)
 		}
 	}
-
-	// Create git client for this repo (always uses root, not subpath)
-	client := m.gitClientFunc(repoRoot)
-
-	// Always try to pull latest changes (for .stackr-deployment.yaml updates)
-	// But be graceful if it fails (network issue, etc.)
-	if err := m.pullLatest(ctx, client, stackName); err != nil {
-		log.Printf("warning: failed to pull latest changes for %s: %v (using cached version)", stackName, err)
-		// Continue with cached version - this is the graceful degradation
+	token, err := resolveToken(repo, envVars)
+	if err != nil {
+		return nil, fmt.Errorf("stack %s: %w", stackName, err)
 	}
 
-	// Check for release type override from .stackr-deployment.yaml
-	releaseType := repo.Release.Type
+	return &remoteSource{
+		repo:       repo,
+		ref:        resolvedRef,
+		sshKeyPath: sshKeyPath,
+		token:      token,
+		repoRoot:   filepath.Join(m.remoteRepoDir, stackName),
+	}, nil
+}
+
+// releaseType returns the stack's release type, which the clone's
+// .stackr-deployment.yaml may override.
+func (m *Manager) releaseType(stackName string, repo *config.RemoteStackConfig) string {
 	repoPath := m.getRepoPath(stackName, repo.Path)
 	if deployCfg, err := config.LoadDeploymentConfig(repoPath); err == nil {
 		if deployCfg.Stackr.Release != "" {
-			releaseType = deployCfg.Stackr.Release
+			return deployCfg.Stackr.Release
 		}
 	}
-
-	// Check if we need to checkout a different version
-	if err := m.ensureCorrectVersion(ctx, client, stackName, resolvedRef, releaseType); err != nil {
-		return NewCheckoutError(stackName, resolvedRef, repo.Release.Type, err)
-	}
-
-	log.Printf("remote stack %s ready at version %s", stackName, resolvedRef)
-	return nil
+	return repo.Release.Type
 }
 
 // GetCurrentVersion returns the currently checked out version
@@ -162,6 +237,67 @@ func (m *Manager) BuildMergedEnv(ctx context.Context, stackName string, baseEnv
 	return merged, nil
 }
 
+// resolveSSHKey returns the deploy key path set by remote_repo.ssh_key, or
+// named by remote_repo.ssh_key_env and looked up in the stack env first and
+// then the process env.
+func resolveSSHKey(repo *config.RemoteStackConfig, envVars map[string]string) (string, error) {
+	if repo.SSHKey != "" {
+		return repo.SSHKey, nil
+	}
+	if repo.SSHKeyEnv == "" {
+		return "", nil
+	}
+	keyPath := strings.TrimSpace(envVars[repo.SSHKeyEnv])
+	if keyPath == "" {
+		keyPath = strings.TrimSpace(os.Getenv(repo.SSHKeyEnv))
+	}
+	if keyPath == "" {
+		// Name the variable, never the key path
+		return "", fmt.Errorf("remote_repo.ssh_key_env: %s is not set", repo.SSHKeyEnv)
+	}
+	return keyPath, nil
+}
+
+// checkSSHKey reports whether keyPath is a readable file. Errors leave the
+// path out, like the redacted git output.
+func checkSSHKey(keyPath string) error {
+	info, err := os.Stat(keyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return errors.New("key file does not exist")
+	}
+	if err != nil {
+		return errors.New("key file is not accessible")
+	}
+	if !info.Mode().IsRegular() {
+		return errors.New("key path is not a regular file")
+	}
+	return nil
+}
+
+// sshKeySource names the setting the deploy key path came from.
+func sshKeySource(repo *config.RemoteStackConfig) string {
+	if repo.SSHKey != "" {
+		return "remote_repo.ssh_key"
+	}
+	return "remote_repo.ssh_key_env (" + repo.SSHKeyEnv + ")"
+}
+
+// resolveToken returns the access token named by remote_repo.token_env,
+// looked up in the stack env first and then the process env.
+func resolveToken(repo *config.RemoteStackConfig, envVars map[string]string) (string, error) {
+	if repo.TokenEnv == "" {
+		return "", nil
+	}
+	token := strings.TrimSpace(envVars[repo.TokenEnv])
+	if token == "" {
+		token = strings.TrimSpace(os.Getenv(repo.TokenEnv))
+	}
+	if token == "" {
+		return "", fmt.Errorf("remote_repo.token_env: %s is not set", repo.TokenEnv)
+	}
+	return token, nil
+}
+
 // getRepoPath returns the full path to the cloned repository
 func (m *Manager) getRepoPath(stackName, subPath string) string {
 	// If subPath is specified and not ".", use it
@@ -178,8 +314,8 @@ func (m *Manager) repoExists(repoPath string) bool {
 	return err == nil && info.IsDir()
 }
 
-// cloneRepo clones a repository with shallow clone
-func (m *Manager) cloneRepo(ctx context.Context, url, branch, destination string) error {
+// cloneRepo clones a repository, shallow unless depth is 0
+func (m *Manager) cloneRepo(ctx context.Context, url, branch string, depth int, sshKeyPath, token, destination string) error {
 	// Ensure parent directory exists
 	parentDir := filepath.Dir(destination)
 	if err := os.MkdirAll(parentDir, 0o755); err != nil {
@@ -187,9 +323,11 @@ func (m *Manager) cloneRepo(ctx context.Context, url, branch, destination string
 	}
 
 	opts := git.CloneOptions{
-		URL:    url,
-		Branch: branch,
-		Depth:  1, // Shallow clone
+		URL:        url,
+		Branch:     branch,
+		Depth:      depth,
+		SSHKeyPath: sshKeyPath,
+		Token:      token,
 	}
 
 	if err := git.Clone(ctx, destination, opts); err != nil {
@@ -235,6 +373,19 @@ func (m *Manager) pullLatest(ctx context.Context, client *git.Client, stackName
 
 // ensureCorrectVersion checks out the correct version if needed
 func (m *Manager) ensureCorrectVersion(ctx context.Context, client *git.Client, stackName, ref, refType string) error {
+	// A branch is fetched explicitly, since the clone only tracks
+	// remote_repo.branch, and checked out at its tip on every deploy
+	if refType == "branch" {
+		log.Printf("checking out branch %s for stack %s", ref, stackName)
+		if err := client.FetchBranch(ctx, ref); err != nil {
+			return fmt.Errorf("git fetch of branch %s failed: %w", ref, err)
+		}
+		if err := client.CheckoutBranch(ctx, ref); err != nil {
+			return fmt.Errorf("git checkout failed: %w", err)
+		}
+		return nil
+	}
+
 	// Get current commit
 	currentCommit, err := client.CurrentCommit(ctx)
 	if err != nil {
@@ -170,6 +170,123 @@ remote_repo:
 	require.NotEmpty(t, version)
 }
 
+func TestEnsureRemoteStack_BranchAdvances(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sourceRepo := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(sourceRepo, 0o755))
+	initGitRepo(t, sourceRepo)
+	createTag(t, sourceRepo, "v1.0.0")
+
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "myapp"), 0o755))
+
+	stackrYaml := `
+remote_repo:
+  url: ` + sourceRepo + `
+  branch: main
+  release:
+    type: branch
+    ref: ${APP_BRANCH}
+`
+	require.NoError(t, os.WriteFile(
+		filepath.Join(stacksDir, "myapp", "stackr-repo.yml"),
+		[]byte(stackrYaml),
+		0o644,
+	))
+
+	cfg := config.Config{
+		RepoRoot:  tmpDir,
+		StacksDir: stacksDir,
+		Global: config.GlobalConfig{
+			RemoteStacksDir: ".stackr-repos",
+		},
+	}
+
+	manager := NewManager(cfg)
+	envVars := map[string]string{"APP_BRANCH": "main"}
+	require.NoError(t, manager.EnsureRemoteStack(context.Background(), "myapp", envVars))
+
+	// Leave HEAD detached, as a previous tag deploy would
+	cloneRepo := filepath.Join(tmpDir, ".stackr-repos", "myapp")
+	require.NoError(t, git.RunGitCommand(context.Background(), cloneRepo, "checkout", "v1.0.0"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(sourceRepo, "test.txt"), []byte("new content"), 0o644))
+	commitFile(t, sourceRepo, "test.txt", "Add test file")
+	head, err := git.NewClient(sourceRepo).CurrentCommit(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, manager.EnsureRemoteStack(context.Background(), "myapp", envVars))
+
+	version, err := manager.GetCurrentVersion(context.Background(), "myapp")
+	require.NoError(t, err)
+	require.Equal(t, head, version, "a branch deploy advances to the branch tip")
+
+	ref, err := git.NewClient(cloneRepo).CurrentRef(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "main", ref)
+}
+
+func TestEnsureRemoteStack_OtherBranch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sourceRepo := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(sourceRepo, 0o755))
+	initGitRepo(t, sourceRepo)
+	require.NoError(t, git.RunGitCommand(context.Background(), sourceRepo, "checkout", "-b", "staging"))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceRepo, "test.txt"), []byte("staging"), 0o644))
+	commitFile(t, sourceRepo, "test.txt", "Staging change")
+	require.NoError(t, git.RunGitCommand(context.Background(), sourceRepo, "checkout", "main"))
+
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "myapp"), 0o755))
+
+	// file:// so the clone is really shallow and single-branch
+	stackrYaml := `
+remote_repo:
+  url: file://` + sourceRepo + `
+  branch: main
+  release:
+    type: branch
+    ref: ${APP_BRANCH}
+`
+	require.NoError(t, os.WriteFile(
+		filepath.Join(stacksDir, "myapp", "stackr-repo.yml"),
+		[]byte(stackrYaml),
+		0o644,
+	))
+
+	cfg := config.Config{
+		RepoRoot:  tmpDir,
+		StacksDir: stacksDir,
+		Global: config.GlobalConfig{
+			RemoteStacksDir: ".stackr-repos",
+		},
+	}
+
+	manager := NewManager(cfg)
+	envVars := map[string]string{"APP_BRANCH": "staging"}
+	require.NoError(t, manager.EnsureRemoteStack(context.Background(), "myapp", envVars))
+
+	cloneRepo := filepath.Join(tmpDir, ".stackr-repos", "myapp")
+	ref, err := git.NewClient(cloneRepo).CurrentRef(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "staging", ref)
+
+	// A later deploy advances to the new tip of the branch
+	require.NoError(t, git.RunGitCommand(context.Background(), sourceRepo, "checkout", "staging"))
+	require.NoError(t, os.WriteFile(filepath.Join(sourceRepo, "test.txt"), []byte("staging 2"), 0o644))
+	commitFile(t, sourceRepo, "test.txt", "Second staging change")
+	head, err := git.NewClient(sourceRepo).CurrentCommit(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, manager.EnsureRemoteStack(context.Background(), "myapp", envVars))
+
+	version, err := manager.GetCurrentVersion(context.Background(), "myapp")
+	require.NoError(t, err)
+	require.Equal(t, head, version)
+}
+
 func TestEnsureRemoteStack_WithSubdirectory(t *testing.T) {
 	t.Helper()
 	tmpDir := t.TempDir()
@@ -551,3 +668,111 @@ func createTag(t *testing.T, repoPath, tag string) {
 	err := git.RunGitCommand(context.Background(), repoPath, "tag", tag)
 	require.NoError(t, err)
 }
+
+func TestResolveSSHKey(t *testing.T) {
+	repo := &config.RemoteStackConfig{SSHKeyEnv: "MYAPP_DEPLOY_KEY"}
+
+	key, err := resolveSSHKey(repo, map[string]string{"MYAPP_DEPLOY_KEY": "/keys/myapp"})
+	require.NoError(t, err)
+	require.Equal(t, "/keys/myapp", key)
+
+	_, err = resolveSSHKey(repo, map[string]string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "MYAPP_DEPLOY_KEY")
+
+	key, err = resolveSSHKey(&config.RemoteStackConfig{SSHKey: "keys/myapp"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "keys/myapp", key)
+
+	key, err = resolveSSHKey(&config.RemoteStackConfig{}, nil)
+	require.NoError(t, err)
+	require.Empty(t, key)
+}
+
+func TestEnsureRemoteStack_MissingSSHKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "myapp"), 0o755))
+
+	stackrYaml := `
+remote_repo:
+  url: git@example.invalid:org/myapp.git
+  ssh_key: keys/myapp_deploy
+  release:
+    type: tag
+    ref: v1.0.0
+`
+	require.NoError(t, os.WriteFile(
+		filepath.Join(stacksDir, "myapp", "stackr-repo.yml"),
+		[]byte(stackrYaml),
+		0o644,
+	))
+
+	cfg := config.Config{
+		RepoRoot:  tmpDir,
+		StacksDir: stacksDir,
+		Global: config.GlobalConfig{
+			RemoteStacksDir: ".stackr-repos",
+		},
+	}
+
+	err := NewManager(cfg).EnsureRemoteStack(context.Background(), "myapp", map[string]string{})
+	var stackErr *StackError
+	require.ErrorAs(t, err, &stackErr)
+	require.Equal(t, "load ssh key", stackErr.Operation)
+	require.Contains(t, stackErr.Hint, "remote_repo.ssh_key")
+	require.NoDirExists(t, filepath.Join(tmpDir, ".stackr-repos", "myapp"), "nothing is cloned without the key")
+}
+
+func TestResolveToken(t *testing.T) {
+	repo := &config.RemoteStackConfig{TokenEnv: "MYAPP_GIT_TOKEN"}
+
+	token, err := resolveToken(repo, map[string]string{"MYAPP_GIT_TOKEN": "ghp_s3cret"})
+	require.NoError(t, err)
+	require.Equal(t, "ghp_s3cret", token)
+
+	t.Setenv("MYAPP_GIT_TOKEN", "ghp_from_env")
+	token, err = resolveToken(repo, map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, "ghp_from_env", token)
+
+	t.Setenv("MYAPP_GIT_TOKEN", "")
+	_, err = resolveToken(repo, map[string]string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "MYAPP_GIT_TOKEN")
+
+	token, err = resolveToken(&config.RemoteStackConfig{}, nil)
+	require.NoError(t, err)
+	require.Empty(t, token)
+}
+
+func TestCloneRepo_FullDepthKeepsHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sourceRepo := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(sourceRepo, 0o755))
+	initGitRepo(t, sourceRepo)
+	oldCommit, err := git.NewClient(sourceRepo).CurrentCommit(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(sourceRepo, "app.txt"), []byte("v2"), 0o644))
+	commitFile(t, sourceRepo, "app.txt", "Second commit")
+
+	manager := NewManager(config.Config{RepoRoot: tmpDir})
+	// file:// so git honours --depth for the shallow case
+	url := "file://" + sourceRepo
+
+	shallowPath := filepath.Join(tmpDir, "shallow")
+	require.NoError(t, manager.cloneRepo(context.Background(), url, "main", 1, "", "", shallowPath))
+	shallow := git.NewClient(shallowPath)
+	require.Error(t, shallow.Checkout(context.Background(), git.CheckoutOptions{Ref: oldCommit}))
+
+	fullPath := filepath.Join(tmpDir, "full")
+	require.NoError(t, manager.cloneRepo(context.Background(), url, "main", 0, "", "", fullPath))
+	full := git.NewClient(fullPath)
+	require.NoError(t, full.Checkout(context.Background(), git.CheckoutOptions{Ref: oldCommit}))
+
+	current, err := full.CurrentCommit(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, oldCommit, current)
+}
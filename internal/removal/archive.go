@@ -2,20 +2,21 @@ package removal
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/jamestiberiuskirk/stackr/internal/config"
 	"github.com/jamestiberiuskirk/stackr/internal/fsutil"
 )
 
 // ArchiveConfig holds configuration for archiving
 type ArchiveConfig struct {
-	BackupDir string
-	PoolBases map[string]string
-	StacksDir string
+	BackupDir  string
+	PoolBases  map[string]string
+	StacksDir  string
+	ConfigDirs []string // Stack subdirectories to archive (defaults to config.DefaultBackupConfigDirs)
 }
 
 // Archive creates a timestamped archive of a stack's volumes
@@ -28,23 +29,17 @@ func Archive(stack string, cfg ArchiveConfig) (string, error) {
 		return "", fmt.Errorf("failed to create archive directory: %w", err)
 	}
 
-	log.Printf("archiving removed stack %s to %s", stack, archivePath)
-
 	stackDir := filepath.Join(cfg.StacksDir, stack)
 
 	// Archive config directories (if they still exist)
-	configDirs := []struct {
-		src string
-		dst string
-	}{
-		{filepath.Join(stackDir, "config"), filepath.Join(archivePath, "config")},
-		{filepath.Join(stackDir, "dashboards"), filepath.Join(archivePath, "dashboards")},
-		{filepath.Join(stackDir, "dynamic"), filepath.Join(archivePath, "dynamic")},
+	configDirs := cfg.ConfigDirs
+	if len(configDirs) == 0 {
+		configDirs = config.DefaultBackupConfigDirs
 	}
-
-	for _, item := range configDirs {
-		if err := copyDirIfExists(item.src, item.dst); err != nil {
-			return archivePath, fmt.Errorf("failed to archive %s: %w", item.src, err)
+	for _, dir := range configDirs {
+		src := filepath.Join(stackDir, dir)
+		if err := copyDirIfExists(src, filepath.Join(archivePath, dir)); err != nil {
+			return archivePath, fmt.Errorf("failed to archive %s: %w", src, err)
 		}
 	}
 
@@ -57,7 +52,6 @@ func Archive(stack string, cfg ArchiveConfig) (string, error) {
 		}
 	}
 
-	log.Printf("successfully archived stack %s", stack)
 	return archivePath, nil
 }
 
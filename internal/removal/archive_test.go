@@ -0,0 +1,42 @@
+package removal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveCopiesConfiguredDirs(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	for _, dir := range []string{"conf", "grafana", "config"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "myapp", dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "myapp", dir, "file.txt"), []byte(dir), 0o644))
+	}
+
+	archivePath, err := Archive("myapp", ArchiveConfig{
+		BackupDir:  filepath.Join(root, "backups"),
+		StacksDir:  stacksDir,
+		ConfigDirs: []string{"conf", "grafana"},
+	})
+	require.NoError(t, err)
+
+	require.FileExists(t, filepath.Join(archivePath, "conf", "file.txt"))
+	require.FileExists(t, filepath.Join(archivePath, "grafana", "file.txt"))
+	require.NoDirExists(t, filepath.Join(archivePath, "config"), "dirs outside config_dirs must not be archived")
+}
+
+func TestArchiveDefaultsToStandardDirs(t *testing.T) {
+	root := t.TempDir()
+	stacksDir := filepath.Join(root, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "myapp", "dashboards"), 0o755))
+
+	archivePath, err := Archive("myapp", ArchiveConfig{
+		BackupDir: filepath.Join(root, "backups"),
+		StacksDir: stacksDir,
+	})
+	require.NoError(t, err)
+	require.DirExists(t, filepath.Join(archivePath, "dashboards"))
+}
@@ -3,22 +3,33 @@ package removal
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/jamestiberiuskirk/stackr/internal/compose"
 	"github.com/jamestiberiuskirk/stackr/internal/config"
 )
 
+// cleanupRetryDelay is the wait before the first retry of a failed docker
+// command; it doubles on every further attempt.
+var cleanupRetryDelay = 2 * time.Second
+
 // Cleanup removes all Docker resources for a stack
-// Uses docker compose down with volume removal
-func Cleanup(ctx context.Context, stack string, stacksDir string) error {
+// Uses docker compose down with volume removal, retrying failed docker
+// commands up to retries times. A nil logger logs to slog.Default()
+func Cleanup(ctx context.Context, logger *slog.Logger, stack string, stacksDir string, retries int) error {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
 	stackDir := filepath.Join(stacksDir, stack)
 	localCfg, err := config.LoadStackLocalConfig(stackDir)
 	if err != nil {
-		log.Printf("failed to load stack config for %s, falling back to default: %v", stack, err)
+		logger.Warn("failed to load stack config, falling back to default", "stack", stack, "error", err)
 		localCfg = &config.StackLocalConfig{ComposeFiles: []string{"docker-compose.yml"}}
 	}
 
@@ -35,134 +46,167 @@ func Cleanup(ctx context.Context, stack string, stacksDir string) error {
 	// If not, we need to use docker CLI directly to clean by project label
 	if _, err := os.Stat(composePaths[0]); err != nil {
 		if os.IsNotExist(err) {
-			log.Printf("compose file gone for %s, cleaning by project label", stack)
-			return cleanupByProjectLabel(ctx, stack)
+			logger.Info("compose file gone, cleaning by project label", "stack", stack)
+			return cleanupByProjectLabel(ctx, logger, stack, retries)
 		}
 		return fmt.Errorf("failed to check compose file: %w", err)
 	}
 
+	// Same project directory as the deploy, resolved against the primary
+	// compose file
+	projectDir := localCfg.ProjectDirectory
+	if projectDir != "" && !filepath.IsAbs(projectDir) {
+		projectDir = filepath.Join(filepath.Dir(composePaths[0]), projectDir)
+	}
+
 	// Compose file exists, use docker compose down
-	log.Printf("running docker compose down for stack %s", stack)
-	return dockerComposeDown(ctx, composePaths)
+	logger.Info("running docker compose down", "stack", stack)
+	return dockerComposeDown(ctx, logger, stack, projectDir, composePaths, retries)
 }
 
-// dockerComposeDown runs docker compose down with volume removal
-func dockerComposeDown(ctx context.Context, composePaths []string) error {
+// dockerComposeDown runs docker compose down with volume removal. The project
+// is named after the stack, as on deploy, so a project directory override
+// still targets the deployed resources
+func dockerComposeDown(ctx context.Context, logger *slog.Logger, stack, projectDir string, composePaths []string, retries int) error {
 	args := []string{"compose"}
+	if projectDir != "" {
+		args = append(args, "--project-directory", projectDir)
+	}
+	args = append(args, "-p", compose.ProjectName(stack))
 	for _, p := range composePaths {
 		args = append(args, "-f", p)
 	}
 	args = append(args, "down", "--volumes", "--remove-orphans")
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
-
-	output, err := cmd.CombinedOutput()
+	output, err := runDocker(ctx, logger, retries, args...)
 	if err != nil {
 		return fmt.Errorf("docker compose down failed: %w\nOutput: %s", err, string(output))
 	}
 
-	log.Printf("docker compose down completed: %s", strings.TrimSpace(string(output)))
+	logger.Info("docker compose down completed", "output", strings.TrimSpace(string(output)))
 	return nil
 }
 
 // cleanupByProjectLabel cleans resources when compose file is gone
 // Uses docker CLI to find and remove resources by project label
-func cleanupByProjectLabel(ctx context.Context, stack string) error {
+func cleanupByProjectLabel(ctx context.Context, logger *slog.Logger, stack string, retries int) error {
 	// Remove containers
-	if err := removeContainers(ctx, stack); err != nil {
+	if err := removeContainers(ctx, logger, stack, retries); err != nil {
 		return fmt.Errorf("failed to remove containers: %w", err)
 	}
 
 	// Remove volumes
-	if err := removeVolumes(ctx, stack); err != nil {
+	if err := removeVolumes(ctx, logger, stack, retries); err != nil {
 		return fmt.Errorf("failed to remove volumes: %w", err)
 	}
 
 	// Remove networks
-	if err := removeNetworks(ctx, stack); err != nil {
+	if err := removeNetworks(ctx, logger, stack, retries); err != nil {
 		return fmt.Errorf("failed to remove networks: %w", err)
 	}
 
 	return nil
 }
 
-func removeContainers(ctx context.Context, stack string) error {
+func removeContainers(ctx context.Context, logger *slog.Logger, stack string, retries int) error {
 	// List containers
-	listCmd := exec.CommandContext(ctx, "docker", "ps", "-aq",
+	output, err := runDocker(ctx, logger, retries, "ps", "-aq",
 		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", stack))
-
-	output, err := listCmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to list containers: %w", err)
 	}
 
 	containerIDs := strings.Fields(strings.TrimSpace(string(output)))
 	if len(containerIDs) == 0 {
-		log.Printf("no containers found for stack %s", stack)
+		logger.Info("no containers found", "stack", stack)
 		return nil
 	}
 
+	// Stop every container before removing any of them. Removing containers one
+	// by one while their dependents are still running can fail for services
+	// linked via depends_on/links, so bring the whole project down first.
+	stopArgs := append([]string{"stop"}, containerIDs...)
+	stopCmd := exec.CommandContext(ctx, "docker", stopArgs...)
+	if output, err := stopCmd.CombinedOutput(); err != nil {
+		logger.Warn("failed to stop containers, continuing with forced removal", "stack", stack, "error", err, "output", string(output))
+	}
+
 	// Remove containers
 	args := append([]string{"rm", "-f"}, containerIDs...)
-	rmCmd := exec.CommandContext(ctx, "docker", args...)
-	if output, err := rmCmd.CombinedOutput(); err != nil {
+	if output, err := runDocker(ctx, logger, retries, args...); err != nil {
 		return fmt.Errorf("failed to remove containers: %w\nOutput: %s", err, string(output))
 	}
 
-	log.Printf("removed %d containers for stack %s", len(containerIDs), stack)
+	logger.Info("removed containers", "stack", stack, "count", len(containerIDs))
 	return nil
 }
 
-func removeVolumes(ctx context.Context, stack string) error {
+func removeVolumes(ctx context.Context, logger *slog.Logger, stack string, retries int) error {
 	// List volumes
-	listCmd := exec.CommandContext(ctx, "docker", "volume", "ls", "-q",
+	output, err := runDocker(ctx, logger, retries, "volume", "ls", "-q",
 		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", stack))
-
-	output, err := listCmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to list volumes: %w", err)
 	}
 
 	volumeNames := strings.Fields(strings.TrimSpace(string(output)))
 	if len(volumeNames) == 0 {
-		log.Printf("no volumes found for stack %s", stack)
+		logger.Info("no volumes found", "stack", stack)
 		return nil
 	}
 
 	// Remove volumes
 	args := append([]string{"volume", "rm", "-f"}, volumeNames...)
-	rmCmd := exec.CommandContext(ctx, "docker", args...)
-	if output, err := rmCmd.CombinedOutput(); err != nil {
+	if output, err := runDocker(ctx, logger, retries, args...); err != nil {
 		return fmt.Errorf("failed to remove volumes: %w\nOutput: %s", err, string(output))
 	}
 
-	log.Printf("removed %d volumes for stack %s", len(volumeNames), stack)
+	logger.Info("removed volumes", "stack", stack, "count", len(volumeNames))
 	return nil
 }
 
-func removeNetworks(ctx context.Context, stack string) error {
+func removeNetworks(ctx context.Context, logger *slog.Logger, stack string, retries int) error {
 	// List networks
-	listCmd := exec.CommandContext(ctx, "docker", "network", "ls", "-q",
+	output, err := runDocker(ctx, logger, retries, "network", "ls", "-q",
 		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", stack))
-
-	output, err := listCmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to list networks: %w", err)
 	}
 
 	networkIDs := strings.Fields(strings.TrimSpace(string(output)))
 	if len(networkIDs) == 0 {
-		log.Printf("no networks found for stack %s", stack)
+		logger.Info("no networks found", "stack", stack)
 		return nil
 	}
 
 	// Remove networks
 	args := append([]string{"network", "rm"}, networkIDs...)
-	rmCmd := exec.CommandContext(ctx, "docker", args...)
-	if output, err := rmCmd.CombinedOutput(); err != nil {
+	if output, err := runDocker(ctx, logger, retries, args...); err != nil {
 		return fmt.Errorf("failed to remove networks for stack %s: %w\nOutput: %s", stack, err, string(output))
 	}
 
-	log.Printf("removed %d networks for stack %s", len(networkIDs), stack)
+	logger.Info("removed networks", "stack", stack, "count", len(networkIDs))
 	return nil
 }
+
+// runDocker runs a docker command, retrying up to retries times with
+// exponential backoff. Every attempt is bound to ctx, so the caller's timeout
+// caps the total time spent.
+func runDocker(ctx context.Context, logger *slog.Logger, retries int, args ...string) ([]byte, error) {
+	delay := cleanupRetryDelay
+	for attempt := 0; ; attempt++ {
+		output, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+		if err == nil || attempt >= retries || ctx.Err() != nil {
+			return output, err
+		}
+
+		logger.Warn("docker command failed, retrying", "command", "docker "+strings.Join(args, " "),
+			"attempt", attempt+1, "attempts", retries+1, "delay", delay, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return output, err
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,111 @@
+package removal
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stubDocker installs a fake docker binary that records its arguments and
+// reports two containers for any "ps" invocation.
+func stubDocker(t *testing.T) string {
+	t.Helper()
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" >> \"" + logPath + "\"\n" +
+		"if [ \"$1\" = \"ps\" ]; then echo web; echo db; fi\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func TestRemoveContainersStopsAllBeforeRemoving(t *testing.T) {
+	logPath := stubDocker(t)
+
+	require.NoError(t, removeContainers(context.Background(), slog.Default(), "myapp", 0))
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Equal(t, []string{
+		"ps -aq --filter label=com.docker.compose.project=myapp",
+		"stop web db",
+		"rm -f web db",
+	}, lines)
+}
+
+func TestCleanupRetriesTransientComposeDownFailure(t *testing.T) {
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	marker := filepath.Join(binDir, "failed-once")
+	// Fail the first invocation only, like a daemon hiccup
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" >> \"" + logPath + "\"\n" +
+		"if [ ! -f \"" + marker + "\" ]; then touch \"" + marker + "\"; echo 'daemon unavailable' >&2; exit 1; fi\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	prevDelay := cleanupRetryDelay
+	cleanupRetryDelay = time.Millisecond
+	t.Cleanup(func() { cleanupRetryDelay = prevDelay })
+
+	stacksDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "myapp"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "myapp", "docker-compose.yml"), []byte("services: {}\n"), 0o644))
+
+	require.NoError(t, Cleanup(context.Background(), slog.Default(), "myapp", stacksDir, 2))
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2, "first down fails, the retry succeeds")
+	for _, line := range lines {
+		require.Contains(t, line, "down --volumes --remove-orphans")
+	}
+}
+
+func TestCleanupComposeDownUsesProjectDirectoryOverride(t *testing.T) {
+	logPath := stubDocker(t)
+
+	stacksDir := t.TempDir()
+	stackDir := filepath.Join(stacksDir, "myapp")
+	require.NoError(t, os.MkdirAll(filepath.Join(stackDir, "compose"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(stackDir, "stackr"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "compose", "docker-compose.yml"), []byte("services: {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(stackDir, "stackr", "config.yaml"),
+		[]byte("compose_files:\n  - compose/docker-compose.yml\nproject_directory: ..\n"), 0o644))
+
+	require.NoError(t, Cleanup(context.Background(), slog.Default(), "myapp", stacksDir, 0))
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, "compose --project-directory "+stackDir+" -p myapp -f "+
+		filepath.Join(stackDir, "compose", "docker-compose.yml")+" down --volumes --remove-orphans",
+		strings.TrimSpace(string(data)))
+}
+
+func TestRunDockerGivesUpAfterRetries(t *testing.T) {
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := "#!/bin/sh\necho \"$@\" >> \"" + logPath + "\"\nexit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	prevDelay := cleanupRetryDelay
+	cleanupRetryDelay = time.Millisecond
+	t.Cleanup(func() { cleanupRetryDelay = prevDelay })
+
+	_, err := runDocker(context.Background(), slog.Default(), 2, "volume", "ls")
+	require.Error(t, err)
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), 3)
+}
@@ -2,7 +2,7 @@ package removal
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"time"
@@ -14,6 +14,8 @@ import (
 type HandlerConfig struct {
 	ContinueOnArchiveError bool
 	CleanupTimeout         time.Duration
+	// Logger receives the handler's logs; nil means slog.Default()
+	Logger *slog.Logger
 }
 
 // Handler orchestrates stack removal detection and cleanup
@@ -21,35 +23,50 @@ type Handler struct {
 	tracker       *Tracker
 	archiveConfig ArchiveConfig
 	stacksDir     string
+	retries       int
 	config        HandlerConfig
+	logger        *slog.Logger
 }
 
 // NewHandler creates a new removal handler
 func NewHandler(cfg config.Config, handlerCfg HandlerConfig) *Handler {
+	logger := handlerCfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	h := &Handler{
+		tracker: NewTracker(),
+		config:  handlerCfg,
+		logger:  logger,
+	}
+	h.SetConfig(cfg)
+	return h
+}
+
+// SetConfig replaces the paths and retries used for cleanup, e.g. after a
+// config reload. It must not run concurrently with CheckForRemovals.
+func (h *Handler) SetConfig(cfg config.Config) {
 	poolBases := make(map[string]string)
 	for name, rel := range cfg.Global.Paths.Pools {
 		key := strings.ToUpper(strings.TrimSpace(name))
 		poolBases[key] = absolutePath(cfg.RepoRoot, rel)
 	}
 
-	backupDir := absolutePath(cfg.RepoRoot, cfg.Global.Paths.BackupDir)
-
-	return &Handler{
-		tracker: NewTracker(),
-		archiveConfig: ArchiveConfig{
-			BackupDir: backupDir,
-			PoolBases: poolBases,
-			StacksDir: cfg.StacksDir,
-		},
-		stacksDir: cfg.StacksDir,
-		config:    handlerCfg,
+	h.archiveConfig = ArchiveConfig{
+		BackupDir:  absolutePath(cfg.RepoRoot, cfg.Global.Paths.BackupDir),
+		PoolBases:  poolBases,
+		StacksDir:  cfg.StacksDir,
+		ConfigDirs: cfg.Global.Backup.Dirs(),
 	}
+	h.stacksDir = cfg.StacksDir
+	h.retries = cfg.Global.Removal.Retries()
 }
 
 // Initialize sets the initial stack state
 func (h *Handler) Initialize(stacks []string) {
 	h.tracker.Initialize(stacks)
-	log.Printf("initialized removal tracker with %d stacks", len(stacks))
+	h.logger.Info("initialized removal tracker", "stacks", len(stacks))
 }
 
 // CheckForRemovals scans for removed stacks and handles cleanup
@@ -61,7 +78,7 @@ func (h *Handler) CheckForRemovals(currentStacks []string) {
 		return
 	}
 
-	log.Printf("detected %d removed stack(s): %v", len(removed), removed)
+	h.logger.Info("detected removed stacks", "count", len(removed), "stacks", removed)
 
 	for _, stack := range removed {
 		h.handleRemovedStack(stack)
@@ -69,31 +86,32 @@ func (h *Handler) CheckForRemovals(currentStacks []string) {
 }
 
 func (h *Handler) handleRemovedStack(stack string) {
-	log.Printf("handling removal of stack: %s", stack)
+	logger := h.logger.With("stack", stack)
+	logger.Info("handling removal of stack", "status", "started")
 
 	// Phase 1: Archive
 	archivePath, err := Archive(stack, h.archiveConfig)
 	if err != nil {
-		log.Printf("ERROR: failed to archive stack %s: %v", stack, err)
+		logger.Error("failed to archive stack", "archive", archivePath, "error", err)
 		if !h.config.ContinueOnArchiveError {
-			log.Printf("skipping cleanup for %s due to archive failure", stack)
+			logger.Error("skipping cleanup due to archive failure", "status", "failed")
 			return
 		}
-		log.Printf("continuing with cleanup despite archive failure (ContinueOnArchiveError=true)")
+		logger.Warn("continuing with cleanup despite archive failure (ContinueOnArchiveError=true)")
 	} else {
-		log.Printf("archived stack %s to %s", stack, archivePath)
+		logger.Info("archived stack", "archive", archivePath)
 	}
 
 	// Phase 2: Cleanup Docker resources
 	ctx, cancel := context.WithTimeout(context.Background(), h.config.CleanupTimeout)
 	defer cancel()
 
-	if err := Cleanup(ctx, stack, h.stacksDir); err != nil {
-		log.Printf("ERROR: failed to cleanup stack %s: %v", stack, err)
+	if err := Cleanup(ctx, logger, stack, h.stacksDir, h.retries); err != nil {
+		logger.Error("failed to clean up stack", "status", "failed", "error", err)
 		return
 	}
 
-	log.Printf("successfully cleaned up stack %s", stack)
+	logger.Info("cleaned up removed stack", "status", "succeeded")
 }
 
 // absolutePath returns an absolute path, handling both absolute and relative paths
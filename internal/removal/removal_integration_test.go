@@ -76,7 +76,7 @@ func TestCleanupWithComposeFile(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	err = Cleanup(ctx, stackName, filepath.Join(root, "stacks"))
+	err = Cleanup(ctx, nil, stackName, filepath.Join(root, "stacks"), 0)
 	require.NoError(t, err)
 
 	// Verify containers are gone
@@ -118,7 +118,7 @@ func TestCleanupWithoutComposeFile(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	err = Cleanup(ctx, stackName, filepath.Join(root, "stacks"))
+	err = Cleanup(ctx, nil, stackName, filepath.Join(root, "stacks"), 0)
 	require.NoError(t, err)
 
 	// Verify containers are gone
@@ -166,7 +166,7 @@ volumes:
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	err = Cleanup(ctx, stackName, filepath.Join(root, "stacks"))
+	err = Cleanup(ctx, nil, stackName, filepath.Join(root, "stacks"), 0)
 	require.NoError(t, err)
 
 	// Verify containers gone
@@ -313,7 +313,7 @@ func TestArchiveBeforeCleanup(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	err = Cleanup(ctx, stackName, filepath.Join(root, "stacks"))
+	err = Cleanup(ctx, nil, stackName, filepath.Join(root, "stacks"), 0)
 	require.NoError(t, err)
 
 	// Docker resources should be gone
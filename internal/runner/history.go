@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// historyFile is where the tags of successful deploys are kept, relative to
+// the repo root.
+const historyFile = ".stackr/deploy-history.json"
+
+// maxHistory caps how many known-good tags are kept per stack.
+const maxHistory = 10
+
+// ErrNoPreviousTag is returned by Rollback when no earlier successful deploy
+// of the stack is recorded.
+var ErrNoPreviousTag = errors.New("no previous successful deploy recorded")
+
+// tagHistory persists the last known-good tags of each stack, oldest first,
+// so a stack can be rolled back to the tag before its current one.
+type tagHistory struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newTagHistory(repoRoot string) *tagHistory {
+	return &tagHistory{path: filepath.Join(repoRoot, historyFile)}
+}
+
+// record appends tag as the current known-good tag of stack. Redeploying the
+// current tag leaves the history as is.
+func (h *tagHistory) record(stack, tag string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stacks, err := h.load()
+	if err != nil {
+		return err
+	}
+	tags := stacks[stack]
+	if len(tags) > 0 && tags[len(tags)-1] == tag {
+		return nil
+	}
+	tags = append(tags, tag)
+	if len(tags) > maxHistory {
+		tags = tags[len(tags)-maxHistory:]
+	}
+	stacks[stack] = tags
+	return h.save(stacks)
+}
+
+// previous returns the tag deployed before the current one of stack.
+func (h *tagHistory) previous(stack string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stacks, err := h.load()
+	if err != nil {
+		return "", err
+	}
+	tags := stacks[stack]
+	if len(tags) < 2 {
+		return "", ErrNoPreviousTag
+	}
+	return tags[len(tags)-2], nil
+}
+
+// rolledBack drops the current tag of stack once it has been rolled back to
+// tag, so another rollback goes one release further back.
+func (h *tagHistory) rolledBack(stack, tag string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stacks, err := h.load()
+	if err != nil {
+		return err
+	}
+	tags := stacks[stack]
+	if len(tags) < 2 || tags[len(tags)-2] != tag {
+		return nil
+	}
+	stacks[stack] = tags[:len(tags)-1]
+	return h.save(stacks)
+}
+
+func (h *tagHistory) load() (map[string][]string, error) {
+	stacks := map[string][]string{}
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return stacks, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &stacks); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", h.path, err)
+	}
+	return stacks, nil
+}
+
+func (h *tagHistory) save(stacks map[string][]string) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(stacks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, append(data, '\n'), 0o644)
+}
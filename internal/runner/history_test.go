@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagHistory(t *testing.T) {
+	root := t.TempDir()
+	h := newTagHistory(root)
+
+	_, err := h.previous("web")
+	require.ErrorIs(t, err, ErrNoPreviousTag)
+
+	require.NoError(t, h.record("web", "v1.0.0"))
+	_, err = h.previous("web")
+	require.ErrorIs(t, err, ErrNoPreviousTag, "the only deploy has nothing before it")
+
+	require.NoError(t, h.record("web", "v1.1.0"))
+	require.NoError(t, h.record("web", "v1.1.0"))
+	require.NoError(t, h.record("web", "v1.2.0"))
+	require.NoError(t, h.record("api", "v2.0.0"))
+
+	// Persisted under the repo root and read back by a fresh history
+	h = newTagHistory(root)
+	require.FileExists(t, filepath.Join(root, ".stackr", "deploy-history.json"))
+	tag, err := h.previous("web")
+	require.NoError(t, err)
+	require.Equal(t, "v1.1.0", tag)
+
+	require.NoError(t, h.rolledBack("web", "v1.1.0"))
+	tag, err = h.previous("web")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", tag, "a second rollback goes one release further back")
+
+	_, err = h.previous("api")
+	require.ErrorIs(t, err, ErrNoPreviousTag)
+}
+
+func TestTagHistoryKeepsLatest(t *testing.T) {
+	h := newTagHistory(t.TempDir())
+	for i := range maxHistory + 5 {
+		require.NoError(t, h.record("web", string(rune('a'+i))))
+	}
+	stacks, err := h.load()
+	require.NoError(t, err)
+	require.Len(t, stacks["web"], maxHistory)
+	require.Equal(t, string(rune('a'+maxHistory+4)), stacks["web"][maxHistory-1])
+}
+
+func TestTagHistoryInvalidFile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".stackr"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, historyFile), []byte("not json"), 0o644))
+
+	_, err := newTagHistory(root).previous("web")
+	require.ErrorContains(t, err, "failed to parse")
+}
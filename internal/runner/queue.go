@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// deployQueue runs one deploy at a time. Waiting deploys are served
+// round-robin across stacks, FIFO within a stack, so a burst of deploys for
+// one stack cannot starve the others.
+type deployQueue struct {
+	mu   sync.Mutex
+	busy bool
+	// order lists the stacks with waiting deploys; the head is served next.
+	order   []string
+	waiting map[string][]chan struct{}
+}
+
+func newDeployQueue() *deployQueue {
+	return &deployQueue{waiting: map[string][]chan struct{}{}}
+}
+
+// acquire blocks until it is stack's turn to deploy or ctx is done. Every
+// successful acquire must be paired with a release.
+func (q *deployQueue) acquire(ctx context.Context, stack string) error {
+	q.mu.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return nil
+	}
+	turn := make(chan struct{})
+	if len(q.waiting[stack]) == 0 {
+		q.order = append(q.order, stack)
+	}
+	q.waiting[stack] = append(q.waiting[stack], turn)
+	q.mu.Unlock()
+
+	select {
+	case <-turn:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	waiters := q.waiting[stack]
+	if i := slices.Index(waiters, turn); i >= 0 {
+		q.waiting[stack] = slices.Delete(waiters, i, i+1)
+		if len(q.waiting[stack]) == 0 {
+			delete(q.waiting, stack)
+			q.order = slices.DeleteFunc(q.order, func(s string) bool { return s == stack })
+		}
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+	q.mu.Unlock()
+	// The turn was handed over while ctx was finishing; pass it on
+	q.release()
+	return ctx.Err()
+}
+
+// release ends the current deploy and hands the turn to the next stack in
+// the rotation.
+func (q *deployQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		q.busy = false
+		return
+	}
+	stack := q.order[0]
+	q.order = q.order[1:]
+	waiters := q.waiting[stack]
+	next := waiters[0]
+	if len(waiters) > 1 {
+		q.waiting[stack] = waiters[1:]
+		q.order = append(q.order, stack)
+	} else {
+		delete(q.waiting, stack)
+	}
+	close(next)
+}
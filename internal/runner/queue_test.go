@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// queued reports how many deploys are waiting for a turn.
+func (q *deployQueue) queued() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, waiters := range q.waiting {
+		n += len(waiters)
+	}
+	return n
+}
+
+func TestDeployQueueRoundRobin(t *testing.T) {
+	q := newDeployQueue()
+	ctx := context.Background()
+	require.NoError(t, q.acquire(ctx, "running"))
+
+	var mu sync.Mutex
+	var served []string
+	var wg sync.WaitGroup
+	enqueue := func(stack string) {
+		want := q.queued() + 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := q.acquire(ctx, stack); err != nil {
+				return
+			}
+			mu.Lock()
+			served = append(served, stack)
+			mu.Unlock()
+			q.release()
+		}()
+		require.Eventually(t, func() bool { return q.queued() == want }, time.Second, time.Millisecond)
+	}
+
+	for range 5 {
+		enqueue("a")
+	}
+	enqueue("b")
+	enqueue("c")
+
+	q.release()
+	wg.Wait()
+
+	require.Equal(t, []string{"a", "b", "c", "a", "a", "a", "a"}, served)
+	require.Zero(t, q.queued())
+	require.NoError(t, q.acquire(ctx, "a"), "queue should be idle again")
+}
+
+func TestDeployQueueCancelWhileWaiting(t *testing.T) {
+	q := newDeployQueue()
+	require.NoError(t, q.acquire(context.Background(), "a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- q.acquire(ctx, "b") }()
+	require.Eventually(t, func() bool { return q.queued() == 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	require.Zero(t, q.queued())
+
+	q.release()
+	acquired := make(chan error, 1)
+	go func() { acquired <- q.acquire(context.Background(), "c") }()
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("cancelled waiter kept the queue busy")
+	}
+}
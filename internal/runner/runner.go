@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -13,8 +13,10 @@ import (
 
 	"github.com/jamestiberiuskirk/stackr/internal/config"
 	"github.com/jamestiberiuskirk/stackr/internal/envfile"
+	"github.com/jamestiberiuskirk/stackr/internal/metrics"
 	"github.com/jamestiberiuskirk/stackr/internal/remote"
 	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
+	"github.com/jamestiberiuskirk/stackr/internal/stacklock"
 )
 
 const CommandTimeout = 15 * time.Minute
@@ -38,12 +40,62 @@ func (e *CommandError) Error() string {
 }
 
 type Runner struct {
-	cfg config.Config
-	mu  sync.Mutex
+	cfgMu   sync.RWMutex
+	cfg     config.Config
+	queue   *deployQueue
+	history *tagHistory
+	metrics *metrics.Metrics
+	// stackLocks keeps deploys from overlapping cron runs of the same stack
+	stackLocks *stacklock.Locks
+	logger     *slog.Logger
 }
 
 func New(cfg config.Config) *Runner {
-	return &Runner{cfg: cfg}
+	return &Runner{cfg: cfg, queue: newDeployQueue(), history: newTagHistory(cfg.RepoRoot), logger: slog.Default()}
+}
+
+// SetConfig replaces the config used by deploys that start from now on.
+func (r *Runner) SetConfig(cfg config.Config) {
+	r.cfgMu.Lock()
+	defer r.cfgMu.Unlock()
+	r.cfg = cfg
+}
+
+func (r *Runner) config() config.Config {
+	r.cfgMu.RLock()
+	defer r.cfgMu.RUnlock()
+	return r.cfg
+}
+
+// SetMetrics makes the runner count its deploys in m.
+func (r *Runner) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+// SetLogger makes the runner log deploys to l.
+func (r *Runner) SetLogger(l *slog.Logger) {
+	r.logger = l
+}
+
+// SetStackLocks makes deploys wait for anything else holding the stack's
+// lock in l, such as a running cron job.
+func (r *Runner) SetStackLocks(l *stacklock.Locks) {
+	r.stackLocks = l
+}
+
+type startedKey struct{}
+
+// WithStarted returns a copy of ctx that makes a deploy run with it call
+// started once it has left the deploy queue and holds the stack's lock.
+func WithStarted(ctx context.Context, started func()) context.Context {
+	return context.WithValue(ctx, startedKey{}, started)
+}
+
+// NotifyStarted calls the function WithStarted attached to ctx, if any.
+func NotifyStarted(ctx context.Context) {
+	if started, ok := ctx.Value(startedKey{}).(func()); ok {
+		started()
+	}
 }
 
 func parseDeployArgs(args []string) stackcmd.Options {
@@ -62,53 +114,109 @@ func parseDeployArgs(args []string) stackcmd.Options {
 			opts.GetVars = true
 		case "all":
 			opts.All = true
+		case "--accept-env-changes":
+			opts.AcceptEnvChanges = true
+		case "--no-pull":
+			opts.NoPull = true
+		case "--force-recreate":
+			opts.ForceRecreate = true
 		}
 	}
 	return opts
 }
 
 func (r *Runner) Deploy(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*Result, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	result, err := r.deploy(ctx, stack, stackCfg, tag)
+	r.metrics.DeployFinished(stack, err)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.history.record(stack, tag); err != nil {
+		r.logger.Warn("failed to record deploy history", "stack", stack, "tag", tag, "error", err)
+	}
+	return result, nil
+}
 
-	log.Printf("starting deployment: stack=%s tag=%s tagEnv=%s args=%v", stack, tag, stackCfg.TagEnv, stackCfg.Args)
-	log.Printf("config: RepoRoot=%s HostRepoRoot=%s StacksDir=%s", r.cfg.RepoRoot, r.cfg.HostRepoRoot, r.cfg.StacksDir)
+// Rollback redeploys the tag stack had before its last successful deploy,
+// as recorded in the deploy history. It returns ErrNoPreviousTag when there
+// is nothing to roll back to.
+func (r *Runner) Rollback(ctx context.Context, stack string, stackCfg config.StackConfig) (*Result, error) {
+	tag, err := r.history.previous(stack)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Info("rolling back", "stack", stack, "tag", tag)
 
-	snap, err := envfile.SnapshotFile(r.cfg.EnvFile)
+	result, err := r.deploy(ctx, stack, stackCfg, tag)
+	r.metrics.DeployFinished(stack, err)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.history.rolledBack(stack, tag); err != nil {
+		r.logger.Warn("failed to record rollback history", "stack", stack, "tag", tag, "error", err)
+	}
+	return result, nil
+}
+
+func (r *Runner) deploy(ctx context.Context, stack string, stackCfg config.StackConfig, tag string) (*Result, error) {
+	if err := r.queue.acquire(ctx, stack); err != nil {
+		return nil, fmt.Errorf("deploy of %s cancelled while queued: %w", stack, err)
+	}
+	defer r.queue.release()
+
+	logger := r.logger.With("stack", stack, "tag", tag)
+
+	unlock, ok := r.stackLocks.TryLock(stack)
+	if !ok {
+		logger.Info("deploy waiting for the stack's running cron jobs to finish")
+		var err error
+		if unlock, err = r.stackLocks.Lock(ctx, stack); err != nil {
+			return nil, fmt.Errorf("deploy of %s cancelled while waiting for a cron job: %w", stack, err)
+		}
+	}
+	defer unlock()
+	NotifyStarted(ctx)
+
+	cfg := r.config()
+
+	logger.Info("starting deployment", "status", "started", "tag_env", stackCfg.TagEnv, "args", stackCfg.Args)
+	logger.Info("deploy config", "repo_root", cfg.RepoRoot, "host_repo_root", cfg.HostRepoRoot, "stacks_dir", cfg.StacksDir)
+
+	snap, err := envfile.SnapshotFile(cfg.EnvFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read env file: %w", err)
 	}
 
-	previous, err := envfile.Update(r.cfg.EnvFile, stackCfg.TagEnv, tag)
+	previous, err := envfile.Update(cfg.EnvFile, stackCfg.TagEnv, tag)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update env file: %w", err)
 	}
 
-	log.Printf("updated %s to %s (previous: %s)", stackCfg.TagEnv, tag, previous)
+	logger.Info("updated tag", "tag_env", stackCfg.TagEnv, "previous", previous)
 
 	// Check if remote stack and sync before deployment
-	stackInfo, err := stackcmd.ResolveStackPath(r.cfg, stack)
+	stackInfo, err := stackcmd.ResolveStackPath(cfg, stack)
 	if err != nil {
-		if rollbackErr := envfile.Restore(r.cfg.EnvFile, snap); rollbackErr != nil {
-			log.Printf("failed to roll back %s after stack resolution error: %v", stackCfg.TagEnv, rollbackErr)
+		if rollbackErr := envfile.Restore(cfg.EnvFile, snap); rollbackErr != nil {
+			logger.Error("failed to roll back tag after stack resolution error", "tag_env", stackCfg.TagEnv, "error", rollbackErr)
 		}
 		return nil, fmt.Errorf("failed to resolve stack: %w", err)
 	}
 
 	if stackInfo.Type == stackcmd.StackTypeRemote {
 		// Read current .env for variable resolution
-		envVals, _, err := readEnvFile(r.cfg.EnvFile)
+		envVals, _, err := readEnvFile(cfg.EnvFile)
 		if err != nil {
-			if rollbackErr := envfile.Restore(r.cfg.EnvFile, snap); rollbackErr != nil {
-				log.Printf("failed to roll back %s after env read error: %v", stackCfg.TagEnv, rollbackErr)
+			if rollbackErr := envfile.Restore(cfg.EnvFile, snap); rollbackErr != nil {
+				logger.Error("failed to roll back tag after env read error", "tag_env", stackCfg.TagEnv, "error", rollbackErr)
 			}
 			return nil, fmt.Errorf("failed to read env file: %w", err)
 		}
 
-		remoteMgr := remote.NewManager(r.cfg)
+		remoteMgr := remote.NewManager(cfg)
 		if err := remoteMgr.EnsureRemoteStack(ctx, stack, envVals); err != nil {
 			// Use cached version on git failure (graceful degradation)
-			log.Printf("warning: git sync failed for %s, using cached version: %v", stack, err)
+			logger.Warn("git sync failed, using cached version", "error", err)
 		}
 	}
 
@@ -117,16 +225,21 @@ func (r *Runner) Deploy(ctx context.Context, stack string, stackCfg config.Stack
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	manager, err := stackcmd.NewManagerWithWriters(r.cfg, &stdout, &stderr)
+	manager, err := stackcmd.NewManagerWithWriters(cfg, &stdout, &stderr)
 	if err != nil {
-		if rollbackErr := envfile.Restore(r.cfg.EnvFile, snap); rollbackErr != nil {
-			log.Printf("failed to roll back %s after manager creation error: %v", stackCfg.TagEnv, rollbackErr)
+		if rollbackErr := envfile.Restore(cfg.EnvFile, snap); rollbackErr != nil {
+			logger.Error("failed to roll back tag after manager creation error", "tag_env", stackCfg.TagEnv, "error", rollbackErr)
 		}
 		return nil, fmt.Errorf("failed to create stack manager: %w", err)
 	}
 
 	opts := parseDeployArgs(stackCfg.Args)
 	opts.Stacks = []string{stack}
+	// Deploys are unattended, never prompt. Remote env changes are accepted
+	// since nobody is there to re-run with --accept-env-changes; the diff is
+	// logged instead.
+	opts.Yes = true
+	opts.AcceptEnvChanges = true
 
 	// For remote stacks, wrap deploy in retry logic to handle the case where
 	// a git tag exists but the Docker image hasn't been published yet.
@@ -140,14 +253,14 @@ func (r *Runner) Deploy(ctx context.Context, stack string, stackCfg config.Stack
 	}
 
 	if runErr != nil {
-		log.Printf("deployment failed for stack=%s: %v", stack, runErr)
-		log.Printf("deployment stdout: (%d bytes, redacted from logs)", stdout.Len())
-		log.Printf("deployment stderr: (%d bytes, redacted from logs)", stderr.Len())
+		// The output is left out, it can contain secrets
+		logger.Error("deployment failed", "status", "failed", "error", runErr,
+			"stdout_bytes", stdout.Len(), "stderr_bytes", stderr.Len())
 
-		if rollbackErr := envfile.Restore(r.cfg.EnvFile, snap); rollbackErr != nil {
-			log.Printf("failed to roll back %s after deploy error: %v", stackCfg.TagEnv, rollbackErr)
+		if rollbackErr := envfile.Restore(cfg.EnvFile, snap); rollbackErr != nil {
+			logger.Error("failed to roll back tag after deploy error", "tag_env", stackCfg.TagEnv, "error", rollbackErr)
 		} else {
-			log.Printf("rolled back %s to previous value after deploy failure", stackCfg.TagEnv)
+			logger.Info("rolled back tag to previous value after deploy failure", "tag_env", stackCfg.TagEnv)
 		}
 
 		return nil, &CommandError{
@@ -156,14 +269,20 @@ func (r *Runner) Deploy(ctx context.Context, stack string, stackCfg config.Stack
 		}
 	}
 
-	log.Printf("deployment finished for stack=%s tag=%s", stack, tag)
+	logger.Info("deployment finished", "status", "succeeded")
+
+	return NewResult(stack, tag, stdout.String()), nil
+}
 
+// NewResult builds the result of a successful deployment from the captured
+// stack manager output.
+func NewResult(stack, tag, stdout string) *Result {
 	return &Result{
 		Status: "ok",
 		Stack:  stack,
 		Tag:    tag,
-		Stdout: strings.TrimSpace(stdout.String()),
-	}, nil
+		Stdout: strings.TrimSpace(stdout),
+	}
 }
 
 // readEnvFile reads and parses the env file using godotenv for consistent
@@ -1,9 +1,18 @@
 package runner
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/jamestiberiuskirk/stackr/internal/config"
 	"github.com/jamestiberiuskirk/stackr/internal/stackcmd"
+	"github.com/jamestiberiuskirk/stackr/internal/stacklock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,6 +54,11 @@ func TestParseDeployArgs(t *testing.T) {
 			args: []string{"testapp", "update"},
 			want: stackcmd.Options{Update: true},
 		},
+		{
+			name: "UpdateFlags",
+			args: []string{"update", "--no-pull", "--force-recreate"},
+			want: stackcmd.Options{Update: true, NoPull: true, ForceRecreate: true},
+		},
 		{
 			name: "UpdateOnly",
 			args: []string{"update"},
@@ -106,3 +120,102 @@ func TestCommandErrorDoesNotLeakOutput(t *testing.T) {
 		require.EqualError(t, err, "test error")
 	})
 }
+
+// deployRepo sets up a repo with an "app" stack tagged v1 and a stub docker
+// binary, returning the config and the file docker logs its arguments to.
+func deployRepo(t *testing.T) (config.Config, string) {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "stacks", "app"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "stacks", "app", "docker-compose.yml"), []byte("services:\n  web:\n    image: nginx:${APP_TAG}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), []byte("APP_TAG=v1\n"), 0o644))
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+	}
+
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := "#!/bin/sh\necho \"$@\" >> \"" + logPath + "\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return cfg, logPath
+}
+
+func TestDeployLogsStructuredFields(t *testing.T) {
+	cfg, _ := deployRepo(t)
+
+	var buf bytes.Buffer
+	r := New(cfg)
+	r.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	_, err := r.Deploy(context.Background(), "app", config.StackConfig{TagEnv: "APP_TAG", Args: []string{"update"}}, "v2")
+	require.NoError(t, err)
+
+	var statuses []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var line map[string]any
+		require.NoError(t, dec.Decode(&line))
+		require.Equal(t, "app", line["stack"], "every deploy line names the stack: %v", line)
+		require.Equal(t, "v2", line["tag"], "every deploy line names the tag: %v", line)
+		if status, ok := line["status"].(string); ok {
+			statuses = append(statuses, status)
+		}
+	}
+	require.Equal(t, []string{"started", "succeeded"}, statuses)
+}
+
+func TestDeployWaitsForStackLock(t *testing.T) {
+	cfg, logPath := deployRepo(t)
+	root := cfg.RepoRoot
+	stack := "app"
+
+	locks := stacklock.New()
+	r := New(cfg)
+	r.SetStackLocks(locks)
+
+	// Stands in for a cron run of the stack
+	unlock, ok := locks.TryRLock(stack)
+	require.True(t, ok)
+
+	started := make(chan struct{})
+	ctx := WithStarted(context.Background(), func() { close(started) })
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Deploy(ctx, stack, config.StackConfig{TagEnv: "APP_TAG", Args: []string{"update"}}, "v2")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("deploy finished while the stack was locked: %v", err)
+	case <-started:
+		t.Fatal("deploy reported started while the stack was locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoFileExists(t, logPath)
+	env, err := os.ReadFile(filepath.Join(root, ".env"))
+	require.NoError(t, err)
+	require.Equal(t, "APP_TAG=v1\n", string(env))
+
+	unlock()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("deploy did not finish after the stack was unlocked")
+	}
+	require.FileExists(t, logPath)
+	select {
+	case <-started:
+	default:
+		t.Fatal("deploy never reported started")
+	}
+
+	// The deploy released the lock
+	unlock, ok = locks.TryLock(stack)
+	require.True(t, ok)
+	unlock()
+}
@@ -0,0 +1,307 @@
+package stackcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jamestiberiuskirk/stackr/internal/fsutil"
+)
+
+const backupManifestFile = "backup-manifest.json"
+
+// backupManifest is written into every stack backup dir so later incremental
+// backups know what they are relative to.
+type backupManifest struct {
+	Stack     string    `json:"stack"`
+	Type      string    `json:"type"`
+	StartedAt time.Time `json:"started_at"`
+	// Base is the timestamp dir of the backup this one is incremental on top of.
+	Base string `json:"base,omitempty"`
+}
+
+// lastBackupManifest returns the manifest of the most recent backup of stack
+// and the name of its timestamp dir, or nil if there is none.
+func (m *Manager) lastBackupManifest(stack string) (*backupManifest, string, error) {
+	entries, err := os.ReadDir(m.backupDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to read backup dir: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	// Timestamp dirs sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(m.backupDir, name, stack, backupManifestFile))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, "", err
+		}
+		var manifest backupManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, "", fmt.Errorf("failed to parse backup manifest in %s: %w", name, err)
+		}
+		return &manifest, name, nil
+	}
+	return nil, "", nil
+}
+
+// backupTimestampLayout names the per-run dirs under the backup dir.
+const backupTimestampLayout = "20060102_150405"
+
+// backupsToPrune returns the timestamped dirs in backupDir holding a backup
+// of stack beyond the keep most recent, oldest first. Backups that a kept
+// incremental backup (or pinned, the base of one about to be taken) builds
+// on are kept too. Other entries are never touched.
+func backupsToPrune(backupDir, stack string, keep int, pinned string) ([]string, error) {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup dir: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupTimestampLayout, entry.Name()); err != nil {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(backupDir, entry.Name(), stack)); err != nil || !info.IsDir() {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	// Timestamp dirs sort chronologically
+	sort.Strings(names)
+	if len(names) <= keep {
+		return nil, nil
+	}
+
+	kept := map[string]bool{}
+	roots := names[len(names)-max(keep, 0):]
+	if pinned != "" {
+		roots = append(roots, pinned)
+	}
+	for _, name := range roots {
+		// Walk down to the full backup the incremental chain starts from
+		for name != "" && !kept[name] {
+			kept[name] = true
+			manifest, err := readBackupManifest(filepath.Join(backupDir, name, stack))
+			if err != nil {
+				return nil, err
+			}
+			if manifest == nil || manifest.Type != "incremental" {
+				break
+			}
+			name = manifest.Base
+		}
+	}
+
+	var prune []string
+	for _, name := range names {
+		if !kept[name] {
+			prune = append(prune, name)
+		}
+	}
+	return prune, nil
+}
+
+// readBackupManifest reads the manifest of the backup in dir, or returns nil
+// if it has none.
+func readBackupManifest(dir string) (*backupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse backup manifest in %s: %w", dir, err)
+	}
+	return &manifest, nil
+}
+
+// pruneBackups deletes the backups of stack beyond the keep most recent (see
+// backupsToPrune) and returns their paths. A timestamped dir is removed once
+// no other stack's backup is left in it.
+func pruneBackups(backupDir, stack string, keep int) ([]string, error) {
+	names, err := backupsToPrune(backupDir, stack, keep, "")
+	if err != nil {
+		return nil, err
+	}
+	var pruned []string
+	for _, name := range names {
+		dir := filepath.Join(backupDir, name, stack)
+		if err := os.RemoveAll(dir); err != nil {
+			return pruned, fmt.Errorf("failed to prune backup %s: %w", dir, err)
+		}
+		pruned = append(pruned, dir)
+		// Fails while other stacks' backups are still in it
+		_ = os.Remove(filepath.Join(backupDir, name))
+	}
+	return pruned, nil
+}
+
+// applyBackupRetention prunes old backups of stack down to
+// paths.backup_retention. Under --dry-run the backup was not written, so one
+// fewer existing backup is kept, plus base, the backup it would have been
+// incremental on.
+func (m *Manager) applyBackupRetention(stack, base string, opts Options) error {
+	keep := m.cfg.Global.Paths.BackupRetention
+	if keep <= 0 {
+		return nil
+	}
+
+	if opts.DryRun {
+		names, err := backupsToPrune(m.backupDir, stack, keep-1, base)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			_, _ = fmt.Fprintf(m.stdout, "[DRY RUN] Would delete old backup: %s\n", filepath.Join(m.backupDir, name, stack))
+		}
+		return nil
+	}
+
+	pruned, err := pruneBackups(m.backupDir, stack, keep)
+	for _, dir := range pruned {
+		_, _ = fmt.Fprintf(m.stdout, "Deleted old backup: %s\n", dir)
+	}
+	return err
+}
+
+func writeBackupManifest(dest string, manifest backupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dest, backupManifestFile), append(data, '\n'), 0o644)
+}
+
+// checkBackupSpace estimates how much the backup of sources will copy and
+// refuses to start if it would leave less than the configured headroom free
+// on the backup filesystem, rather than failing halfway through the copy.
+func (m *Manager) checkBackupSpace(stack string, sources []string, since time.Time) error {
+	var needed int64
+	for _, src := range sources {
+		size, err := fsutil.DirSize(src, since)
+		if err != nil {
+			return fmt.Errorf("failed to estimate backup size of %s: %w", src, err)
+		}
+		needed += size
+	}
+
+	available, err := m.availableSpace(m.backupDir)
+	if err != nil {
+		return fmt.Errorf("failed to check free space in %s: %w", m.backupDir, err)
+	}
+
+	headroom := m.cfg.Global.Backup.Headroom()
+	if uint64(needed+headroom) > available {
+		return fmt.Errorf("not enough space to back up %s: needs %s plus %s headroom, %s available in %s",
+			stack, formatBytes(uint64(needed)), formatBytes(uint64(headroom)), formatBytes(available), m.backupDir)
+	}
+	return nil
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// volumeBackupImage runs tar inside the helper container that dumps a named
+// volume.
+const volumeBackupImage = "alpine"
+
+// stackVolumes lists the named docker volumes compose created for stack.
+func stackVolumes(ctx context.Context, stack string) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "docker", "volume", "ls", "-q",
+		"--filter", fmt.Sprintf("label=com.docker.compose.project=%s", stack)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes of %s: %w", stack, err)
+	}
+	volumes := strings.Fields(string(out))
+	sort.Strings(volumes)
+	return volumes, nil
+}
+
+// backupVolumes dumps each named volume of stack into dest as
+// volume_<name>.tar.gz using a throwaway helper container. Volumes are
+// always dumped in full, even for incremental backups.
+func (m *Manager) backupVolumes(ctx context.Context, stack, dest string, opts Options) error {
+	volumes, err := stackVolumes(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if len(volumes) == 0 {
+		debugf(opts.Debug, "%s: no named volumes to back up", stack)
+		return nil
+	}
+
+	for _, volume := range volumes {
+		archive := fmt.Sprintf("volume_%s.tar.gz", volume)
+		if opts.DryRun {
+			_, _ = fmt.Fprintf(m.stdout, "  [DRY RUN] Would backup volume: %s -> %s\n", volume, filepath.Join(dest, archive))
+			continue
+		}
+
+		args := []string{"run", "--rm",
+			"-v", volume + ":/data:ro",
+			"-v", m.hostPath(dest) + ":/backup",
+			volumeBackupImage, "tar", "czf", "/backup/" + archive, "-C", "/data", "."}
+		debugf(opts.Debug, "%s: running docker %s", stack, strings.Join(args, " "))
+		out, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to backup volume %s: %v\n%s", volume, err, strings.TrimSpace(string(out)))
+		}
+		_, _ = fmt.Fprintf(m.stdout, "  ✓ Backed up volume %s\n", volume)
+	}
+	return nil
+}
+
+// hostPath maps a path under the repo root to the same path under
+// STACKR_HOST_REPO_ROOT, for bind mounts handed to the docker daemon when
+// stackr itself runs in a container.
+func (m *Manager) hostPath(path string) string {
+	repoRoot := filepath.Clean(m.cfg.RepoRoot)
+	if m.cfg.HostRepoRoot == "" || m.cfg.RepoRoot == "" || !isWithinDir(repoRoot, path) {
+		return path
+	}
+	rel, err := filepath.Rel(repoRoot, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(m.cfg.HostRepoRoot, rel)
+}
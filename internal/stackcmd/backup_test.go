@@ -0,0 +1,419 @@
+package stackcmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestIncrementalBackupCopiesOnlyChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+
+	makeDirs(t, root, ".ssd_pool/app/data")
+	oldFile := filepath.Join(root, ".ssd_pool/app/data/old.log")
+	newFile := filepath.Join(root, ".ssd_pool/app/data/new.log")
+	writeFile(t, oldFile, "old")
+	writeFile(t, newFile, "new")
+
+	now := time.Now()
+	require.NoError(t, os.Chtimes(oldFile, now.Add(-2*time.Hour), now.Add(-2*time.Hour)))
+
+	// Previous full backup taken an hour ago
+	baseDir := filepath.Join(root, "backups", "20200101_000000", "app")
+	require.NoError(t, os.MkdirAll(baseDir, 0o755))
+	require.NoError(t, writeBackupManifest(baseDir, backupManifest{
+		Stack:     "app",
+		Type:      "full",
+		StartedAt: now.Add(-time.Hour),
+	}))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	opts := Options{Stacks: []string{"app"}, Backup: true, Incremental: true}
+	require.NoError(t, manager.Run(context.Background(), opts))
+
+	manifest, dir, err := manager.lastBackupManifest("app")
+	require.NoError(t, err)
+	require.NotEqual(t, "20200101_000000", dir)
+	require.Equal(t, "incremental", manifest.Type)
+	require.Equal(t, "20200101_000000", manifest.Base)
+
+	dest := filepath.Join(root, "backups", dir, "app", "pool_ssd", "data")
+	require.FileExists(t, filepath.Join(dest, "new.log"))
+	require.NoFileExists(t, filepath.Join(dest, "old.log"))
+
+	data, err := os.ReadFile(filepath.Join(root, "backups", dir, "app", backupManifestFile))
+	require.NoError(t, err)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	require.Equal(t, "20200101_000000", raw["base"])
+}
+
+func TestFullBackupOverridesIncremental(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	makeDirs(t, root, ".ssd_pool/app")
+	oldFile := filepath.Join(root, ".ssd_pool/app/old.log")
+	writeFile(t, oldFile, "old")
+	past := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(oldFile, past, past))
+
+	baseDir := filepath.Join(root, "backups", "20200101_000000", "app")
+	require.NoError(t, os.MkdirAll(baseDir, 0o755))
+	require.NoError(t, writeBackupManifest(baseDir, backupManifest{Stack: "app", Type: "full", StartedAt: time.Now().Add(-time.Hour)}))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	opts := Options{Stacks: []string{"app"}, Backup: true, Incremental: true, Full: true}
+	require.NoError(t, manager.Run(context.Background(), opts))
+
+	manifest, dir, err := manager.lastBackupManifest("app")
+	require.NoError(t, err)
+	require.Equal(t, "full", manifest.Type)
+	require.Empty(t, manifest.Base)
+	require.FileExists(t, filepath.Join(root, "backups", dir, "app", "pool_ssd", "old.log"))
+}
+
+func TestBackupPreservesOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing file owners requires root")
+	}
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	makeDirs(t, root, ".ssd_pool/app/data")
+	dataFile := filepath.Join(root, ".ssd_pool/app/data/db.sqlite")
+	writeFile(t, dataFile, "rows")
+	require.NoError(t, os.Chown(filepath.Join(root, ".ssd_pool/app/data"), 1234, 1234))
+	require.NoError(t, os.Chown(dataFile, 1234, 5678))
+
+	global := testGlobalConfig()
+	global.Backup.PreserveOwnership = true
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true}))
+
+	_, dir, err := manager.lastBackupManifest("app")
+	require.NoError(t, err)
+	dest := filepath.Join(root, "backups", dir, "app", "pool_ssd", "data")
+
+	owner := func(path string) (uint32, uint32) {
+		info, err := os.Lstat(path)
+		require.NoError(t, err)
+		stat := info.Sys().(*syscall.Stat_t)
+		return stat.Uid, stat.Gid
+	}
+	uid, gid := owner(dest)
+	require.Equal(t, []uint32{1234, 1234}, []uint32{uid, gid})
+	uid, gid = owner(filepath.Join(dest, "db.sqlite"))
+	require.Equal(t, []uint32{1234, 5678}, []uint32{uid, gid})
+}
+
+func TestBackupRefusesWhenSpaceIsInsufficient(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	makeDirs(t, root, ".hdd_pool/app")
+	writeFile(t, filepath.Join(root, ".hdd_pool/app/data.bin"), string(make([]byte, 4096)))
+
+	headroom := 1
+	global := testGlobalConfig()
+	global.Backup.HeadroomMB = &headroom
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	// 4 KiB of data plus 1 MiB headroom does not fit in 1 MiB
+	manager.availableSpace = func(string) (uint64, error) { return 1 << 20, nil }
+
+	err = manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not enough space to back up app")
+	require.NoDirExists(t, filepath.Join(root, "backups"), "no partial backup should be written")
+
+	manager.availableSpace = func(string) (uint64, error) { return 2 << 20, nil }
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true}))
+}
+
+func TestCompressedBackup(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app/config/conf.d")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	writeFile(t, filepath.Join(root, "stacks/app/config/conf.d/site.conf"), "server {}")
+	makeDirs(t, root, ".ssd_pool/app/data")
+	writeFile(t, filepath.Join(root, ".ssd_pool/app/data/db.sqlite"), "rows")
+
+	global := testGlobalConfig()
+	global.Paths.BackupCompress = true
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true, DryRun: true}))
+	require.Contains(t, stdout.String(), filepath.Join("app", "config.tar.gz"))
+	require.Contains(t, stdout.String(), filepath.Join("app", "pool_ssd.tar.gz"))
+	require.NoDirExists(t, filepath.Join(root, "backups"))
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true}))
+	_, dir, err := manager.lastBackupManifest("app")
+	require.NoError(t, err)
+	dest := filepath.Join(root, "backups", dir, "app")
+	require.NoDirExists(t, filepath.Join(dest, "config"))
+
+	entries := func(archive string) map[string]string {
+		f, err := os.Open(archive)
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		tr := tar.NewReader(gz)
+		contents := map[string]string{}
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return contents
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			contents[header.Name] = string(data)
+		}
+	}
+	require.Equal(t, map[string]string{"conf.d/": "", "conf.d/site.conf": "server {}"}, entries(filepath.Join(dest, "config.tar.gz")))
+	require.Equal(t, map[string]string{"data/": "", "data/db.sqlite": "rows"}, entries(filepath.Join(dest, "pool_ssd.tar.gz")))
+}
+
+func TestPruneBackups(t *testing.T) {
+	backupDir := t.TempDir()
+	for _, name := range []string{"20240101_000000", "20240102_000000", "20240103_000000", "20240104_000000", "manual-copy"} {
+		makeDirs(t, backupDir, filepath.Join(name, "app"))
+	}
+	writeFile(t, filepath.Join(backupDir, "20230101_000000"), "not a backup dir")
+
+	pruned, err := pruneBackups(backupDir, "app", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(backupDir, "20240101_000000", "app"), filepath.Join(backupDir, "20240102_000000", "app")}, pruned)
+
+	entries, err := os.ReadDir(backupDir)
+	require.NoError(t, err)
+	var left []string
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+	require.Equal(t, []string{"20230101_000000", "20240103_000000", "20240104_000000", "manual-copy"}, left)
+
+	pruned, err = pruneBackups(backupDir, "app", 2)
+	require.NoError(t, err)
+	require.Empty(t, pruned)
+
+	pruned, err = pruneBackups(filepath.Join(backupDir, "missing"), "app", 2)
+	require.NoError(t, err)
+	require.Empty(t, pruned)
+}
+
+func TestBackupRetentionPerStackKeepsIncrementalBase(t *testing.T) {
+	backupDir := t.TempDir()
+	backup := func(ts, stack, typ, base string) {
+		dir := filepath.Join(backupDir, ts, stack)
+		makeDirs(t, backupDir, filepath.Join(ts, stack))
+		require.NoError(t, writeBackupManifest(dir, backupManifest{Stack: stack, Type: typ, Base: base}))
+	}
+	// app: a full backup with two incrementals on top; db: one backup per run
+	backup("20240101_000000", "app", "full", "")
+	backup("20240101_000000", "db", "full", "")
+	backup("20240102_000000", "app", "incremental", "20240101_000000")
+	backup("20240102_000000", "db", "full", "")
+	backup("20240103_000000", "app", "incremental", "20240102_000000")
+	backup("20240103_000000", "db", "full", "")
+	backup("20240104_000000", "db", "full", "")
+
+	// Only db's own backups count, so app's are all kept
+	pruned, err := pruneBackups(backupDir, "db", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(backupDir, "20240101_000000", "db"), filepath.Join(backupDir, "20240102_000000", "db")}, pruned)
+	for _, ts := range []string{"20240101_000000", "20240102_000000", "20240103_000000"} {
+		require.DirExists(t, filepath.Join(backupDir, ts, "app"))
+	}
+
+	// The two newest app backups are incremental on the oldest, which stays
+	pruned, err = pruneBackups(backupDir, "app", 2)
+	require.NoError(t, err)
+	require.Empty(t, pruned)
+
+	// Once a new full backup is kept, the old chain goes, and so do the
+	// timestamp dirs it leaves empty
+	backup("20240105_000000", "app", "full", "")
+	pruned, err = pruneBackups(backupDir, "app", 1)
+	require.NoError(t, err)
+	require.Len(t, pruned, 3)
+	require.NoDirExists(t, filepath.Join(backupDir, "20240101_000000"))
+	require.NoDirExists(t, filepath.Join(backupDir, "20240102_000000"))
+	require.NoDirExists(t, filepath.Join(backupDir, "20240103_000000", "app"))
+	require.DirExists(t, filepath.Join(backupDir, "20240103_000000", "db"))
+	require.DirExists(t, filepath.Join(backupDir, "20240105_000000", "app"))
+}
+
+func TestBackupAllWithRetentionKeepsEachStacksBackup(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"app", "db"} {
+		makeDirs(t, root, "stacks/"+stack+"/config")
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+		makeDirs(t, root, "backups/20240101_000000/"+stack)
+	}
+
+	global := testGlobalConfig()
+	global.Paths.BackupRetention = 1
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	manager, err := NewManagerWithWriters(cfg, io.Discard, io.Discard)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{All: true, Backup: true}))
+	require.NoDirExists(t, filepath.Join(root, "backups", "20240101_000000"))
+	// Backing up db didn't prune the backup just taken of app
+	for _, stack := range []string{"app", "db"} {
+		backups, err := filepath.Glob(filepath.Join(root, "backups", "*", stack))
+		require.NoError(t, err)
+		require.Len(t, backups, 1, stack)
+	}
+}
+
+func TestBackupRetentionDryRun(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	makeDirs(t, root, "backups/20240101_000000/app")
+	makeDirs(t, root, "backups/20240102_000000/app")
+
+	global := testGlobalConfig()
+	global.Paths.BackupRetention = 2
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true, DryRun: true}))
+	require.Contains(t, stdout.String(), "[DRY RUN] Would delete old backup: "+filepath.Join(root, "backups", "20240101_000000"))
+	require.NotContains(t, stdout.String(), "20240102_000000")
+	require.DirExists(t, filepath.Join(root, "backups", "20240101_000000"))
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true}))
+	require.NoDirExists(t, filepath.Join(root, "backups", "20240101_000000"))
+	require.DirExists(t, filepath.Join(root, "backups", "20240102_000000"))
+}
+
+func TestBackupNamedVolumes(t *testing.T) {
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	writeFile(t, filepath.Join(binDir, "docker"), `#!/bin/sh
+echo "$@" >> "`+logPath+`"
+case "$*" in
+  "volume ls"*) printf 'app_media\napp_db\n' ;;
+esac
+`)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app/config")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	cfg := config.Config{
+		RepoRoot:     root,
+		HostRepoRoot: "/srv/stackr",
+		EnvFile:      filepath.Join(root, ".env"),
+		StacksDir:    filepath.Join(root, "stacks"),
+		Global:       testGlobalConfig(),
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true, Volumes: true, DryRun: true}))
+	require.Contains(t, stdout.String(), "[DRY RUN] Would backup volume: app_db -> ")
+	require.Contains(t, stdout.String(), "[DRY RUN] Would backup volume: app_media -> ")
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, "volume ls -q --filter label=com.docker.compose.project=app\n", string(logData))
+
+	require.NoError(t, os.Remove(logPath))
+	stdout.Reset()
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true, Volumes: true}))
+	_, dir, err := manager.lastBackupManifest("app")
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), "✓ Backed up volume app_db")
+	require.Contains(t, stdout.String(), "✓ Backed up volume app_media")
+
+	logData, err = os.ReadFile(logPath)
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(logData)), "\n")
+	require.Equal(t, []string{
+		"volume ls -q --filter label=com.docker.compose.project=app",
+		"run --rm -v app_db:/data:ro -v " + filepath.Join("/srv/stackr/backups", dir, "app") + ":/backup alpine tar czf /backup/volume_app_db.tar.gz -C /data .",
+		"run --rm -v app_media:/data:ro -v " + filepath.Join("/srv/stackr/backups", dir, "app") + ":/backup alpine tar czf /backup/volume_app_media.tar.gz -C /data .",
+	}, calls)
+}
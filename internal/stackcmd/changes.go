@@ -0,0 +1,74 @@
+package stackcmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// configHashLabel is set by compose on every container to the hash of the
+// service config it was created from.
+const configHashLabel = "com.docker.compose.config-hash"
+
+// stackChanged reports whether bringing the stack up would change anything:
+// a service's resolved compose config (image tags included) differs from
+// the config its container was created from, or a service has no running
+// container. It returns a short reason for the first difference found.
+func (m *Manager) stackChanged(ctx context.Context, env []string, stackInfo StackInfo) (bool, string, error) {
+	out, err := m.composeOutput(ctx, env, stackInfo, "config", "--hash", "*")
+	if err != nil {
+		return false, "", err
+	}
+	desired := parseServiceHashes(out)
+
+	ids, err := m.composeOutput(ctx, env, stackInfo, "ps", "-a", "-q")
+	if err != nil {
+		return false, "", err
+	}
+	running := map[string]string{}
+	if ids != "" {
+		format := fmt.Sprintf(`{{index .Config.Labels "com.docker.compose.service"}} {{index .Config.Labels %q}} {{.State.Running}}`, configHashLabel)
+		args := append([]string{"inspect", "--format", format}, strings.Fields(ids)...)
+		cmd := exec.CommandContext(ctx, "docker", args...)
+		cmd.Dir = m.cfg.RepoRoot
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return false, "", fmt.Errorf("docker inspect failed: %v\n%s", err, string(out))
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 3 || fields[2] != "true" {
+				continue
+			}
+			running[fields[0]] = fields[1]
+		}
+	}
+
+	for _, service := range slices.Sorted(maps.Keys(desired)) {
+		current, ok := running[service]
+		if !ok {
+			return true, fmt.Sprintf("%s is not running", service), nil
+		}
+		if current != desired[service] {
+			return true, fmt.Sprintf("%s config changed", service), nil
+		}
+	}
+	return false, "", nil
+}
+
+// parseServiceHashes parses "compose config --hash" output: one
+// "<service> <hash>" pair per line.
+func parseServiceHashes(out string) map[string]string {
+	hashes := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			hashes[fields[0]] = fields[1]
+		}
+	}
+	return hashes
+}
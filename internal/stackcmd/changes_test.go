@@ -0,0 +1,58 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestOnlyChangedSkipsUnchangedStacks(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"same", "stale"} {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	}
+
+	// docker stub: "config --hash" reports hash h1 for every stack; the
+	// running container of "stale" was created from hash h0
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+  *"config --hash"*) echo "app h1" ;;
+  *"ps -a -q"*) case "$*" in *stale*) echo stale1 ;; *) echo same1 ;; esac ;;
+  "inspect "*stale1) echo "app h0 true" ;;
+  "inspect "*same1) echo "app h1 true" ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{All: true, Update: true, OnlyChanged: true}))
+	require.Contains(t, stdout.String(), "same: no changes, skipping")
+	require.NotContains(t, stdout.String(), "stale: no changes")
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.NotContains(t, string(logData), filepath.Join("stacks", "same", "docker-compose.yml")+" pull")
+	require.Contains(t, string(logData), filepath.Join("stacks", "stale", "docker-compose.yml")+" pull")
+}
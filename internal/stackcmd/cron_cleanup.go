@@ -0,0 +1,74 @@
+package stackcmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// CleanupCronContainers removes old cron job containers, keeping the newest
+// retention per service. With stack set only that stack's containers are
+// considered. It returns the names of the removed containers.
+func CleanupCronContainers(ctx context.Context, stack string, retention int) ([]string, error) {
+	// List all containers with name pattern: *-cron-*
+	args := []string{"ps", "-a", "--filter", "name=-cron-"}
+	if stack != "" {
+		args = append(args, "--filter", fmt.Sprintf("label=com.docker.compose.project=%s", stack))
+	}
+	args = append(args, "--format", "{{.Names}}")
+	cmd := exec.CommandContext(ctx, "docker", args...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	containerNames := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(containerNames) == 0 || containerNames[0] == "" {
+		return nil, nil // No containers to clean
+	}
+
+	// Group containers by stack-service
+	containersByService := make(map[string][]string)
+	for _, name := range containerNames {
+		// Parse: mystack-scraper-cron-1735392000
+		parts := strings.Split(name, "-cron-")
+		if len(parts) != 2 {
+			continue
+		}
+		serviceKey := parts[0] // "mystack-scraper"
+		if stack != "" && !strings.HasPrefix(serviceKey, stack+"-") {
+			continue
+		}
+		containersByService[serviceKey] = append(containersByService[serviceKey], name)
+	}
+
+	// For each service, sort by timestamp and remove old containers
+	var removed []string
+	for _, containers := range containersByService {
+		if len(containers) <= retention {
+			continue // Don't exceed retention limit
+		}
+
+		// Sort by timestamp (newest first)
+		sort.Slice(containers, func(i, j int) bool {
+			return containers[i] > containers[j]
+		})
+
+		// Remove containers beyond retention
+		toRemove := containers[retention:]
+		for _, containerName := range toRemove {
+			rmCmd := exec.CommandContext(ctx, "docker", "rm", containerName)
+			if err := rmCmd.Run(); err != nil {
+				log.Printf("failed to remove container %s: %v", containerName, err)
+				continue
+			}
+			removed = append(removed, containerName)
+		}
+	}
+
+	return removed, nil
+}
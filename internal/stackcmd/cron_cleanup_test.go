@@ -0,0 +1,89 @@
+package stackcmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+// stubDockerCronContainers installs a docker stub that logs its args and
+// lists cron containers of two stacks for "ps -a", ignoring any filters.
+func stubDockerCronContainers(t *testing.T) string {
+	t.Helper()
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+  "ps -a --filter name=-cron-"*)
+    printf '%s\n' demo-backup-cron-300 demo-backup-cron-100 demo-backup-cron-200 demo-sync-cron-100 other-backup-cron-100 other-backup-cron-200 ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func removedContainers(t *testing.T, logPath string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	var removed []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if name, ok := strings.CutPrefix(line, "rm "); ok {
+			removed = append(removed, name)
+		}
+	}
+	return removed
+}
+
+func TestCleanupCronContainersScopedToStack(t *testing.T) {
+	logPath := stubDockerCronContainers(t)
+
+	removed, err := CleanupCronContainers(context.Background(), "demo", 1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"demo-backup-cron-200", "demo-backup-cron-100"}, removed)
+	require.ElementsMatch(t, removed, removedContainers(t, logPath))
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "--filter label=com.docker.compose.project=demo")
+}
+
+func TestCleanupCronContainersAllStacks(t *testing.T) {
+	stubDockerCronContainers(t)
+
+	removed, err := CleanupCronContainers(context.Background(), "", 1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"demo-backup-cron-200", "demo-backup-cron-100", "other-backup-cron-100"}, removed)
+}
+
+func TestUpdateCleansUpStackCronContainers(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, "stacks", "demo", "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+
+	global := testGlobalConfig()
+	global.Cron = config.CronConfig{ContainerRetention: 2}
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	logPath := stubDockerCronContainers(t)
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Update: true}))
+
+	require.Equal(t, []string{"demo-backup-cron-100"}, removedContainers(t, logPath))
+}
@@ -0,0 +1,97 @@
+package stackcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	digestPattern    = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+	imageLinePattern = regexp.MustCompile(`(?m)^\s*image:\s*["']?([^"'\s#]+)["']?`)
+)
+
+// resolveTagDigest pins tag to the registry digest of the stack image that
+// references tagEnv, returning "<tag>@sha256:...".
+func (m *Manager) resolveTagDigest(ctx context.Context, composePaths []string, tagEnv, tag string) (string, error) {
+	if strings.Contains(tag, "@") {
+		_, digest, _ := strings.Cut(tag, "@")
+		if !digestPattern.MatchString(digest) {
+			return "", fmt.Errorf("invalid digest in tag %q", tag)
+		}
+		return tag, nil
+	}
+
+	image, err := findTaggedImage(composePaths, tagEnv, tag)
+	if err != nil {
+		return "", err
+	}
+
+	// imagetools reports the top-level descriptor, which for a multi-platform
+	// image is the manifest list, so the pin works on every platform.
+	out, err := exec.CommandContext(ctx, "docker", "buildx", "imagetools", "inspect", "--format", "{{json .Manifest}}", image).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect manifest for %s: %w", image, err)
+	}
+
+	digest, err := parseManifestDigest(out)
+	if err != nil {
+		return "", fmt.Errorf("image %s: %w", image, err)
+	}
+	return tag + "@" + digest, nil
+}
+
+// findTaggedImage returns the image reference whose tag comes from tagEnv,
+// with the variable substituted by tag.
+func findTaggedImage(composePaths []string, tagEnv, tag string) (string, error) {
+	varPattern := regexp.MustCompile(`\$\{` + regexp.QuoteMeta(tagEnv) + `(:?-[^}]*)?\}`)
+
+	var images []string
+	for _, p := range composePaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		for _, match := range imageLinePattern.FindAllStringSubmatch(string(data), -1) {
+			if !varPattern.MatchString(match[1]) {
+				continue
+			}
+			images = append(images, varPattern.ReplaceAllLiteralString(match[1], tag))
+		}
+	}
+
+	images = dedupePreserve(images)
+	switch len(images) {
+	case 0:
+		return "", fmt.Errorf("no image in compose files uses ${%s}", tagEnv)
+	case 1:
+		return images[0], nil
+	default:
+		return "", fmt.Errorf("multiple images use ${%s} (%s); cannot pin a single digest", tagEnv, strings.Join(images, ", "))
+	}
+}
+
+// parseManifestDigest extracts the digest from
+// "docker buildx imagetools inspect --format '{{json .Manifest}}'" output.
+// For a manifest list this is the digest of the list itself.
+func parseManifestDigest(out []byte) (string, error) {
+	var manifest struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal(out, &manifest); err != nil {
+		return "", fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	digest := manifest.Digest
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return digest, nil
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 
 	"github.com/jamestiberiuskirk/stackr/internal/config"
 )
@@ -22,9 +24,11 @@ const (
 
 // StackInfo contains information about a discovered stack
 type StackInfo struct {
-	Name         string    // Stack name
-	Type         StackType // Local or remote
-	ComposePaths []string  // Full paths to compose files (first is primary)
+	Name         string    `json:"name"`               // Stack name
+	Type         StackType `json:"type"`               // Local or remote
+	ComposePaths []string  `json:"compose_paths"`      // Full paths to compose files (first is primary)
+	ProjectDir   string    `json:"project_dir"`        // Directory passed to compose as --project-directory
+	Profiles     []string  `json:"profiles,omitempty"` // Profiles gating the stack in `all`; empty means always
 }
 
 // PrimaryComposePath returns the first (primary) compose file path.
@@ -35,6 +39,12 @@ func (s StackInfo) PrimaryComposePath() string {
 	return s.ComposePaths[0]
 }
 
+// EnabledFor reports whether the stack belongs in `all` with the given
+// profile active.
+func (s StackInfo) EnabledFor(profile string) bool {
+	return len(s.Profiles) == 0 || slices.Contains(s.Profiles, profile)
+}
+
 // DiscoverStacks scans the stacks directory and returns both local and remote stacks
 func DiscoverStacks(cfg config.Config) ([]StackInfo, error) {
 	entries, err := os.ReadDir(cfg.StacksDir)
@@ -83,7 +93,50 @@ func ResolveStackPath(cfg config.Config, stackName string) (StackInfo, error) {
 		return StackInfo{}, err
 	}
 	if info == nil {
-		return StackInfo{}, fmt.Errorf("stack %q has neither docker-compose.yml, stackr/config.yaml, nor stackr-repo.yml", stackName)
+		return StackInfo{}, &MissingComposeError{Stack: stackName, Dir: stackDir}
+	}
+	return *info, nil
+}
+
+// MissingComposeError is returned for a stack directory that has no compose
+// file stackr recognises and no remote stack config.
+type MissingComposeError struct {
+	Stack string
+	Dir   string
+}
+
+func (e *MissingComposeError) Error() string {
+	return fmt.Sprintf("stack %q has no compose file in %s (looked for %s); rename the compose file to one of these, "+
+		"list it under compose_files in stackr/config.yaml, or run \"stackr init\" to set up a new repo",
+		e.Stack, e.Dir, strings.Join(config.ComposeFileNames(), ", "))
+}
+
+// ResolveStackComposeFile resolves a stack like ResolveStackPath, but with
+// composeFile (relative to the stack's compose directory) in place of its
+// configured compose files.
+func ResolveStackComposeFile(cfg config.Config, stackName, composeFile string) (StackInfo, error) {
+	stackDir := filepath.Join(cfg.StacksDir, stackName)
+	if !dirExists(stackDir) {
+		return StackInfo{}, fmt.Errorf("stack %q does not exist", stackName)
+	}
+
+	localCfg, err := config.LoadStackLocalConfig(stackDir)
+	if err != nil {
+		return StackInfo{}, fmt.Errorf("failed to load config for stack %q: %w", stackName, err)
+	}
+	localCfg.ComposeFiles = []string{composeFile}
+
+	var info *StackInfo
+	if localCfg.IsRemote() {
+		info, err = resolveRemoteStack(cfg, stackName, localCfg)
+	} else {
+		info, err = resolveLocalStack(stackDir, stackName, localCfg)
+	}
+	if err != nil {
+		return StackInfo{}, err
+	}
+	if info == nil {
+		return StackInfo{}, fmt.Errorf("stack %q: compose file %s does not exist", stackName, filepath.Join(stackDir, composeFile))
 	}
 	return *info, nil
 }
@@ -119,6 +172,8 @@ func resolveLocalStack(stackDir, stackName string, localCfg *config.StackLocalCo
 		Name:         stackName,
 		Type:         StackTypeLocal,
 		ComposePaths: paths,
+		ProjectDir:   projectDir(paths[0], localCfg),
+		Profiles:     localCfg.Profiles,
 	}, nil
 }
 
@@ -142,9 +197,25 @@ func resolveRemoteStack(cfg config.Config, stackName string, localCfg *config.St
 		Name:         stackName,
 		Type:         StackTypeRemote,
 		ComposePaths: paths,
+		ProjectDir:   projectDir(paths[0], localCfg),
+		Profiles:     localCfg.Profiles,
 	}, nil
 }
 
+// projectDir returns the compose project directory for a stack: the
+// configured project_directory, or the primary compose file's directory.
+func projectDir(primaryCompose string, localCfg *config.StackLocalConfig) string {
+	baseDir := filepath.Dir(primaryCompose)
+	dir := localCfg.ProjectDirectory
+	if dir == "" {
+		return baseDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(baseDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
 // fileExists checks if a file exists
 func fileExists(path string) bool {
 	info, err := os.Stat(path)
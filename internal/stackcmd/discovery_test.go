@@ -231,7 +231,7 @@ func TestResolveStackPath_NoComposeOrDef(t *testing.T) {
 
 	_, err := ResolveStackPath(cfg, "empty")
 	require.Error(t, err)
-	require.Contains(t, err.Error(), "has neither docker-compose.yml")
+	require.Contains(t, err.Error(), "has no compose file")
 }
 
 func TestResolveStackPath_LegacyWithLocalCompose(t *testing.T) {
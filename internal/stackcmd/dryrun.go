@@ -0,0 +1,32 @@
+package stackcmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// printDryRunCmd prints the command a dry run skips, as a shell command line
+// with secrets from env masked.
+func (m *Manager) printDryRunCmd(env []string, args []string) {
+	_, _ = fmt.Fprintf(m.stdout, "[DRY RUN] Would run: %s\n", m.maskText(shellJoin(args), env))
+}
+
+// composeCmd returns the full docker compose command line for stackInfo.
+func composeCmd(stackInfo StackInfo, args ...string) []string {
+	cmd := append([]string{"docker"}, composeFileArgs(stackInfo)...)
+	return append(cmd, args...)
+}
+
+// shellJoin joins args into a command line, single-quoting the ones the
+// shell would split or expand.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}#~!") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,69 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestDryRunPrintsCommands(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "API_TOKEN=s3cr3t-value\n")
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), "services:\n  web:\n    image: nginx\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+	composeArgs := "docker compose --project-directory " + filepath.Join(root, "stacks/demo") +
+		" -p demo -f " + filepath.Join(root, "stacks/demo/docker-compose.yml")
+
+	for _, tt := range []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "tear-down", opts: Options{TearDown: true}, want: composeArgs + " down"},
+		{name: "pause", opts: Options{Pause: true}, want: composeArgs + " pause"},
+		{name: "pull", opts: Options{Pull: true}, want: composeArgs + " pull"},
+		{name: "compose", opts: Options{VarsOnly: true, Compose: true, VarsCommand: []string{"up", "-d"}}, want: composeArgs + " up -d"},
+		{
+			name: "vars-only",
+			opts: Options{VarsOnly: true, VarsCommand: []string{"sh", "-c", "echo $API_TOKEN it's s3cr3t-value"}},
+			want: `sh -c 'echo $API_TOKEN it'\''s ***'`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout.Reset()
+			opts := tt.opts
+			opts.Stacks = []string{"demo"}
+			opts.DryRun = true
+			require.NoError(t, manager.Run(context.Background(), opts))
+			require.Contains(t, stdout.String(), "[DRY RUN] Would run: "+tt.want+"\n")
+		})
+	}
+
+	_, err = os.Stat(logPath)
+	require.ErrorIs(t, err, os.ErrNotExist, "dry run must not call docker")
+}
+
+func TestShellJoin(t *testing.T) {
+	require.Equal(t, "docker compose down", shellJoin([]string{"docker", "compose", "down"}))
+	require.Equal(t, `sh -c 'a b' '' 'it'\''s'`, shellJoin([]string{"sh", "-c", "a b", "", "it's"}))
+}
@@ -0,0 +1,70 @@
+package stackcmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StackError is the failure of one stack in a run over several stacks.
+type StackError struct {
+	Stack string
+	Err   error
+}
+
+func (e *StackError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stack, e.Err)
+}
+
+func (e *StackError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError collects the stacks that failed in a run that kept going past
+// failures (--parallel or --continue-on-error). Each failure is a
+// *StackError, reachable with errors.As.
+type MultiError struct {
+	// Total is how many stacks the run covered.
+	Total  int
+	Errors []*StackError
+}
+
+// Add records err for stack; a nil err is ignored.
+func (e *MultiError) Add(stack string, err error) {
+	if err != nil {
+		e.Errors = append(e.Errors, &StackError{Stack: stack, Err: err})
+	}
+}
+
+// Stacks returns the names of the failed stacks, in run order.
+func (e *MultiError) Stacks() []string {
+	names := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		names[i] = err.Stack
+	}
+	return names
+}
+
+func (e *MultiError) Error() string {
+	details := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		details[i] = "  " + err.Error()
+	}
+	return fmt.Sprintf("%d of %d stack(s) failed: %s\n%s", len(e.Errors), e.Total, strings.Join(e.Stacks(), ", "), strings.Join(details, "\n"))
+}
+
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// errOrNil returns e, or nil when no stack failed, so a MultiError without
+// failures never reaches callers as a non-nil error.
+func (e *MultiError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
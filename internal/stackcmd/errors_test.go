@@ -0,0 +1,76 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestContinueOnErrorReturnsMultiError(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"api", "web", "worker"} {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	}
+	writeFile(t, filepath.Join(root, "stacks", "api", "docker-compose.yml"), "services:\n  app:\n    image: nginx:${API_TAG}\n")
+	writeFile(t, filepath.Join(root, "stacks", "worker", "docker-compose.yml"), "services:\n  app:\n    image: nginx:${WORKER_TAG}\n")
+
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "docker"), "#!/bin/sh\necho \"docker $*\"\n")
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	run := func(opts Options) (string, error) {
+		t.Helper()
+		var stdout bytes.Buffer
+		manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+		require.NoError(t, err)
+		opts.All = true
+		return stdout.String(), manager.Run(context.Background(), opts)
+	}
+
+	out, err := run(Options{})
+	require.ErrorContains(t, err, "API_TAG")
+	var multi *MultiError
+	require.False(t, errors.As(err, &multi), "without --continue-on-error the first failure is returned as is")
+	require.NotContains(t, out, "Stack: web")
+
+	for _, opts := range []Options{{ContinueOnError: true}, {Parallel: 3}} {
+		_, err = run(opts)
+		require.ErrorAs(t, err, &multi)
+		require.Equal(t, 3, multi.Total)
+		require.Equal(t, []string{"api", "worker"}, multi.Stacks())
+		require.Contains(t, err.Error(), "2 of 3 stack(s) failed: api, worker")
+
+		for i, inner := range multi.Unwrap() {
+			var stackErr *StackError
+			require.ErrorAs(t, inner, &stackErr)
+			require.Equal(t, multi.Stacks()[i], stackErr.Stack)
+		}
+		require.ErrorContains(t, multi.Errors[0].Err, "API_TAG")
+		require.ErrorContains(t, multi.Errors[1].Err, "WORKER_TAG")
+
+		var first *StackError
+		require.ErrorAs(t, err, &first)
+		require.Equal(t, "api", first.Stack)
+	}
+
+	writeFile(t, filepath.Join(root, ".env"), "API_TAG=1\nWORKER_TAG=1\n")
+	_, err = run(Options{ContinueOnError: true})
+	require.NoError(t, err, "a run without failures returns a nil error, not an empty MultiError")
+}
@@ -0,0 +1,51 @@
+package stackcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// stackEvents streams "docker events" for the stack's compose project until
+// ctx is cancelled (Ctrl-C), which is not reported as an error.
+func (m *Manager) stackEvents(ctx context.Context, env []string, stackInfo StackInfo, stack string, opts Options) error {
+	project, err := m.composeProjectName(ctx, env, stackInfo, stack)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"events", "--filter", "label=com.docker.compose.project=" + project}
+	debugf(opts.Debug, "%s: streaming docker events for project %s", stack, project)
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Dir = m.cfg.RepoRoot
+	cmd.Env = env
+	cmd.Stdout = m.stdout
+	cmd.Stderr = m.stderr
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("docker events failed: %w", err)
+	}
+	return nil
+}
+
+// composeProjectName returns the project name compose labels the stack's
+// containers with, falling back to the stack name.
+func (m *Manager) composeProjectName(ctx context.Context, env []string, stackInfo StackInfo, stack string) (string, error) {
+	configJSON, err := m.composeOutput(ctx, env, stackInfo, "config", "--format", "json")
+	if err != nil {
+		return "", err
+	}
+	if configJSON == "" {
+		return stack, nil
+	}
+	var cfg struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return "", fmt.Errorf("stack %s: failed to parse compose config: %w", stack, err)
+	}
+	if cfg.Name == "" {
+		return stack, nil
+	}
+	return cfg.Name, nil
+}
@@ -0,0 +1,66 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerEventsFiltersByProject(t *testing.T) {
+	cfg := setupTopStack(t)
+
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+  *"config --format json"*) echo '{"name":"demo-prod","services":{}}' ;;
+  events*) echo 'container die abc123 (com.docker.compose.project=demo-prod)' ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Events: true}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(logData)), "\n")
+	require.Len(t, calls, 2)
+	require.Equal(t, "events --filter label=com.docker.compose.project=demo-prod", calls[1])
+	require.Contains(t, stdout.String(), "container die abc123")
+}
+
+func TestManagerEventsCancelIsNotAnError(t *testing.T) {
+	cfg := setupTopStack(t)
+
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in
+  events*) exec sleep 5 ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, manager.Run(ctx, Options{Stacks: []string{"demo"}, Events: true}))
+	require.Less(t, time.Since(start), 4*time.Second)
+}
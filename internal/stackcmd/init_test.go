@@ -0,0 +1,22 @@
+package stackcmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestInitTemplateRetentionIsHonored(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "stacks"), 0o755))
+	writeFile(t, filepath.Join(repo, ".stackr.yaml"), stackrConfigTemplate)
+
+	cfg, err := config.LoadForCLI(repo)
+	require.NoError(t, err)
+	// The scheduler reads retention straight from this field
+	require.Equal(t, 10, cfg.Global.Cron.ContainerRetention)
+}
@@ -0,0 +1,25 @@
+package stackcmd
+
+import (
+	"context"
+)
+
+// stackLogs streams "docker compose logs" for the stack, limited to
+// opts.LogServices when set. Cancelling ctx (Ctrl-C while following) is a
+// normal way to stop and not reported as an error.
+func (m *Manager) stackLogs(ctx context.Context, env []string, stackInfo StackInfo, stack string, opts Options) error {
+	args := []string{"logs"}
+	if opts.LogsFollow {
+		args = append(args, "--follow")
+	}
+	if opts.LogsTail != "" {
+		args = append(args, "--tail", opts.LogsTail)
+	}
+	args = append(args, opts.LogServices...)
+
+	debugf(opts.Debug, "%s: streaming logs", stack)
+	if err := m.runComposeCmd(ctx, env, stackInfo, args...); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
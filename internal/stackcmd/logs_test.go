@@ -0,0 +1,56 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerLogsStreamsComposeLogs(t *testing.T) {
+	cfg := setupTopStack(t)
+
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	writeFile(t, filepath.Join(binDir, "docker"), "#!/bin/sh\necho \"$@\" >> \""+logPath+"\"\necho 'web-1  | ready'\n")
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	opts := Options{Stacks: []string{"demo"}, Logs: true, LogsFollow: true, LogsTail: "50", LogServices: []string{"web"}}
+	require.NoError(t, manager.Run(context.Background(), opts))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(logData)), "\n")
+	require.Len(t, calls, 1)
+	require.True(t, strings.HasSuffix(calls[0], "-f "+filepath.Join(cfg.StacksDir, "demo/docker-compose.yml")+" logs --follow --tail 50 web"), calls[0])
+	require.Contains(t, stdout.String(), "web-1  | ready")
+}
+
+func TestManagerLogsCancelIsNotAnError(t *testing.T) {
+	cfg := setupTopStack(t)
+
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "docker"), "#!/bin/sh\nexec sleep 5\n")
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, manager.Run(ctx, Options{Stacks: []string{"demo"}, Logs: true, LogsFollow: true}))
+	require.Less(t, time.Since(start), 4*time.Second)
+}
@@ -0,0 +1,72 @@
+package stackcmd
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maskedValue replaces secret values in displayed output.
+const maskedValue = "***"
+
+// defaultMaskPatterns match env keys whose values are always masked;
+// env.mask adds more.
+var defaultMaskPatterns = []string{"*_TOKEN", "*_PASSWORD", "*_SECRET"}
+
+// isSecretKey reports whether key matches a mask pattern. Matching ignores
+// case.
+func (m *Manager) isSecretKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, pattern := range slices.Concat(defaultMaskPatterns, m.cfg.Global.Env.Mask) {
+		if ok, _ := filepath.Match(strings.ToUpper(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSecrets returns a copy of values with secret values replaced by ***,
+// for display only.
+func (m *Manager) maskSecrets(values map[string]string) map[string]string {
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		if v != "" && m.isSecretKey(k) {
+			v = maskedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// maskText replaces every secret value of env (KEY=value entries) found in
+// text, such as docker compose config output, with ***.
+func (m *Manager) maskText(text string, env []string) string {
+	var secrets []string
+	for _, kv := range env {
+		key, value, ok := strings.Cut(kv, "=")
+		if ok && value != "" && m.isSecretKey(key) {
+			secrets = append(secrets, value)
+		}
+	}
+	// Longest first so a secret containing another is masked whole
+	slices.SortFunc(secrets, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, maskedValue)
+	}
+	return text
+}
+
+// formatEnv renders values as sorted KEY=value lines for debug output.
+func formatEnv(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, "  "+k+"="+values[k])
+	}
+	return strings.Join(lines, "\n")
+}
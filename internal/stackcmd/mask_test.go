@@ -0,0 +1,86 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	global := testGlobalConfig()
+	global.Env.Mask = []string{"DATABASE_URL", "*_dsn"}
+	manager := &Manager{cfg: config.Config{Global: global}}
+
+	values := map[string]string{
+		"API_TOKEN":      "tok",
+		"DB_PASSWORD":    "hunter2",
+		"signing_secret": "s3",
+		"DATABASE_URL":   "postgres://u:p@db/app",
+		"SENTRY_DSN":     "https://key@sentry",
+		"EMPTY_TOKEN":    "",
+		"APP_IMAGE_TAG":  "v1.2.3",
+		"TOKEN_TTL":      "3600",
+	}
+	require.Equal(t, map[string]string{
+		"API_TOKEN":      "***",
+		"DB_PASSWORD":    "***",
+		"signing_secret": "***",
+		"DATABASE_URL":   "***",
+		"SENTRY_DSN":     "***",
+		"EMPTY_TOKEN":    "",
+		"APP_IMAGE_TAG":  "v1.2.3",
+		"TOKEN_TTL":      "3600",
+	}, manager.maskSecrets(values))
+	require.Equal(t, "hunter2", values["DB_PASSWORD"], "the input map must not be modified")
+
+	env := []string{"DB_PASSWORD=hunter2", "ADMIN_PASSWORD=hunter22", "APP_IMAGE_TAG=v1.2.3"}
+	require.Equal(t, "db: ***, admin: ***, tag: v1.2.3",
+		manager.maskText("db: hunter2, admin: hunter22, tag: v1.2.3", env))
+}
+
+func TestDryRunMasksSecrets(t *testing.T) {
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "docker"), `#!/bin/sh
+echo "password: $DB_PASSWORD"
+echo "tag: $APP_IMAGE_TAG"
+`)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, ".env"), "DB_PASSWORD=hunter2\nAPP_IMAGE_TAG=v1.2.3\n")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), `
+services:
+  app:
+    image: app:${APP_IMAGE_TAG}
+    environment:
+      DB_PASSWORD: ${DB_PASSWORD}
+`)
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, DryRun: true}))
+	require.Contains(t, stdout.String(), "password: ***")
+	require.Contains(t, stdout.String(), "tag: v1.2.3")
+	require.NotContains(t, stdout.String(), "hunter2")
+
+	// The child process still gets the real value
+	stdout.Reset()
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, VarsOnly: true, VarsCommand: []string{"sh", "-c", "echo $DB_PASSWORD"}}))
+	require.Contains(t, stdout.String(), "hunter2")
+}
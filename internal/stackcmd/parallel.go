@@ -0,0 +1,53 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// runParallel runs up to opts.Parallel stacks at a time. Each stack's output
+// is buffered and written out in one piece once it finishes, so stacks don't
+// interleave. A failing stack doesn't stop the others; failures are reported
+// together at the end as a *MultiError.
+func (m *Manager) runParallel(ctx context.Context, stacks []string, opts Options) error {
+	var (
+		wg     sync.WaitGroup
+		outMu  sync.Mutex
+		failed = make([]error, len(stacks))
+		sem    = make(chan struct{}, opts.Parallel)
+	)
+
+	for i, stack := range stacks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var stdout, stderr bytes.Buffer
+			worker := *m
+			worker.stdout = &stdout
+			worker.stderr = &stderr
+
+			// Keep stdout parseable when printing JSON
+			if !opts.JSON {
+				_, _ = fmt.Fprintf(&stdout, "Stack: %s\n", stack)
+			}
+			failed[i] = worker.runStack(ctx, stack, opts)
+
+			outMu.Lock()
+			defer outMu.Unlock()
+			_, _ = m.stdout.Write(stdout.Bytes())
+			_, _ = m.stderr.Write(stderr.Bytes())
+		}()
+	}
+	wg.Wait()
+
+	multi := &MultiError{Total: len(stacks)}
+	for i, err := range failed {
+		multi.Add(stacks[i], err)
+	}
+	return multi.errOrNil()
+}
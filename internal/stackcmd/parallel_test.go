@@ -0,0 +1,121 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestRunParallelGroupsOutputAndReportsFailures(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"api", "web", "worker"} {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	}
+	// Two stacks missing vars, so both append to .env concurrently
+	writeFile(t, filepath.Join(root, "stacks", "api", "docker-compose.yml"), "services:\n  app:\n    image: nginx:${API_TAG}\n")
+	writeFile(t, filepath.Join(root, "stacks", "worker", "docker-compose.yml"), "services:\n  app:\n    image: nginx:${WORKER_TAG}\n")
+
+	binDir := t.TempDir()
+	script := filepath.Join(binDir, "docker")
+	writeFile(t, script, "#!/bin/sh\nsleep 0.05\necho \"docker $*\"\n")
+	require.NoError(t, os.Chmod(script, 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	var stdout, stderr bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stderr)
+	require.NoError(t, err)
+
+	err = manager.Run(context.Background(), Options{All: true, Parallel: 3})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "2 of 3 stack(s) failed: api, worker")
+	require.Contains(t, err.Error(), "API_TAG")
+	require.Contains(t, err.Error(), "WORKER_TAG")
+
+	// The healthy stack still ran, and its output is not interleaved
+	sections := strings.Split(stdout.String(), "Stack: ")[1:]
+	require.Len(t, sections, 3)
+	for _, section := range sections {
+		stack := strings.SplitN(section, "\n", 2)[0]
+		for _, other := range []string{"api", "web", "worker"} {
+			if other != stack {
+				require.NotContains(t, section, filepath.Join(root, "stacks", other), "output of %s leaked into %s", other, stack)
+			}
+		}
+	}
+	require.Contains(t, stdout.String(), filepath.Join(root, "stacks", "web"))
+
+	envData, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	require.Contains(t, string(envData), "API_TAG=")
+	require.Contains(t, string(envData), "WORKER_TAG=")
+}
+
+func TestParallelPullRunsStacksConcurrently(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	stacks := []string{"api", "web", "worker"}
+	for _, stack := range stacks {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services:\n  app:\n    image: app\n")
+	}
+
+	// Each pull waits for the others to start, and records how many it saw
+	binDir := t.TempDir()
+	pullDir := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in
+  *"config --images") echo app ;;
+  *" pull")
+    touch "` + pullDir + `/pulling-$$"
+    i=0
+    while [ "$(ls "` + pullDir + `" | grep -c pulling-)" -lt 3 ] && [ $i -lt 250 ]; do
+      sleep 0.02
+      i=$((i+1))
+    done
+    ls "` + pullDir + `" | grep -c pulling- > "` + pullDir + `/seen-$$" ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{All: true, Pull: true, Parallel: 3}))
+	for _, stack := range stacks {
+		require.Contains(t, stdout.String(), stack+": pulled images\n")
+	}
+
+	seen, err := filepath.Glob(filepath.Join(pullDir, "seen-*"))
+	require.NoError(t, err)
+	require.Len(t, seen, 3)
+	for _, path := range seen {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "3", strings.TrimSpace(string(data)), "pulls did not overlap")
+	}
+}
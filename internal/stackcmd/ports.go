@@ -0,0 +1,196 @@
+package stackcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// publishedPort is a host port a stack's services publish.
+type publishedPort struct {
+	HostIP   string
+	Port     int
+	Protocol string
+	Service  string
+}
+
+func (p publishedPort) key() string {
+	return fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+}
+
+// composePort is a port entry of "docker compose config --format json".
+// Older compose versions emit published as a number, newer ones as a string.
+type composePort struct {
+	Published json.RawMessage `json:"published"`
+	Protocol  string          `json:"protocol"`
+	HostIP    string          `json:"host_ip"`
+}
+
+// parsePublishedPorts returns the project name and the host ports published
+// by the services of a normalized compose config.
+func parsePublishedPorts(configJSON []byte) (string, []publishedPort, error) {
+	var cfg struct {
+		Name     string `json:"name"`
+		Services map[string]struct {
+			Ports []composePort `json:"ports"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal(configJSON, &cfg); err != nil {
+		return "", nil, fmt.Errorf("failed to parse compose config: %w", err)
+	}
+
+	var ports []publishedPort
+	for name, svc := range cfg.Services {
+		for _, p := range svc.Ports {
+			published := strings.Trim(string(p.Published), `"`)
+			if published == "" || published == "null" {
+				// Container-only port, nothing bound on the host
+				continue
+			}
+			first, last, err := parsePortRange(published)
+			if err != nil {
+				return "", nil, fmt.Errorf("service %s: %w", name, err)
+			}
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			for port := first; port <= last; port++ {
+				ports = append(ports, publishedPort{HostIP: p.HostIP, Port: port, Protocol: protocol, Service: name})
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Service < ports[j].Service
+	})
+	return cfg.Name, ports, nil
+}
+
+func parsePortRange(value string) (int, int, error) {
+	start, end, isRange := strings.Cut(value, "-")
+	first, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid published port %q", value)
+	}
+	last := first
+	if isRange {
+		if last, err = strconv.Atoi(end); err != nil || last < first {
+			return 0, 0, fmt.Errorf("invalid published port %q", value)
+		}
+	}
+	return first, last, nil
+}
+
+// parseDockerPsPorts maps "<port>/<proto>" to the compose project owning it,
+// from "docker ps --format '{{.Label "com.docker.compose.project"}}\t{{.Ports}}'".
+func parseDockerPsPorts(out string) map[string]string {
+	owners := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		project, ports, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok || project == "" {
+			continue
+		}
+		// e.g. "0.0.0.0:8080->80/tcp, :::8080->80/tcp, 9000/tcp"
+		for _, mapping := range strings.Split(ports, ",") {
+			host, container, ok := strings.Cut(strings.TrimSpace(mapping), "->")
+			if !ok {
+				continue
+			}
+			_, protocol, _ := strings.Cut(container, "/")
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			first, last, err := parsePortRange(host[strings.LastIndex(host, ":")+1:])
+			if err != nil {
+				continue
+			}
+			for port := first; port <= last; port++ {
+				owners[fmt.Sprintf("%d/%s", port, protocol)] = project
+			}
+		}
+	}
+	return owners
+}
+
+// checkPortConflicts fails if a host port the stack publishes is already
+// bound, naming the owning stack when a compose project holds it. Ports held
+// by the stack's own containers are fine, as up -d recreates them in place.
+func (m *Manager) checkPortConflicts(ctx context.Context, env []string, stackInfo StackInfo, stack string) error {
+	configJSON, err := m.composeOutput(ctx, env, stackInfo, "config", "--format", "json")
+	if err != nil {
+		return err
+	}
+	project, ports, err := parsePublishedPorts([]byte(configJSON))
+	if err != nil {
+		return fmt.Errorf("stack %s: %w", stack, err)
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	if project == "" {
+		project = stack
+	}
+
+	psCmd := exec.CommandContext(ctx, "docker", "ps", "--format", `{{.Label "com.docker.compose.project"}}`+"\t{{.Ports}}")
+	psCmd.Env = env
+	psOut, err := psCmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to list running containers: %w", err)
+	}
+	owners := parseDockerPsPorts(string(psOut))
+
+	var conflicts []string
+	for _, p := range ports {
+		owner, held := owners[p.key()]
+		switch {
+		case held && owner == project:
+			continue
+		case held:
+			conflicts = append(conflicts, fmt.Sprintf("%s (service %s) is already published by stack %s", p.key(), p.Service, owner))
+		case !portFree(p):
+			conflicts = append(conflicts, fmt.Sprintf("%s (service %s) is already bound by another process", p.key(), p.Service))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("stack %s: host port conflict(s):\n  %s", stack, strings.Join(conflicts, "\n  "))
+	}
+	return nil
+}
+
+// listenTCP and listenUDP bind the probes of portFree; tests replace them.
+var (
+	listenTCP = net.Listen
+	listenUDP = net.ListenPacket
+)
+
+// portFree reports whether the port can be bound on the host. Only "address
+// already in use" counts as taken: other bind errors, such as EACCES for a
+// privileged port when stackr runs unprivileged, say nothing about docker's
+// ability to publish it.
+func portFree(p publishedPort) bool {
+	addr := net.JoinHostPort(p.HostIP, strconv.Itoa(p.Port))
+	if p.Protocol == "udp" {
+		conn, err := listenUDP("udp", addr)
+		if err != nil {
+			return !errors.Is(err, syscall.EADDRINUSE)
+		}
+		_ = conn.Close()
+		return true
+	}
+	ln, err := listenTCP("tcp", addr)
+	if err != nil {
+		return !errors.Is(err, syscall.EADDRINUSE)
+	}
+	_ = ln.Close()
+	return true
+}
@@ -0,0 +1,148 @@
+package stackcmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+// stubDockerPorts installs a docker stub answering "compose config" with
+// configJSON and "docker ps" with psOut, logging every call.
+func stubDockerPorts(t *testing.T, configJSON, psOut string) string {
+	t.Helper()
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	writeFile(t, filepath.Join(binDir, "config.json"), configJSON)
+	writeFile(t, filepath.Join(binDir, "ps.txt"), psOut)
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+  *"config --format json"*) cat "` + filepath.Join(binDir, "config.json") + `" ;;
+  "ps --format"*) cat "` + filepath.Join(binDir, "ps.txt") + `" ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func setupPortStacks(t *testing.T) config.Config {
+	t.Helper()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"api", "web"} {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), `
+services:
+  app:
+    image: nginx
+    ports:
+      - "8080:80"
+`)
+	}
+	return config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+}
+
+const webPortsConfig = `{"name":"web","services":{"app":{"ports":[{"mode":"ingress","target":80,"published":"8080","protocol":"tcp"}]}}}`
+
+func TestCheckPortsReportsConflictWithOtherStack(t *testing.T) {
+	cfg := setupPortStacks(t)
+	// api is already running and publishes 8080
+	logPath := stubDockerPorts(t, webPortsConfig, "api\t0.0.0.0:8080->80/tcp, :::8080->80/tcp\n")
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	err = manager.Run(context.Background(), Options{Stacks: []string{"web"}, CheckPorts: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "8080/tcp (service app) is already published by stack api")
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.NotContains(t, string(logData), "up -d", "stack must not be brought up on conflict")
+	require.NotContains(t, string(logData), " down", "running stack must not be torn down on conflict")
+}
+
+func TestCheckPortsAllowsPortsHeldByOwnStack(t *testing.T) {
+	cfg := setupPortStacks(t)
+	logPath := stubDockerPorts(t, webPortsConfig, "web\t0.0.0.0:8080->80/tcp\n")
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"web"}, CheckPorts: true}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(logData), "up -d")
+}
+
+func TestCheckPortsReportsPortBoundByProcess(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	cfg := setupPortStacks(t)
+	configJSON := fmt.Sprintf(`{"name":"web","services":{"app":{"ports":[{"target":80,"published":%d,"host_ip":"127.0.0.1"}]}}}`, port)
+	stubDockerPorts(t, configJSON, "")
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	err = manager.Run(context.Background(), Options{Stacks: []string{"web"}, CheckPorts: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("%d/tcp (service app) is already bound by another process", port))
+}
+
+func TestCheckPortsIgnoresPermissionErrors(t *testing.T) {
+	// Ports below 1024 fail to bind with EACCES when stackr runs unprivileged
+	prevListen := listenTCP
+	listenTCP = func(network, address string) (net.Listener, error) {
+		return nil, &net.OpError{Op: "listen", Net: network, Err: os.NewSyscallError("bind", syscall.EACCES)}
+	}
+	t.Cleanup(func() { listenTCP = prevListen })
+
+	cfg := setupPortStacks(t)
+	logPath := stubDockerPorts(t, `{"name":"web","services":{"app":{"ports":[{"target":80,"published":"80"}]}}}`, "")
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"web"}, CheckPorts: true}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(logData), "up -d")
+}
+
+func TestParseDockerPsPorts(t *testing.T) {
+	owners := parseDockerPsPorts(strings.Join([]string{
+		"api\t0.0.0.0:8080->80/tcp, :::8080->80/tcp",
+		"dns\t0.0.0.0:53->53/udp",
+		"range\t0.0.0.0:9000-9001->9000-9001/tcp",
+		"\t0.0.0.0:7000->7000/tcp",
+		"internal\t5432/tcp",
+	}, "\n"))
+	require.Equal(t, map[string]string{
+		"8080/tcp": "api",
+		"53/udp":   "dns",
+		"9000/tcp": "range",
+		"9001/tcp": "range",
+	}, owners)
+}
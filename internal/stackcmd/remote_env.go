@@ -0,0 +1,120 @@
+package stackcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// appliedEnvPath returns where the merged env of the last successful deploy of
+// a remote stack is recorded. It lives next to the clone so "remote clean"
+// does not forget what is running.
+func (m *Manager) appliedEnvPath(stack string) string {
+	remoteRepoDir := m.cfg.Global.RemoteStacksDir
+	if !filepath.IsAbs(remoteRepoDir) {
+		remoteRepoDir = filepath.Join(m.cfg.RepoRoot, remoteRepoDir)
+	}
+	return filepath.Join(remoteRepoDir, "."+stack+".applied-env.json")
+}
+
+// loadAppliedEnv returns the recorded env, or ok=false if the stack has never
+// been deployed by stackr.
+func (m *Manager) loadAppliedEnv(stack string) (env map[string]string, ok bool, err error) {
+	data, err := os.ReadFile(m.appliedEnvPath(stack))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", m.appliedEnvPath(stack), err)
+	}
+	return env, true, nil
+}
+
+// saveAppliedEnv records env, secrets included, so the file is only readable
+// by its owner.
+func (m *Manager) saveAppliedEnv(stack string, env map[string]string) error {
+	path := m.appliedEnvPath(stack)
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0o600)
+}
+
+// checkRemoteEnvChanges compares the merged env about to be deployed with the
+// one recorded at the last deploy. Any difference is printed and, unless
+// accepted, blocks the deploy. Accepted changes are also logged so unattended
+// deploys leave a record of them.
+func (m *Manager) checkRemoteEnvChanges(stack string, env map[string]string, opts Options) error {
+	applied, ok, err := m.loadAppliedEnv(stack)
+	if err != nil {
+		return fmt.Errorf("stack %s: %w", stack, err)
+	}
+	if !ok {
+		return nil
+	}
+
+	diff := diffEnv(applied, env, m.isSecretKey)
+	if len(diff) == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(m.stdout, "%s: merged env differs from the last deploy:\n", stack)
+	for _, line := range diff {
+		_, _ = fmt.Fprintf(m.stdout, "  %s\n", line)
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+	if opts.AcceptEnvChanges {
+		log.Printf("%s: deploying with changed env: %s", stack, strings.Join(diff, ", "))
+		return nil
+	}
+	return fmt.Errorf("stack %s: remote deployment config changed the env (%d change(s)); re-run with --accept-env-changes to deploy", stack, len(diff))
+}
+
+// diffEnv returns one sorted line per added (+), removed (-) or changed (~) key.
+// Values are compared in full but shown as *** for keys secret reports.
+func diffEnv(old, updated map[string]string, secret func(key string) bool) []string {
+	show := func(k, v string) string {
+		if v != "" && secret(k) {
+			return maskedValue
+		}
+		return v
+	}
+	var lines []string
+	for k, v := range updated {
+		prev, ok := old[k]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("+ %s=%s", k, show(k, v)))
+		case prev != v:
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", k, show(k, prev), show(k, v)))
+		}
+	}
+	for k := range old {
+		if _, ok := updated[k]; !ok {
+			lines = append(lines, fmt.Sprintf("- %s", k))
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return strings.TrimLeft(lines[i], "+-~ ") < strings.TrimLeft(lines[j], "+-~ ")
+	})
+	return lines
+}
@@ -0,0 +1,130 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestRemoteEnvChangeBlocksDeployWithoutAccept(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/remote-app")
+	// Unreachable URL: sync fails and the cached clone below is used
+	writeFile(t, filepath.Join(root, "stacks/remote-app/stackr-repo.yml"), `
+remote_repo:
+  url: `+filepath.Join(root, "missing-repo")+`
+  branch: main
+  release:
+    type: commit
+    ref: HEAD
+`)
+	repoDir := filepath.Join(root, ".stackr-repos", "remote-app")
+	makeDirs(t, root, ".stackr-repos/remote-app")
+	writeFile(t, filepath.Join(repoDir, "docker-compose.yml"), `
+services:
+  app:
+    image: nginx
+`)
+	writeDeployConfig := func(logLevel string) {
+		writeFile(t, filepath.Join(repoDir, ".stackr-deployment.yaml"), "env:\n  LOG_LEVEL: "+logLevel+"\n")
+	}
+	writeDeployConfig("info")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	cfg.Global.RemoteStacksDir = ".stackr-repos"
+
+	_, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	// First deploy records the applied env
+	opts := Options{Stacks: []string{"remote-app"}}
+	require.NoError(t, manager.Run(context.Background(), opts))
+	applied, ok, err := manager.loadAppliedEnv("remote-app")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "info", applied["LOG_LEVEL"])
+
+	// Unchanged config deploys again without the flag
+	require.NoError(t, manager.Run(context.Background(), opts))
+
+	// Changed deployment config blocks the deploy
+	writeDeployConfig("debug")
+	err = manager.Run(context.Background(), opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--accept-env-changes")
+
+	applied, _, err = manager.loadAppliedEnv("remote-app")
+	require.NoError(t, err)
+	require.Equal(t, "info", applied["LOG_LEVEL"])
+
+	// Accepting the change deploys, logs the diff and records the new env
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	opts.AcceptEnvChanges = true
+	require.NoError(t, manager.Run(context.Background(), opts))
+	require.Contains(t, logs.String(), "remote-app: deploying with changed env: ~ LOG_LEVEL: info -> debug")
+	applied, _, err = manager.loadAppliedEnv("remote-app")
+	require.NoError(t, err)
+	require.Equal(t, "debug", applied["LOG_LEVEL"])
+}
+
+func TestDiffEnv(t *testing.T) {
+	diff := diffEnv(
+		map[string]string{"A": "1", "B": "2", "C": "3"},
+		map[string]string{"A": "1", "B": "20", "D": "4"},
+		func(string) bool { return false },
+	)
+	require.Equal(t, []string{"~ B: 2 -> 20", "- C", "+ D=4"}, diff)
+}
+
+func TestDiffEnvMasksSecrets(t *testing.T) {
+	manager := &Manager{cfg: config.Config{Global: testGlobalConfig()}}
+	manager.cfg.Global.Env.Mask = []string{"DB_URL"}
+	diff := diffEnv(
+		map[string]string{"API_TOKEN": "old-token", "DB_URL": "postgres://a"},
+		map[string]string{"API_TOKEN": "new-token", "DB_URL": "postgres://b", "NEW_SECRET": "s3cret"},
+		manager.isSecretKey,
+	)
+	require.Equal(t, []string{
+		"~ API_TOKEN: *** -> ***",
+		"~ DB_URL: *** -> ***",
+		"+ NEW_SECRET=***",
+	}, diff)
+}
+
+func TestSaveAppliedEnvIsOwnerOnly(t *testing.T) {
+	root := t.TempDir()
+	cfg := config.Config{RepoRoot: root, Global: testGlobalConfig()}
+	cfg.Global.RemoteStacksDir = ".stackr-repos"
+	manager, err := NewManagerWithWriters(cfg, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	// A file written by an older version is tightened too
+	path := manager.appliedEnvPath("app")
+	makeDirs(t, root, ".stackr-repos")
+	writeFile(t, path, "{}")
+	require.NoError(t, os.Chmod(path, 0o644))
+
+	require.NoError(t, manager.saveAppliedEnv("app", map[string]string{"API_TOKEN": "secret"}))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
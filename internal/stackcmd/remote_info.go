@@ -2,6 +2,7 @@ package stackcmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,14 +15,14 @@ import (
 
 // RemoteStackStatus contains information about a remote stack's sync status
 type RemoteStackStatus struct {
-	Name           string
-	Type           StackType
-	IsCloned       bool
-	CurrentVersion string
-	ConfiguredRef  string
-	RepoPath       string
-	IsDirty        bool
-	Error          string
+	Name           string    `json:"name"`
+	Type           StackType `json:"type"`
+	IsCloned       bool      `json:"is_cloned"`
+	CurrentVersion string    `json:"current_version,omitempty"`
+	ConfiguredRef  string    `json:"configured_ref,omitempty"`
+	RepoPath       string    `json:"repo_path,omitempty"`
+	IsDirty        bool      `json:"is_dirty"`
+	Error          string    `json:"error,omitempty"`
 }
 
 // GetRemoteStackStatus returns the status of a remote stack
@@ -56,7 +57,11 @@ func GetRemoteStackStatus(cfg config.Config, stackName string) (*RemoteStackStat
 		return status, nil
 	}
 
-	status.ConfiguredRef = localCfg.RemoteRepo.Release.Ref
+	ref, err := localCfg.RemoteRepo.Release.RefFor(cfg.Profile)
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.ConfiguredRef = ref
 
 	// Check if repo is cloned
 	remoteRepoDir := cfg.Global.RemoteStacksDir
@@ -145,16 +150,28 @@ func FormatRemoteStackStatus(status *RemoteStackStatus, verbose bool) string {
 	return b.String()
 }
 
+var errNotCloned = errors.New("is not cloned")
+
 // CleanRemoteStack removes a cloned remote stack repository
 func CleanRemoteStack(cfg config.Config, stackName string) error {
-	// Get stack info
-	stackInfo, err := ResolveStackPath(cfg, stackName)
+	repoPath, err := PlanCleanRemoteStack(cfg, stackName)
 	if err != nil {
 		return err
 	}
 
-	if stackInfo.Type != StackTypeRemote {
-		return fmt.Errorf("stack %q is not a remote stack", stackName)
+	// Remove the directory
+	if err := os.RemoveAll(repoPath); err != nil {
+		return fmt.Errorf("failed to remove repository: %w", err)
+	}
+
+	return nil
+}
+
+// PlanCleanRemoteStack returns the clone CleanRemoteStack would remove,
+// without removing it.
+func PlanCleanRemoteStack(cfg config.Config, stackName string) (string, error) {
+	if err := requireRemoteStack(cfg, stackName); err != nil {
+		return "", err
 	}
 
 	// Determine repo path
@@ -167,28 +184,43 @@ func CleanRemoteStack(cfg config.Config, stackName string) error {
 
 	// Check if repo exists
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-		return fmt.Errorf("repository for stack %q is not cloned", stackName)
+		return "", fmt.Errorf("repository for stack %q %w", stackName, errNotCloned)
 	}
+	return repoPath, nil
+}
 
-	// Remove the directory
-	if err := os.RemoveAll(repoPath); err != nil {
-		return fmt.Errorf("failed to remove repository: %w", err)
+// PlanSyncRemoteStack describes the git steps SyncRemoteStack would take,
+// without running git or touching the filesystem.
+func PlanSyncRemoteStack(cfg config.Config, stackName string, envVars map[string]string, forceClone bool) ([]string, error) {
+	if err := requireRemoteStack(cfg, stackName); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return remote.NewManager(cfg).PlanRemoteStack(stackName, envVars, forceClone)
 }
 
-// SyncRemoteStack manually syncs a remote stack (pull latest changes and checkout configured version)
-func SyncRemoteStack(cfg config.Config, stackName string, envVars map[string]string) error {
-	// Get stack info
+func requireRemoteStack(cfg config.Config, stackName string) error {
 	stackInfo, err := ResolveStackPath(cfg, stackName)
 	if err != nil {
 		return err
 	}
-
 	if stackInfo.Type != StackTypeRemote {
 		return fmt.Errorf("stack %q is not a remote stack", stackName)
 	}
+	return nil
+}
+
+// SyncRemoteStack manually syncs a remote stack (pull latest changes and checkout configured version)
+// With forceClone the existing clone is removed first and the stack re-cloned fresh
+func SyncRemoteStack(cfg config.Config, stackName string, envVars map[string]string, forceClone bool) error {
+	if err := requireRemoteStack(cfg, stackName); err != nil {
+		return err
+	}
+
+	if forceClone {
+		if err := CleanRemoteStack(cfg, stackName); err != nil && !errors.Is(err, errNotCloned) {
+			return fmt.Errorf("failed to remove existing clone: %w", err)
+		}
+	}
 
 	// Use remote manager to sync
 	mgr := remote.NewManager(cfg)
@@ -2,6 +2,7 @@ package stackcmd
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -411,3 +412,148 @@ func initTestGitRepo(t *testing.T, path string) {
 	require.NoError(t, git.RunGitCommand(context.Background(), path, "commit", "-m", "Initial commit"))
 	require.NoError(t, git.RunGitCommand(context.Background(), path, "branch", "-M", "main"))
 }
+
+func TestRemoteStackStatusJSON(t *testing.T) {
+	status := &RemoteStackStatus{
+		Name:           "myapp",
+		Type:           StackTypeRemote,
+		IsCloned:       true,
+		CurrentVersion: "abc123",
+		ConfiguredRef:  "${APP_VERSION}",
+		RepoPath:       "/path/to/repo",
+		IsDirty:        true,
+	}
+
+	data, err := json.Marshal(status)
+	require.NoError(t, err)
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, "myapp", got["name"])
+	require.Equal(t, "remote", got["type"])
+	require.Equal(t, true, got["is_cloned"])
+	require.Equal(t, "abc123", got["current_version"])
+	require.Equal(t, "${APP_VERSION}", got["configured_ref"])
+	require.Equal(t, "/path/to/repo", got["repo_path"])
+	require.Equal(t, true, got["is_dirty"])
+	require.NotContains(t, got, "error")
+}
+
+func TestSyncRemoteStack_ForceCloneReplacesCorruptedClone(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sourceRepo := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(sourceRepo, 0o755))
+	initTestGitRepo(t, sourceRepo)
+
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, "remote-app"), 0o755))
+	stackrYaml := `
+remote_repo:
+  url: ` + sourceRepo + `
+  branch: main
+  release:
+    type: commit
+    ref: HEAD
+`
+	require.NoError(t, os.WriteFile(filepath.Join(stacksDir, "remote-app", "stackr-repo.yml"), []byte(stackrYaml), 0o644))
+
+	// A clone whose .git is garbage: it looks cloned but git cannot use it
+	repoPath := filepath.Join(tmpDir, ".stackr-repos", "remote-app")
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".git", "HEAD"), []byte("garbage"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "stale.txt"), []byte("stale"), 0o644))
+
+	cfg := config.Config{
+		RepoRoot:  tmpDir,
+		StacksDir: stacksDir,
+		Global: config.GlobalConfig{
+			RemoteStacksDir: ".stackr-repos",
+		},
+	}
+
+	require.Error(t, SyncRemoteStack(cfg, "remote-app", map[string]string{}, false))
+
+	require.NoError(t, SyncRemoteStack(cfg, "remote-app", map[string]string{}, true))
+	require.FileExists(t, filepath.Join(repoPath, "README.md"))
+	require.NoFileExists(t, filepath.Join(repoPath, "stale.txt"))
+	_, err := git.NewClient(repoPath).CurrentCommit(context.Background())
+	require.NoError(t, err)
+
+	// Force-cloning a stack that was never cloned just clones it
+	require.NoError(t, os.RemoveAll(repoPath))
+	require.NoError(t, SyncRemoteStack(cfg, "remote-app", map[string]string{}, true))
+	require.FileExists(t, filepath.Join(repoPath, "README.md"))
+}
+
+func TestPlanSyncRemoteStack_NoGitOperations(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	sourceRepo := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(sourceRepo, 0o755))
+	initTestGitRepo(t, sourceRepo)
+
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	stackrYaml := `
+remote_repo:
+  url: ` + sourceRepo + `
+  branch: main
+  release:
+    type: tag
+    ref: ${APP_VERSION}
+`
+	for _, stack := range []string{"new-app", "cloned-app"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(stacksDir, stack), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(stacksDir, stack, "stackr-repo.yml"), []byte(stackrYaml), 0o644))
+	}
+	clonedPath := filepath.Join(tmpDir, ".stackr-repos", "cloned-app")
+	require.NoError(t, git.Clone(context.Background(), clonedPath, git.CloneOptions{URL: sourceRepo, Branch: "main", Depth: 1}))
+
+	// From here on any git call is recorded instead of run
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "git.log")
+	writeFile(t, filepath.Join(binDir, "git"), "#!/bin/sh\necho \"$@\" >> \""+logPath+"\"\nexit 1\n")
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "git"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := config.Config{
+		RepoRoot:  tmpDir,
+		StacksDir: stacksDir,
+		Global: config.GlobalConfig{
+			RemoteStacksDir: ".stackr-repos",
+		},
+	}
+	envVars := map[string]string{"APP_VERSION": "v1.2.0"}
+	newPath := filepath.Join(tmpDir, ".stackr-repos", "new-app")
+
+	plan, err := PlanSyncRemoteStack(cfg, "new-app", envVars, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"clone " + sourceRepo + " (branch main) into " + newPath,
+		"fetch and pull latest changes",
+		"check out tag v1.2.0",
+	}, plan)
+	require.NoDirExists(t, newPath)
+
+	plan, err = PlanSyncRemoteStack(cfg, "cloned-app", envVars, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fetch and pull latest changes", "check out tag v1.2.0"}, plan)
+
+	plan, err = PlanSyncRemoteStack(cfg, "cloned-app", envVars, true)
+	require.NoError(t, err)
+	require.Equal(t, "remove existing clone "+clonedPath, plan[0])
+	require.Equal(t, "clone "+sourceRepo+" (branch main) into "+clonedPath, plan[1])
+
+	_, err = PlanSyncRemoteStack(cfg, "new-app", map[string]string{}, false)
+	require.ErrorContains(t, err, "APP_VERSION")
+
+	repoPath, err := PlanCleanRemoteStack(cfg, "cloned-app")
+	require.NoError(t, err)
+	require.Equal(t, clonedPath, repoPath)
+	require.DirExists(t, clonedPath)
+
+	_, err = PlanCleanRemoteStack(cfg, "new-app")
+	require.ErrorIs(t, err, errNotCloned)
+
+	require.NoFileExists(t, logPath, "a dry run must not run git")
+}
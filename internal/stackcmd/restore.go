@@ -0,0 +1,232 @@
+package stackcmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jamestiberiuskirk/stackr/internal/fsutil"
+)
+
+// restoreStep copies one archived directory of a backup back into place.
+type restoreStep struct {
+	src     string
+	dest    string
+	archive bool
+}
+
+// restoreStack copies a backup of stack back into its stack dir and pool
+// bases. Incremental backups are replayed on top of their base chain, oldest
+// first, so the result matches the state at the chosen backup.
+func (m *Manager) restoreStack(stack, stackDir string, opts Options) error {
+	if m.backupDir == "" {
+		return errors.New("BACKUP_DIR is not set")
+	}
+
+	from := opts.RestoreFrom
+	if from == "" {
+		latest, err := m.latestBackup(stack)
+		if err != nil {
+			return err
+		}
+		if latest == "" {
+			return fmt.Errorf("no backup of %s found in %s", stack, m.backupDir)
+		}
+		from = latest
+	} else if _, err := time.Parse(backupTimestampLayout, from); err != nil {
+		return fmt.Errorf("invalid --from %q: expected a backup timestamp like %s", from, backupTimestampLayout)
+	}
+
+	chain, err := m.backupChain(stack, from)
+	if err != nil {
+		return err
+	}
+	steps := m.restoreSteps(stack, stackDir, chain)
+	if len(steps) == 0 {
+		return fmt.Errorf("backup %s of %s holds nothing to restore", from, stack)
+	}
+
+	if opts.DryRun {
+		_, _ = fmt.Fprintf(m.stdout, "[DRY RUN] Would restore %s from backup %s\n", stack, from)
+		for _, step := range steps {
+			_, _ = fmt.Fprintf(m.stdout, "  [DRY RUN] Would restore: %s -> %s\n", step.src, step.dest)
+		}
+		return nil
+	}
+
+	if !opts.Yes {
+		prompt := fmt.Sprintf("Restore %s from backup %s? This overwrites files in %s and the stack's pool volumes; stop the stack first. [y/N]: ", stack, from, stackDir)
+		if !m.confirm(prompt) {
+			return errors.New("restore aborted; re-run with --yes to skip the prompt")
+		}
+	}
+
+	_, _ = fmt.Fprintf(m.stdout, "Restoring %s from backup %s\n", stack, from)
+	if len(chain) > 1 {
+		_, _ = fmt.Fprintf(m.stdout, "  Replaying incremental chain: %s\n", strings.Join(chain, " -> "))
+	}
+	for _, step := range steps {
+		if err := m.applyRestoreStep(step); err != nil {
+			return err
+		}
+	}
+	_, _ = fmt.Fprintf(m.stdout, "Restore completed for %s\n", stack)
+	return nil
+}
+
+// latestBackup returns the most recent timestamp dir holding a backup of
+// stack, or "" if there is none.
+func (m *Manager) latestBackup(stack string) (string, error) {
+	entries, err := os.ReadDir(m.backupDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read backup dir: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupTimestampLayout, entry.Name()); err != nil {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	// Timestamp dirs sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(m.backupDir, name, stack))
+		if err == nil && info.IsDir() {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// backupChain returns the timestamp dirs needed to restore the backup of
+// stack taken at from, oldest first: the full backup followed by every
+// incremental on top of it. Backups without a manifest count as full.
+func (m *Manager) backupChain(stack, from string) ([]string, error) {
+	var chain []string
+	seen := map[string]bool{}
+	for name := from; name != ""; {
+		if seen[name] {
+			return nil, fmt.Errorf("backup %s of %s has a cyclic incremental chain", from, stack)
+		}
+		seen[name] = true
+
+		dir := filepath.Join(m.backupDir, name, stack)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			if name == from {
+				return nil, fmt.Errorf("no backup of %s at %s", stack, filepath.Join(m.backupDir, name))
+			}
+			return nil, fmt.Errorf("backup %s of %s is incremental on %s, which is missing", from, stack, name)
+		}
+		chain = append([]string{name}, chain...)
+
+		data, err := os.ReadFile(filepath.Join(dir, backupManifestFile))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				break
+			}
+			return nil, err
+		}
+		var manifest backupManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse backup manifest in %s: %w", name, err)
+		}
+		if manifest.Type != "incremental" {
+			break
+		}
+		name = manifest.Base
+	}
+	return chain, nil
+}
+
+// restoreSteps maps the archived dirs of every backup in chain back to where
+// backupStack copied them from. Dirs missing from a backup are skipped.
+func (m *Manager) restoreSteps(stack, stackDir string, chain []string) []restoreStep {
+	// Stack config directories, then pool volumes
+	var names, dests []string
+	for _, dir := range m.cfg.Global.Backup.Dirs() {
+		names = append(names, dir)
+		dests = append(dests, filepath.Join(stackDir, dir))
+	}
+	poolNames := make([]string, 0, len(m.poolBases))
+	for poolName := range m.poolBases {
+		poolNames = append(poolNames, poolName)
+	}
+	sort.Strings(poolNames)
+	for _, poolName := range poolNames {
+		names = append(names, fmt.Sprintf("pool_%s", strings.ToLower(poolName)))
+		dests = append(dests, filepath.Join(m.poolBases[poolName], stack))
+	}
+
+	var steps []restoreStep
+	for _, timestamp := range chain {
+		base := filepath.Join(m.backupDir, timestamp, stack)
+		for i, name := range names {
+			src := filepath.Join(base, name)
+			if info, err := os.Stat(src); err == nil && info.IsDir() {
+				steps = append(steps, restoreStep{src: src, dest: dests[i]})
+				continue
+			}
+			if info, err := os.Stat(src + ".tar.gz"); err == nil && info.Mode().IsRegular() {
+				steps = append(steps, restoreStep{src: src + ".tar.gz", dest: dests[i], archive: true})
+			}
+		}
+	}
+	return steps
+}
+
+func (m *Manager) applyRestoreStep(step restoreStep) error {
+	if step.archive {
+		extracted, err := fsutil.ExtractTarGz(step.src, step.dest)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s -> %s: %w", step.src, step.dest, err)
+		}
+		_, _ = fmt.Fprintf(m.stdout, "  ✓ Restored %s (%d file(s))\n", step.dest, extracted)
+		return nil
+	}
+
+	if err := fsutil.CopyDir(step.src, step.dest); err != nil {
+		return fmt.Errorf("failed to restore %s -> %s: %w", step.src, step.dest, err)
+	}
+	if err := m.preserveOwnership(step.src, step.dest); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(m.stdout, "  ✓ Restored %s\n", step.dest)
+	return nil
+}
+
+// confirm prints prompt and reports whether the answer read from stdin is
+// yes. A closed stdin counts as no.
+func (m *Manager) confirm(prompt string) bool {
+	switch m.ask(prompt) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// ask prints prompt and returns the lowercased answer read from stdin, or ""
+// when stdin is closed.
+func (m *Manager) ask(prompt string) string {
+	_, _ = fmt.Fprint(m.stdout, prompt)
+	answer, err := bufio.NewReader(m.stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		_, _ = fmt.Fprintln(m.stdout)
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(answer))
+}
@@ -0,0 +1,141 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func newRestoreTestManager(t *testing.T, root string, global config.GlobalConfig) (*Manager, *bytes.Buffer) {
+	t.Helper()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+	return manager, &stdout
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRestoreReplaysIncrementalChain(t *testing.T) {
+	root := t.TempDir()
+	manager, stdout := newRestoreTestManager(t, root, testGlobalConfig())
+
+	full := filepath.Join(root, "backups", "20240101_000000", "app")
+	makeDirs(t, full, "config")
+	makeDirs(t, full, "pool_ssd/data")
+	writeFile(t, filepath.Join(full, "config", "site.conf"), "v1")
+	writeFile(t, filepath.Join(full, "config", "keep.conf"), "kept")
+	writeFile(t, filepath.Join(full, "pool_ssd", "data", "db.sqlite"), "rows")
+	require.NoError(t, writeBackupManifest(full, backupManifest{Stack: "app", Type: "full", StartedAt: time.Now()}))
+
+	incremental := filepath.Join(root, "backups", "20240102_000000", "app")
+	makeDirs(t, incremental, "config")
+	writeFile(t, filepath.Join(incremental, "config", "site.conf"), "v2")
+	require.NoError(t, writeBackupManifest(incremental, backupManifest{Stack: "app", Type: "incremental", StartedAt: time.Now(), Base: "20240101_000000"}))
+
+	makeDirs(t, root, "stacks/app/config")
+	writeFile(t, filepath.Join(root, "stacks/app/config/site.conf"), "broken")
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, DryRun: true}))
+	require.Contains(t, stdout.String(), "[DRY RUN] Would restore app from backup 20240102_000000")
+	require.Contains(t, stdout.String(), filepath.Join(full, "pool_ssd")+" -> "+filepath.Join(root, ".ssd_pool", "app"))
+	require.Equal(t, "broken", readString(t, filepath.Join(root, "stacks/app/config/site.conf")))
+	require.NoDirExists(t, filepath.Join(root, ".ssd_pool", "app"))
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, Yes: true}))
+	require.Equal(t, "v2", readString(t, filepath.Join(root, "stacks/app/config/site.conf")))
+	require.Equal(t, "kept", readString(t, filepath.Join(root, "stacks/app/config/keep.conf")))
+	require.Equal(t, "rows", readString(t, filepath.Join(root, ".ssd_pool/app/data/db.sqlite")))
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, RestoreFrom: "20240101_000000", Yes: true}))
+	require.Equal(t, "v1", readString(t, filepath.Join(root, "stacks/app/config/site.conf")))
+}
+
+func TestRestorePromptsForConfirmation(t *testing.T) {
+	root := t.TempDir()
+	manager, stdout := newRestoreTestManager(t, root, testGlobalConfig())
+
+	backup := filepath.Join(root, "backups", "20240101_000000", "app")
+	makeDirs(t, backup, "config")
+	writeFile(t, filepath.Join(backup, "config", "site.conf"), "restored")
+
+	manager.stdin = strings.NewReader("n\n")
+	err := manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true})
+	require.ErrorContains(t, err, "restore aborted")
+	require.Contains(t, stdout.String(), "[y/N]")
+	require.NoDirExists(t, filepath.Join(root, "stacks/app/config"))
+
+	manager.stdin = strings.NewReader("")
+	require.Error(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true}))
+
+	manager.stdin = strings.NewReader("yes\n")
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true}))
+	require.Equal(t, "restored", readString(t, filepath.Join(root, "stacks/app/config/site.conf")))
+}
+
+func TestRestoreCompressedBackup(t *testing.T) {
+	root := t.TempDir()
+	global := testGlobalConfig()
+	global.Paths.BackupCompress = true
+	manager, _ := newRestoreTestManager(t, root, global)
+
+	makeDirs(t, root, "stacks/app/config/conf.d")
+	writeFile(t, filepath.Join(root, "stacks/app/config/conf.d/site.conf"), "server {}")
+	require.NoError(t, os.Symlink("conf.d/site.conf", filepath.Join(root, "stacks/app/config/current.conf")))
+	makeDirs(t, root, ".hdd_pool/app")
+	writeFile(t, filepath.Join(root, ".hdd_pool/app/media.bin"), "media")
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Backup: true}))
+
+	writeFile(t, filepath.Join(root, "stacks/app/config/conf.d/site.conf"), "broken")
+	require.NoError(t, os.RemoveAll(filepath.Join(root, ".hdd_pool/app")))
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, Yes: true}))
+	require.Equal(t, "server {}", readString(t, filepath.Join(root, "stacks/app/config/conf.d/site.conf")))
+	require.Equal(t, "server {}", readString(t, filepath.Join(root, "stacks/app/config/current.conf")))
+	require.Equal(t, "media", readString(t, filepath.Join(root, ".hdd_pool/app/media.bin")))
+}
+
+func TestRestoreErrors(t *testing.T) {
+	root := t.TempDir()
+	manager, _ := newRestoreTestManager(t, root, testGlobalConfig())
+
+	err := manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, Yes: true})
+	require.ErrorContains(t, err, "no backup of app found")
+
+	err = manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, RestoreFrom: "yesterday", Yes: true})
+	require.ErrorContains(t, err, "invalid --from")
+
+	incremental := filepath.Join(root, "backups", "20240102_000000", "app")
+	makeDirs(t, incremental, "config")
+	require.NoError(t, writeBackupManifest(incremental, backupManifest{Stack: "app", Type: "incremental", StartedAt: time.Now(), Base: "20240101_000000"}))
+	err = manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, Yes: true})
+	require.ErrorContains(t, err, "which is missing")
+
+	err = manager.Run(context.Background(), Options{Stacks: []string{"app"}, Restore: true, RestoreFrom: "20240103_000000", Yes: true})
+	require.ErrorContains(t, err, "no backup of app at")
+}
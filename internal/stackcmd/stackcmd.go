@@ -1,6 +1,7 @@
 package stackcmd
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -13,10 +14,13 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 
+	"github.com/jamestiberiuskirk/stackr/internal/compose"
 	"github.com/jamestiberiuskirk/stackr/internal/config"
 	"github.com/jamestiberiuskirk/stackr/internal/envfile"
 	"github.com/jamestiberiuskirk/stackr/internal/fsutil"
@@ -28,6 +32,10 @@ var (
 	closingMarker = "##########################"
 )
 
+// optionalVarsLabel lists, comma separated, vars a service references but
+// does not require to be set (e.g. ones relying on a ${VAR:-default}).
+const optionalVarsLabel = "stackr.validate.optional"
+
 type Options struct {
 	Debug        bool
 	DryRun       bool
@@ -41,25 +49,143 @@ type Options struct {
 	Init         bool
 	RunCron      bool
 	Remote       bool
+	Uninstall    bool
+	Yes          bool
+	Purge        bool
+	TagDigest    bool
+	JSON         bool
+	RecreateEnv  bool
+	Incremental  bool
+	Full         bool
+	Watch        bool
+	UpgradeCfg   bool
+	SelfUpdate   bool
+	Top          bool
 	Stacks       []string
+	Exclude      []string
 	VarsCommand  []string
 	Tag          string
 	CronService  string
 	RemoteSubCmd string
 	RemoteStack  string
+
+	// ApplyUnchanged makes an update bring the stack up even when its images
+	// are already current, so edits to its compose files still apply.
+	ApplyUnchanged bool
+	// AcceptEnvChanges allows deploying a remote stack whose merged env
+	// differs from the one applied at its last deploy.
+	AcceptEnvChanges bool
+	// ForceClone makes "remote sync" discard the existing clone and re-clone.
+	ForceClone bool
+	// List prints the discovered stacks instead of operating on them.
+	List bool
+	// CronList makes run-cron print the stacks' cron services instead of
+	// running one.
+	CronList bool
+	// CheckPorts fails a deploy whose published host ports are already bound.
+	CheckPorts bool
+	// Parallel is how many stacks to operate on at once; 0 or 1 runs serially.
+	Parallel int
+	// Logs streams "docker compose logs" for the stack.
+	Logs bool
+	// LogsFollow keeps streaming new log lines until cancelled.
+	LogsFollow bool
+	// LogsTail limits output to this many lines per container ("all" for no limit).
+	LogsTail string
+	// Events streams "docker events" for the stack until cancelled.
+	Events bool
+	// LogServices limits logs to these services; empty means all of them.
+	LogServices []string
+	// Profile selects per-profile settings such as remote release refs.
+	Profile string
+	// Completion is the shell to print a completion script for.
+	Completion string
+	// CompleteStacks prints stack names for completion scripts generated
+	// before list --names-only.
+	CompleteStacks bool
+	// NamesOnly makes list print only the stack names, one per line.
+	NamesOnly bool
+	// ComposeFile, when set, replaces the stack's compose files with this
+	// one, relative to the stack directory.
+	ComposeFile string
+	// Format is a text/template rendering each item of list output.
+	Format string
+	// OnlyChanged skips updating stacks whose running containers already
+	// match their compose config.
+	OnlyChanged bool
+	// Pause and Unpause freeze and thaw the stack's containers.
+	Pause   bool
+	Unpause bool
+	// Pull pulls the stack's images without restarting anything.
+	Pull bool
+	// Compress writes each backed up directory as a .tar.gz.
+	Compress bool
+	// EnvPassthrough adds host env vars to forward when env.passthrough
+	// restricts them.
+	EnvPassthrough []string
+	// Restore copies a stack's backup back into place.
+	Restore bool
+	// RestoreFrom picks the backup timestamp dir to restore; empty means the
+	// latest backup of the stack.
+	RestoreFrom string
+	// History prints recent HTTP deploys from the audit log, filtered to
+	// Stacks when any are given.
+	History bool
+	// HistoryLimit caps how many deploys history prints; 0 uses the default.
+	HistoryLimit int
+	// Volumes makes backup also dump the stack's named docker volumes.
+	Volumes bool
+	// TagFromGit sets Tag from "git describe --tags" of the repo root.
+	TagFromGit bool
+	// Validate checks stacks without deploying them or writing anything.
+	Validate bool
+	// NoPull makes update skip pulling images and restart with the local ones.
+	NoPull bool
+	// ForceRecreate passes --force-recreate to "up -d" and restarts on update
+	// even when no new images were pulled.
+	ForceRecreate bool
+	// ContinueOnError keeps going after a stack fails; Run then returns a
+	// *MultiError with every failure.
+	ContinueOnError bool
+	// PrintEnvDiff prints a diff of the .env keys a tag update changed.
+	PrintEnvDiff bool
 }
 
 type Manager struct {
-	cfg        config.Config
-	envFile    string
-	envValues  map[string]string
-	envContent string
-	targetDir  string
-	backupDir  string
-	baseEnv    map[string]string
-	poolBases  map[string]string
-	stdout     io.Writer
-	stderr     io.Writer
+	cfg       config.Config
+	envFile   string
+	env       *envState
+	targetDir string
+	backupDir string
+	baseEnv   map[string]string
+	poolBases map[string]string
+	stdin     io.Reader
+	stdout    io.Writer
+	stderr    io.Writer
+
+	// availableSpace reports the free bytes on the filesystem holding a path
+	availableSpace func(path string) (uint64, error)
+	// isTerminal reports whether stdin is interactive, which enables prompts
+	isTerminal func(r io.Reader) bool
+}
+
+// envState holds the parsed .env file. It is shared by the per-stack copies
+// of a Manager made for parallel runs, so access goes through mu.
+type envState struct {
+	mu      sync.Mutex
+	values  map[string]string
+	content string
+}
+
+// valuesCopy returns a snapshot of the .env values safe to use unlocked.
+func (e *envState) valuesCopy() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	values := make(map[string]string, len(e.values))
+	for k, v := range e.values {
+		values[k] = v
+	}
+	return values
 }
 
 func NewManager(cfg config.Config) (*Manager, error) {
@@ -98,23 +224,26 @@ func NewManagerWithWriters(cfg config.Config, stdout, stderr io.Writer) (*Manage
 		poolBases[key] = p
 	}
 
-	baseEnv := envMapFromOS()
+	baseEnv := hostEnv(cfg.Global.Env.Passthrough)
 	for k, v := range envValues {
 		baseEnv[k] = v
 		_ = os.Setenv(k, v)
 	}
 
 	return &Manager{
-		cfg:        cfg,
-		envFile:    cfg.EnvFile,
-		envValues:  envValues,
-		envContent: envContent,
-		targetDir:  targetDir,
-		backupDir:  backupDir,
-		baseEnv:    baseEnv,
-		poolBases:  poolBases,
-		stdout:     stdout,
-		stderr:     stderr,
+		cfg:       cfg,
+		envFile:   cfg.EnvFile,
+		env:       &envState{values: envValues, content: envContent},
+		targetDir: targetDir,
+		backupDir: backupDir,
+		baseEnv:   baseEnv,
+		poolBases: poolBases,
+		stdin:     os.Stdin,
+		stdout:    stdout,
+		stderr:    stderr,
+
+		availableSpace: fsutil.AvailableBytes,
+		isTerminal:     isTerminal,
 	}, nil
 }
 
@@ -126,9 +255,39 @@ func (m *Manager) Run(ctx context.Context, opts Options) error {
 		return errors.New("compose requires arguments (e.g. 'up -d', 'logs', 'ps')")
 	}
 
+	for _, key := range opts.EnvPassthrough {
+		if _, ok := m.baseEnv[key]; ok {
+			continue
+		}
+		if value, ok := os.LookupEnv(key); ok {
+			m.baseEnv[key] = value
+		}
+	}
+
+	if opts.Uninstall {
+		return m.uninstall(ctx, opts)
+	}
+
+	if opts.Validate {
+		return m.validate(ctx, opts)
+	}
+
+	if opts.TagFromGit {
+		tag, err := TagFromGit(ctx, m.cfg.RepoRoot)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(m.stdout, "Using tag %s from git describe\n", tag)
+		opts.Tag = tag
+	}
+
+	if opts.Watch {
+		return m.watchStacks(ctx, opts)
+	}
+
 	stacks := opts.Stacks
 	if opts.All {
-		names, err := m.loadAllStacks()
+		names, err := m.loadEnabledStacks()
 		if err != nil {
 			return err
 		}
@@ -136,7 +295,15 @@ func (m *Manager) Run(ctx context.Context, opts Options) error {
 	}
 
 	stacks = dedupePreserve(stacks)
+	if len(opts.Exclude) > 0 {
+		stacks = slices.DeleteFunc(stacks, func(stack string) bool {
+			return slices.Contains(opts.Exclude, stack)
+		})
+	}
 	if len(stacks) == 0 {
+		if len(opts.Exclude) > 0 {
+			return errors.New("no stacks left after --exclude")
+		}
 		return errors.New("no stacks specified")
 	}
 
@@ -145,6 +312,7 @@ func (m *Manager) Run(ctx context.Context, opts Options) error {
 		debugf(true, "dry run: %v", opts.DryRun)
 		debugf(true, "stacks dir: %s", m.targetDir)
 		debugf(true, "service list: %s", strings.Join(stacks, ", "))
+		debugf(true, "excluded: %s", joinOrNone(opts.Exclude))
 		debugf(true, "all services: %v", opts.All)
 		debugf(true, "tear down: %v", opts.TearDown)
 		debugf(true, "update: %v", opts.Update)
@@ -153,28 +321,51 @@ func (m *Manager) Run(ctx context.Context, opts Options) error {
 		debugf(true, "get vars: %v", opts.GetVars)
 	}
 
-	if opts.Backup && m.backupDir == "" {
+	if (opts.Backup || opts.Restore) && m.backupDir == "" {
 		return errors.New("BACKUP_DIR is not set in .env")
 	}
 
+	if opts.TearDown {
+		if err := m.confirmTearDown(stacks, opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Parallel > 1 && len(stacks) > 1 {
+		return m.runParallel(ctx, stacks, opts)
+	}
+
+	multi := &MultiError{Total: len(stacks)}
 	for _, stack := range stacks {
-		fmt.Printf("Stack: %s\n", stack)
+		// Keep stdout parseable when printing JSON
+		if !opts.JSON {
+			_, _ = fmt.Fprintf(m.stdout, "Stack: %s\n", stack)
+		}
 		if err := m.runStack(ctx, stack, opts); err != nil {
-			return err
+			if !opts.ContinueOnError {
+				return err
+			}
+			multi.Add(stack, err)
 		}
 	}
 
-	return nil
+	return multi.errOrNil()
 }
 
 func (m *Manager) runStack(ctx context.Context, stack string, opts Options) error {
 	if opts.Update && m.isStackOffline(stack) {
-		fmt.Printf("Stack %s is marked offline, skipping\n", stack)
+		_, _ = fmt.Fprintf(m.stdout, "Stack %s is marked offline, skipping\n", stack)
 		return nil
 	}
 
 	// Resolve stack path (handles both local and remote stacks)
-	stackInfo, err := ResolveStackPath(m.cfg, stack)
+	var stackInfo StackInfo
+	var err error
+	if opts.ComposeFile != "" {
+		stackInfo, err = ResolveStackComposeFile(m.cfg, stack, opts.ComposeFile)
+	} else {
+		stackInfo, err = ResolveStackPath(m.cfg, stack)
+	}
 	if err != nil {
 		return fmt.Errorf("stack %s: %w", stack, err)
 	}
@@ -191,29 +382,35 @@ func (m *Manager) runStack(ctx context.Context, stack string, opts Options) erro
 	if len(composePaths) == 0 {
 		return fmt.Errorf("stack %s: no compose files configured", stack)
 	}
+	// A remote stack's checkout only exists once synced
+	if opts.ComposeFile != "" && !fileExists(composePaths[0]) {
+		return fmt.Errorf("stack %s: compose file %s does not exist", stack, composePaths[0])
+	}
 	stackDir := filepath.Dir(composePaths[0])
 
 	// Update .env with new tag if specified
 	if opts.Tag != "" && opts.Update {
 		tagEnv := strings.ToUpper(stack) + "_IMAGE_TAG"
-		previous, err := envfile.Update(m.cfg.EnvFile, tagEnv, opts.Tag)
-		if err != nil {
-			return fmt.Errorf("failed to update %s: %w", tagEnv, err)
+		tag := opts.Tag
+		if opts.TagDigest {
+			tag, err = m.resolveTagDigest(ctx, composePaths, tagEnv, opts.Tag)
+			if err != nil {
+				return fmt.Errorf("stack %s: failed to resolve digest: %w", stack, err)
+			}
 		}
-		log.Printf("updated %s to %s (previous: %s)", tagEnv, opts.Tag, previous)
-
-		// Reload env values after update
-		envValues, envContent, err := readEnvFile(m.cfg.EnvFile)
-		if err != nil {
-			return fmt.Errorf("failed to reload env file: %w", err)
+		if err := m.updateEnvTag(tagEnv, tag, opts.PrintEnvDiff); err != nil {
+			return err
 		}
-		m.envValues = envValues
-		m.envContent = envContent
 	}
 
 	if opts.Backup {
 		debugf(opts.Debug, "%s: starting backup", stack)
-		return m.backupStack(stack, stackDir, opts)
+		return m.backupStack(ctx, stack, stackDir, opts)
+	}
+
+	if opts.Restore {
+		debugf(opts.Debug, "%s: starting restore", stack)
+		return m.restoreStack(stack, stackDir, opts)
 	}
 
 	vars, err := collectAllEnvVars(composePaths)
@@ -221,88 +418,250 @@ func (m *Manager) runStack(ctx context.Context, stack string, opts Options) erro
 		return fmt.Errorf("stack %s: failed to parse env vars: %w", stack, err)
 	}
 
+	envFileVars, err := collectServiceEnvFileVars(composePaths, m.env.valuesCopy())
+	if err != nil {
+		return fmt.Errorf("stack %s: %w", stack, err)
+	}
+
 	if opts.GetVars {
+		stackDotEnv, err := m.readStackEnvFile(stack)
+		if err != nil {
+			return err
+		}
+		// Vars provided by a service env_file or the stack's own .env do not
+		// belong in the global .env
+		vars = slices.DeleteFunc(vars, func(v string) bool {
+			_, inEnvFile := envFileVars[v]
+			_, inStackEnv := stackDotEnv[v]
+			return inEnvFile || inStackEnv
+		})
+		if opts.RecreateEnv {
+			debugf(opts.Debug, "%s: recreating vars block", stack)
+			return m.recreateStackVars(stack, vars, opts)
+		}
 		debugf(opts.Debug, "%s: collecting missing vars", stack)
 		return m.ensureStackVars(stack, vars, opts)
 	}
 
 	debugf(opts.Debug, "%s: running compose operations", stack)
-	return m.runCompose(ctx, stack, composePaths, vars, opts)
+	return m.runCompose(ctx, stack, stackInfo, vars, envFileVars, opts)
+}
+
+// updateEnvTag writes an image tag var to .env and reloads the env values.
+// With printDiff it prints the .env keys that changed as a unified-style diff.
+func (m *Manager) updateEnvTag(tagEnv, tag string, printDiff bool) error {
+	m.env.mu.Lock()
+	defer m.env.mu.Unlock()
+
+	var before map[string]string
+	if printDiff {
+		var err error
+		if before, _, err = readEnvFile(m.cfg.EnvFile); err != nil {
+			return fmt.Errorf("failed to read env file: %w", err)
+		}
+	}
+
+	previous, err := envfile.Update(m.cfg.EnvFile, tagEnv, tag)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", tagEnv, err)
+	}
+	log.Printf("updated %s to %s (previous: %s)", tagEnv, tag, previous)
+
+	// Reload env values after update
+	envValues, envContent, err := readEnvFile(m.cfg.EnvFile)
+	if err != nil {
+		return fmt.Errorf("failed to reload env file: %w", err)
+	}
+	if printDiff {
+		m.printEnvDiff(before, envValues)
+	}
+	m.env.values = envValues
+	m.env.content = envContent
+	return nil
+}
+
+// printEnvDiff writes the keys whose values differ between old and updated
+// as a unified-style diff of the .env file, with secret values masked.
+func (m *Manager) printEnvDiff(old, updated map[string]string) {
+	old, updated = m.maskSecrets(old), m.maskSecrets(updated)
+	keys := make([]string, 0, len(updated))
+	for k, v := range updated {
+		if prev, ok := old[k]; !ok || prev != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range old {
+		if _, ok := updated[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	name := filepath.Base(m.cfg.EnvFile)
+	_, _ = fmt.Fprintf(m.stdout, "--- a/%s\n+++ b/%s\n", name, name)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(m.stdout, "@@ %s @@\n", k)
+		if prev, ok := old[k]; ok {
+			_, _ = fmt.Fprintf(m.stdout, "-%s=%s\n", k, prev)
+		}
+		if v, ok := updated[k]; ok {
+			_, _ = fmt.Fprintf(m.stdout, "+%s=%s\n", k, v)
+		}
+	}
 }
 
+// loadAllStacks returns the names of every discovered stack, profile-gated
+// ones included.
 func (m *Manager) loadAllStacks() ([]string, error) {
+	return m.loadStacks(func(StackInfo) bool { return true })
+}
+
+// loadEnabledStacks returns the stacks `all` expands to: those enabled for
+// the active profile.
+func (m *Manager) loadEnabledStacks() ([]string, error) {
+	return m.loadStacks(func(s StackInfo) bool { return s.EnabledFor(m.cfg.Profile) })
+}
+
+func (m *Manager) loadStacks(keep func(StackInfo) bool) ([]string, error) {
 	stacks, err := DiscoverStacks(m.cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	names := make([]string, len(stacks))
-	for i, s := range stacks {
-		names[i] = s.Name
+	names := make([]string, 0, len(stacks))
+	for _, s := range stacks {
+		if !keep(s) {
+			continue
+		}
+		names = append(names, s.Name)
 	}
 	sort.Strings(names)
 	return names, nil
 }
 
-func (m *Manager) backupStack(stack, stackDir string, opts Options) error {
+func (m *Manager) backupStack(ctx context.Context, stack, stackDir string, opts Options) error {
 	if m.backupDir == "" {
 		return errors.New("BACKUP_DIR is not set")
 	}
 
-	timestamp := time.Now().Format("20060102_150405")
+	if err := m.checkBackupDir(); err != nil {
+		return err
+	}
+
+	startedAt := time.Now()
+	timestamp := startedAt.Format(backupTimestampLayout)
 	dest := filepath.Join(m.backupDir, timestamp, stack)
+	opts.Compress = opts.Compress || m.cfg.Global.Paths.BackupCompress
+
+	manifest := backupManifest{Stack: stack, Type: "full", StartedAt: startedAt}
+	var since time.Time
+	if opts.Incremental && !opts.Full {
+		last, lastDir, err := m.lastBackupManifest(stack)
+		if err != nil {
+			return err
+		}
+		if last == nil {
+			_, _ = fmt.Fprintf(m.stdout, "No previous backup of %s found, taking a full backup\n", stack)
+		} else {
+			manifest.Type = "incremental"
+			manifest.Base = lastDir
+			since = last.StartedAt
+		}
+	}
+
+	// Stack config directories, then pool volumes
+	var sources, dests []string
+	for _, dir := range m.cfg.Global.Backup.Dirs() {
+		sources = append(sources, filepath.Join(stackDir, dir))
+		dests = append(dests, filepath.Join(dest, dir))
+	}
+	for poolName, poolBase := range m.poolBases {
+		sources = append(sources, filepath.Join(poolBase, stack))
+		dests = append(dests, filepath.Join(dest, fmt.Sprintf("pool_%s", strings.ToLower(poolName))))
+	}
+	if opts.Compress {
+		for i := range dests {
+			dests[i] += ".tar.gz"
+		}
+	}
+
+	if err := m.checkBackupSpace(stack, sources, since); err != nil {
+		return err
+	}
 
 	if opts.DryRun {
-		fmt.Printf("[DRY RUN] Would create backup at: %s\n", dest)
+		_, _ = fmt.Fprintf(m.stdout, "[DRY RUN] Would create %s backup at: %s\n", manifest.Type, dest)
 	} else {
 		if err := os.MkdirAll(dest, 0o755); err != nil {
 			return fmt.Errorf("failed to create backup dir %s: %w", dest, err)
 		}
-		fmt.Printf("Creating backup at: %s\n", dest)
-	}
-
-	// Backup stack config directories
-	configDirs := []struct {
-		src string
-		dst string
-	}{
-		{filepath.Join(stackDir, "config"), filepath.Join(dest, "config")},
-		{filepath.Join(stackDir, "dashboards"), filepath.Join(dest, "dashboards")},
-		{filepath.Join(stackDir, "dynamic"), filepath.Join(dest, "dynamic")},
+		_, _ = fmt.Fprintf(m.stdout, "Creating %s backup at: %s\n", manifest.Type, dest)
+		if manifest.Base != "" {
+			_, _ = fmt.Fprintf(m.stdout, "  Copying files modified since %s (base: %s)\n", since.Format(time.RFC3339), manifest.Base)
+		}
 	}
 
-	for _, item := range configDirs {
-		if err := m.copyBackupDir(stack, item.src, item.dst, opts); err != nil {
+	for i, src := range sources {
+		if err := m.copyBackupDir(stack, src, dests[i], since, opts); err != nil {
 			return err
 		}
 	}
-
-	// Backup pool volumes
-	for poolName, poolBase := range m.poolBases {
-		poolVolume := filepath.Join(poolBase, stack)
-		poolDest := filepath.Join(dest, fmt.Sprintf("pool_%s", strings.ToLower(poolName)))
-		if err := m.copyBackupDir(stack, poolVolume, poolDest, opts); err != nil {
+	if opts.Volumes {
+		if err := m.backupVolumes(ctx, stack, dest, opts); err != nil {
 			return err
 		}
 	}
 
 	if !opts.DryRun {
-		fmt.Printf("Backup completed for %s\n", stack)
+		if err := writeBackupManifest(dest, manifest); err != nil {
+			return fmt.Errorf("failed to write backup manifest: %w", err)
+		}
+		_, _ = fmt.Fprintf(m.stdout, "Backup completed for %s\n", stack)
+	}
+	return m.applyBackupRetention(stack, manifest.Base, opts)
+}
+
+// checkBackupDir refuses backup locations that would end up copying backups
+// into themselves: anything inside the stacks dir or a pool base itself.
+func (m *Manager) checkBackupDir() error {
+	backupDir := filepath.Clean(m.backupDir)
+	if stacksDir := strings.TrimSpace(m.cfg.StacksDir); stacksDir != "" && isWithinDir(filepath.Clean(stacksDir), backupDir) {
+		return fmt.Errorf("backup dir %s is inside the stacks dir %s; set paths.backup_dir to a location outside it", backupDir, stacksDir)
+	}
+	for name, base := range m.poolBases {
+		if filepath.Clean(base) == backupDir {
+			return fmt.Errorf("backup dir %s is the same as pool %s; set paths.backup_dir to a separate location", backupDir, name)
+		}
 	}
 	return nil
 }
 
+// isWithinDir reports whether path is dir or nested somewhere below it.
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
 func (m *Manager) ensureStackVars(stack string, vars []string, opts Options) error {
+	m.env.mu.Lock()
+	defer m.env.mu.Unlock()
+
 	missing := make([]string, 0, len(vars))
 	for _, v := range vars {
 		// Skip auto-provisioned variables
 		if isAutoProvisionedVar(v) {
 			continue
 		}
-		if _, ok := m.envValues[v]; ok {
+		if _, ok := m.env.values[v]; ok {
 			continue
 		}
-		if strings.Contains(m.envContent, v) {
+		if strings.Contains(m.env.content, v) {
 			continue
 		}
 		missing = append(missing, fmt.Sprintf("%s=", v))
@@ -313,11 +672,11 @@ func (m *Manager) ensureStackVars(stack string, vars []string, opts Options) err
 	}
 
 	if opts.DryRun {
-		fmt.Printf("[DRY RUN] Would append vars for %s: %s\n", stack, strings.Join(missing, ", "))
+		_, _ = fmt.Fprintf(m.stdout, "[DRY RUN] Would append vars for %s: %s\n", stack, strings.Join(missing, ", "))
 		return nil
 	}
 
-	updated, changed := addVarsToEnv(m.envContent, stack, missing)
+	updated, changed := addVarsToEnv(m.env.content, stack, missing)
 	if !changed {
 		return nil
 	}
@@ -326,10 +685,10 @@ func (m *Manager) ensureStackVars(stack string, vars []string, opts Options) err
 		return fmt.Errorf("failed to update env file: %w", err)
 	}
 
-	m.envContent = updated
+	m.env.content = updated
 	for _, entry := range missing {
 		key := strings.TrimSuffix(entry, "=")
-		m.envValues[key] = ""
+		m.env.values[key] = ""
 	}
 
 	// If this is automatic validation (not get-vars), error out after appending
@@ -345,16 +704,123 @@ func (m *Manager) ensureStackVars(stack string, vars []string, opts Options) err
 	return nil
 }
 
-func (m *Manager) runCompose(ctx context.Context, stack string, composePaths []string, vars []string, opts Options) error {
-	envMap := m.baseEnvCopy()
-	stackEnv, err := m.buildStackEnv(ctx, stack)
+// recreateStackVars rewrites the stack's vars block in .env so it holds exactly
+// the vars currently referenced by its compose files. Values of vars that are
+// still required are kept; stale entries are dropped and reported.
+func (m *Manager) recreateStackVars(stack string, vars []string, opts Options) error {
+	marker := fmt.Sprintf("###### %s vars #####", strings.ToLower(stack))
+	m.env.mu.Lock()
+	start := strings.Index(m.env.content, marker)
+	if start == -1 {
+		m.env.mu.Unlock()
+		return m.ensureStackVars(stack, vars, opts)
+	}
+	defer m.env.mu.Unlock()
+	sectionStart := start + len(marker)
+	end := strings.Index(m.env.content[sectionStart:], closingMarker)
+	if end == -1 {
+		return fmt.Errorf("stack %s: vars block in %s has no closing marker", stack, m.envFile)
+	}
+	sectionEnd := sectionStart + end
+	sectionBody := m.env.content[sectionStart:sectionEnd]
+	outside := m.env.content[:start] + m.env.content[sectionEnd:]
+
+	existing := make(map[string]string)
+	var existingOrder []string
+	for _, line := range strings.Split(sectionBody, "\n") {
+		trim := strings.TrimSpace(line)
+		if trim == "" || strings.HasPrefix(trim, "#") {
+			continue
+		}
+		if idx := strings.Index(trim, "="); idx > 0 {
+			key := strings.TrimSpace(trim[:idx])
+			if _, ok := existing[key]; !ok {
+				existingOrder = append(existingOrder, key)
+			}
+			existing[key] = trim
+		}
+	}
+
+	required := make(map[string]bool)
+	var lines []string
+	for _, v := range vars {
+		if isAutoProvisionedVar(v) || required[v] {
+			continue
+		}
+		required[v] = true
+		if line, ok := existing[v]; ok {
+			lines = append(lines, line)
+			continue
+		}
+		// Defined elsewhere in .env (e.g. a shared var), no need to duplicate it
+		if extractKeys(outside)[v] {
+			continue
+		}
+		lines = append(lines, v+"=")
+	}
+
+	var removed []string
+	for _, key := range existingOrder {
+		if !required[key] {
+			removed = append(removed, key)
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(marker)
+	builder.WriteString("\n")
+	for _, line := range lines {
+		builder.WriteString(line)
+		builder.WriteString("\n")
+	}
+	updated := m.env.content[:start] + builder.String() + m.env.content[sectionEnd:]
+
+	if opts.DryRun {
+		_, _ = fmt.Fprintf(m.stdout, "[DRY RUN] Would recreate vars for %s (removing: %s)\n", stack, joinOrNone(removed))
+		return nil
+	}
+
+	if len(removed) > 0 {
+		_, _ = fmt.Fprintf(m.stdout, "Removed stale vars for %s: %s\n", stack, strings.Join(removed, ", "))
+	}
+	if updated == m.env.content {
+		return nil
+	}
+
+	if err := writeEnvFile(m.envFile, updated); err != nil {
+		return fmt.Errorf("failed to update env file: %w", err)
+	}
+
+	values, err := godotenv.Unmarshal(updated)
+	if err != nil {
+		return fmt.Errorf("failed to parse updated env file: %w", err)
+	}
+	m.env.content = updated
+	m.env.values = values
+	return nil
+}
+
+// composeEnv assembles the env docker compose runs with for stack. It also
+// returns the stack's config env and its own .env values on their own.
+func (m *Manager) composeEnv(ctx context.Context, stack string, composePaths []string) (envMap, stackEnv, stackDotEnv map[string]string, err error) {
+	envMap = m.baseEnvCopy()
+	stackEnv, err = m.buildStackEnv(ctx, stack)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	for k, v := range stackEnv {
 		envMap[k] = v
 	}
 
+	// The stack's own .env wins over everything above
+	stackDotEnv, err = m.readStackEnvFile(stack)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for k, v := range stackDotEnv {
+		envMap[k] = v
+	}
+
 	// Set legacy STACK_STORAGE_HDD and STACK_STORAGE_SSD if pools exist
 	if hddPool, ok := m.poolBases["HDD"]; ok {
 		envMap["STACK_STORAGE_HDD"] = filepath.Join(hddPool, stack)
@@ -368,13 +834,28 @@ func (m *Manager) runCompose(ctx context.Context, stack string, composePaths []s
 	for i, p := range composePaths {
 		envMap[fmt.Sprintf("DCFP_%d", i)] = p
 	}
+	return envMap, stackEnv, stackDotEnv, nil
+}
+
+func (m *Manager) runCompose(ctx context.Context, stack string, stackInfo StackInfo, vars []string, envFileVars map[string]string, opts Options) error {
+	composePaths := stackInfo.ComposePaths
+	envMap, stackEnv, stackDotEnv, err := m.composeEnv(ctx, stack, composePaths)
+	if err != nil {
+		return err
+	}
 
-	// Automatically check and append missing env vars before validation
-	if err := m.ensureStackVars(stack, vars, opts); err != nil {
+	// Automatically check and append missing env vars before validation,
+	// leaving out those a service env_file or the stack's .env provides
+	envVars := slices.DeleteFunc(slices.Clone(vars), func(v string) bool {
+		_, inEnvFile := envFileVars[v]
+		_, inStackEnv := stackDotEnv[v]
+		return inEnvFile || inStackEnv
+	})
+	if err := m.ensureStackVars(stack, envVars, opts); err != nil {
 		return err
 	}
 
-	if err := m.validateEnvVars(vars, envMap); err != nil {
+	if err := m.validateEnvVars(vars, envMap, envFileVars); err != nil {
 		return err
 	}
 
@@ -409,29 +890,49 @@ func (m *Manager) runCompose(ctx context.Context, stack string, composePaths []s
 	}
 
 	envSlice := mapToSlice(envMap)
+	debugf(opts.Debug, "%s: env:\n%s", stack, formatEnv(m.maskSecrets(envMap)))
+
+	isRemote := stackInfo.Type == StackTypeRemote
+	if isRemote && !opts.VarsOnly && !opts.TearDown && !opts.Top && !opts.Logs && !opts.Events && !opts.Pause && !opts.Unpause && !opts.Pull {
+		if err := m.checkRemoteEnvChanges(stack, stackEnv, opts); err != nil {
+			return err
+		}
+	}
 
 	if opts.DryRun {
+		// Commands that change the stack or run arbitrary programs are
+		// printed instead; everything else shows the resolved config
+		switch {
+		case opts.VarsOnly:
+			m.printDryRunCmd(envSlice, varsOnlyCommand(stackInfo, opts))
+			return nil
+		case opts.Pause:
+			m.printDryRunCmd(envSlice, composeCmd(stackInfo, "pause"))
+			return nil
+		case opts.Unpause:
+			m.printDryRunCmd(envSlice, composeCmd(stackInfo, "unpause"))
+			return nil
+		case opts.Pull:
+			m.printDryRunCmd(envSlice, composeCmd(stackInfo, "pull"))
+			return nil
+		case opts.TearDown:
+			m.printDryRunCmd(envSlice, composeCmd(stackInfo, "down"))
+			return nil
+		}
+
 		if hdd, ok := envMap["STACK_STORAGE_HDD"]; ok {
-			fmt.Println("STACK_STORAGE_HDD:", hdd)
+			_, _ = fmt.Fprintln(m.stdout, "STACK_STORAGE_HDD:", hdd)
 		}
 		if ssd, ok := envMap["STACK_STORAGE_SSD"]; ok {
-			fmt.Println("STACK_STORAGE_SSD:", ssd)
+			_, _ = fmt.Fprintln(m.stdout, "STACK_STORAGE_SSD:", ssd)
 		}
-		fmt.Println(composePaths[0])
+		_, _ = fmt.Fprintln(m.stdout, composePaths[0])
 		debugf(opts.Debug, "%s: running docker compose config", stack)
-		return m.runComposeCmd(ctx, envSlice, composePaths, "config")
+		return m.runComposeCmdMasked(ctx, envSlice, stackInfo, "config")
 	}
 
 	if opts.VarsOnly {
-		varsCmd := opts.VarsCommand
-		// If using 'compose' shorthand, prepend docker compose command with all -f flags
-		if opts.Compose {
-			args := []string{"docker", "compose"}
-			for _, p := range composePaths {
-				args = append(args, "-f", p)
-			}
-			varsCmd = append(args, opts.VarsCommand...)
-		}
+		varsCmd := varsOnlyCommand(stackInfo, opts)
 		debugf(opts.Debug, "%s: executing vars-only command %s", stack, strings.Join(varsCmd, " "))
 		cmd := exec.CommandContext(ctx, varsCmd[0], varsCmd[1:]...)
 		cmd.Dir = m.cfg.RepoRoot
@@ -441,46 +942,152 @@ func (m *Manager) runCompose(ctx context.Context, stack string, composePaths []s
 		return cmd.Run()
 	}
 
+	if opts.Top {
+		debugf(opts.Debug, "%s: collecting resource usage", stack)
+		return m.stackTop(ctx, envSlice, stackInfo, stack, opts)
+	}
+
+	if opts.Logs {
+		return m.stackLogs(ctx, envSlice, stackInfo, stack, opts)
+	}
+
+	if opts.Events {
+		return m.stackEvents(ctx, envSlice, stackInfo, stack, opts)
+	}
+
+	if opts.Pause {
+		debugf(opts.Debug, "%s: pausing stack", stack)
+		return m.runComposeCmd(ctx, envSlice, stackInfo, "pause")
+	}
+
+	if opts.Unpause {
+		debugf(opts.Debug, "%s: unpausing stack", stack)
+		return m.runComposeCmd(ctx, envSlice, stackInfo, "unpause")
+	}
+
+	if opts.Pull {
+		debugf(opts.Debug, "%s: pulling images", stack)
+		pulled, err := m.pullImages(ctx, envSlice, stackInfo, stack, opts.Debug)
+		if err != nil {
+			return err
+		}
+		if pulled {
+			_, _ = fmt.Fprintf(m.stdout, "%s: pulled images\n", stack)
+		} else {
+			_, _ = fmt.Fprintf(m.stdout, "%s: images already up to date\n", stack)
+		}
+		return nil
+	}
+
 	if opts.TearDown {
 		debugf(opts.Debug, "%s: tearing stack down", stack)
-		return m.runComposeCmd(ctx, envSlice, composePaths, "down")
+		return m.runComposeCmd(ctx, envSlice, stackInfo, "down")
+	}
+
+	// Checked before the restart below so a conflict leaves the stack running
+	if opts.CheckPorts {
+		debugf(opts.Debug, "%s: checking host ports", stack)
+		if err := m.checkPortConflicts(ctx, envSlice, stackInfo, stack); err != nil {
+			return err
+		}
 	}
 
-	running, err := m.composeOutput(ctx, envSlice, composePaths, "ps", "-a", "--services", "--filter", "status=running")
+	if opts.OnlyChanged {
+		changed, reason, err := m.stackChanged(ctx, envSlice, stackInfo)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			_, _ = fmt.Fprintf(m.stdout, "%s: no changes, skipping\n", stack)
+			return nil
+		}
+		debugf(opts.Debug, "%s: %s", stack, reason)
+	}
+
+	running, err := m.composeOutput(ctx, envSlice, stackInfo, "ps", "-a", "--services", "--filter", "status=running")
 	if err != nil {
 		return err
 	}
-	services, err := m.composeOutput(ctx, envSlice, composePaths, "ps", "-a", "--services")
+	services, err := m.composeOutput(ctx, envSlice, stackInfo, "ps", "-a", "--services")
 	if err != nil {
 		return err
 	}
 
 	if running != "" && running == services {
 		debugf(opts.Debug, "%s: restarting stack (all services running)", stack)
-		if err := m.runComposeCmd(ctx, envSlice, composePaths, "down"); err != nil {
+		if err := m.runComposeCmd(ctx, envSlice, stackInfo, "down"); err != nil {
 			return err
 		}
 	}
 
-	if opts.Update {
-		debugf(opts.Debug, "%s: checking for image updates", stack)
-		updated, err := m.pullImages(ctx, envSlice, composePaths, stack, opts.Debug)
+	policy := m.cfg.Global.ImagePullPolicy()
+	if opts.NoPull {
+		policy = config.PullNever
+	}
+	if opts.Update && policy == config.PullNever {
+		debugf(opts.Debug, "%s: skipping image pull (pull policy %s)", stack, policy)
+	} else if opts.Update {
+		debugf(opts.Debug, "%s: checking for image updates (pull policy %s)", stack, policy)
+		pull := m.pullImages
+		if policy == config.PullMissing {
+			pull = m.pullMissingImages
+		}
+		updated, err := pull(ctx, envSlice, stackInfo, stack, opts.Debug)
 		if err != nil {
 			return err
 		}
-		if !updated {
-			fmt.Printf("%s: all images up to date, skipping restart\n", stack)
+		switch {
+		case updated:
+			_, _ = fmt.Fprintf(m.stdout, "%s: new images downloaded, restarting stack\n", stack)
+		case opts.ForceRecreate:
+			_, _ = fmt.Fprintf(m.stdout, "%s: all images up to date, recreating anyway (--force-recreate)\n", stack)
+		case opts.ApplyUnchanged:
+			_, _ = fmt.Fprintf(m.stdout, "%s: all images up to date, applying stack changes\n", stack)
+		default:
+			_, _ = fmt.Fprintf(m.stdout, "%s: all images up to date, skipping restart\n", stack)
 			return nil
 		}
-		fmt.Printf("%s: new images downloaded, restarting stack\n", stack)
 	}
 
+	upArgs := []string{"up", "-d"}
+	if opts.ForceRecreate {
+		upArgs = append(upArgs, "--force-recreate")
+	}
 	debugf(opts.Debug, "%s: bringing stack up", stack)
-	return m.runComposeCmd(ctx, envSlice, composePaths, "up", "-d")
+	if err := m.runComposeCmd(ctx, envSlice, stackInfo, upArgs...); err != nil {
+		return err
+	}
+
+	if isRemote {
+		if err := m.saveAppliedEnv(stack, stackEnv); err != nil {
+			log.Printf("warning: failed to record applied env for %s: %v", stack, err)
+		}
+	}
+
+	// An update may rename the stack's cron containers, so prune the old ones now
+	// rather than waiting for the scheduler's periodic cleanup
+	if opts.Update {
+		removed, err := CleanupCronContainers(ctx, stack, m.cfg.Global.Cron.ContainerRetention)
+		if err != nil {
+			log.Printf("warning: failed to clean up cron containers of %s: %v", stack, err)
+		} else if len(removed) > 0 {
+			_, _ = fmt.Fprintf(m.stdout, "%s: removed %d old cron container(s)\n", stack, len(removed))
+		}
+	}
+	return nil
 }
 
-func (m *Manager) runComposeCmd(ctx context.Context, env []string, composePaths []string, args ...string) error {
-	fullArgs := composeFileArgs(composePaths)
+// varsOnlyCommand returns the command vars-only runs: the one after "--", or
+// for the compose shorthand, docker compose with the stack's files and it.
+func varsOnlyCommand(stackInfo StackInfo, opts Options) []string {
+	if opts.Compose {
+		return composeCmd(stackInfo, opts.VarsCommand...)
+	}
+	return opts.VarsCommand
+}
+
+func (m *Manager) runComposeCmd(ctx context.Context, env []string, stackInfo StackInfo, args ...string) error {
+	fullArgs := composeFileArgs(stackInfo)
 	fullArgs = append(fullArgs, args...)
 	cmd := exec.CommandContext(ctx, "docker", fullArgs...)
 	cmd.Dir = m.cfg.RepoRoot
@@ -490,32 +1097,56 @@ func (m *Manager) runComposeCmd(ctx context.Context, env []string, composePaths
 	return cmd.Run()
 }
 
-func (m *Manager) composeOutput(ctx context.Context, env []string, composePaths []string, args ...string) (string, error) {
-	fullArgs := composeFileArgs(composePaths)
+// runComposeCmdMasked is runComposeCmd for commands whose output may echo
+// env values: secrets in it are masked before it is printed.
+func (m *Manager) runComposeCmdMasked(ctx context.Context, env []string, stackInfo StackInfo, args ...string) error {
+	fullArgs := composeFileArgs(stackInfo)
+	fullArgs = append(fullArgs, args...)
+	cmd := exec.CommandContext(ctx, "docker", fullArgs...)
+	cmd.Dir = m.cfg.RepoRoot
+	cmd.Env = env
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	_, _ = io.WriteString(m.stdout, m.maskText(stdout.String(), env))
+	_, _ = io.WriteString(m.stderr, m.maskText(stderr.String(), env))
+	return err
+}
+
+func (m *Manager) composeOutput(ctx context.Context, env []string, stackInfo StackInfo, args ...string) (string, error) {
+	fullArgs := composeFileArgs(stackInfo)
 	fullArgs = append(fullArgs, args...)
 	cmd := exec.CommandContext(ctx, "docker", fullArgs...)
 	cmd.Dir = m.cfg.RepoRoot
 	cmd.Env = env
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", fmt.Errorf("docker %s failed: %v\n%s", strings.Join(fullArgs, " "), err, string(out))
+		return "", fmt.Errorf("docker %s failed: %v\n%s", strings.Join(fullArgs, " "), err, m.maskText(string(out), env))
 	}
 	return strings.TrimSpace(string(out)), nil
 }
 
-// composeFileArgs builds ["compose", "-f", path1, "-f", path2, ...] for docker CLI.
-func composeFileArgs(composePaths []string) []string {
+// composeFileArgs builds ["compose", "--project-directory", dir, "-f", path1, "-f", path2, ...] for docker CLI.
+func composeFileArgs(stackInfo StackInfo) []string {
 	args := []string{"compose"}
-	for _, p := range composePaths {
+	if stackInfo.ProjectDir != "" {
+		args = append(args, "--project-directory", stackInfo.ProjectDir)
+	}
+	// The project directory would otherwise name the project
+	if stackInfo.Name != "" {
+		args = append(args, "-p", compose.ProjectName(stackInfo.Name))
+	}
+	for _, p := range stackInfo.ComposePaths {
 		args = append(args, "-f", p)
 	}
 	return args
 }
 
 // pullImages checks for updates, pulls if needed, and returns true if any images were updated
-func (m *Manager) pullImages(ctx context.Context, env []string, composePaths []string, stack string, debug bool) (bool, error) {
+func (m *Manager) pullImages(ctx context.Context, env []string, stackInfo StackInfo, stack string, debug bool) (bool, error) {
 	// First, check if updates are available without downloading
-	hasUpdates, err := m.checkImageUpdates(ctx, env, composePaths, stack, debug)
+	hasUpdates, err := m.checkImageUpdates(ctx, env, stackInfo, stack, debug)
 	if err != nil {
 		// If check fails, fall back to pull (conservative approach)
 		log.Printf("%s: image update check failed (%v), falling back to pull", stack, err)
@@ -526,7 +1157,7 @@ func (m *Manager) pullImages(ctx context.Context, env []string, composePaths []s
 
 	// Updates available or check failed - proceed with pull
 	log.Printf("%s: pulling latest images", stack)
-	fullArgs := composeFileArgs(composePaths)
+	fullArgs := composeFileArgs(stackInfo)
 	fullArgs = append(fullArgs, "pull")
 	cmd := exec.CommandContext(ctx, "docker", fullArgs...)
 	cmd.Dir = m.cfg.RepoRoot
@@ -534,17 +1165,44 @@ func (m *Manager) pullImages(ctx context.Context, env []string, composePaths []s
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return false, fmt.Errorf("docker compose pull failed: %v\n%s", err, string(out))
+		return false, fmt.Errorf("docker compose pull failed: %v\n%s", err, m.maskText(string(out), env))
 	}
 
 	log.Printf("%s: pull completed", stack)
 	return true, nil
 }
 
-// checkImageUpdates checks if remote images have updates without downloading them
-func (m *Manager) checkImageUpdates(ctx context.Context, env []string, composePaths []string, stack string, debug bool) (bool, error) {
-	// Get list of images from compose file
-	fullArgs := composeFileArgs(composePaths)
+// pullMissingImages pulls the stack's images that are not on the host yet,
+// for pull_policy missing, and returns true if it pulled any.
+func (m *Manager) pullMissingImages(ctx context.Context, env []string, stackInfo StackInfo, stack string, debug bool) (bool, error) {
+	images, err := m.composeImages(ctx, env, stackInfo)
+	if err != nil {
+		return false, err
+	}
+
+	pulled := false
+	for _, image := range images {
+		inspect := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{.Id}}", image)
+		if err := inspect.Run(); err == nil {
+			debugf(debug, "%s: image %s present locally", stack, image)
+			continue
+		}
+
+		log.Printf("%s: pulling missing image %s", stack, image)
+		cmd := exec.CommandContext(ctx, "docker", "pull", image)
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return false, fmt.Errorf("docker pull %s failed: %v\n%s", image, err, m.maskText(string(out), env))
+		}
+		pulled = true
+	}
+	return pulled, nil
+}
+
+// composeImages returns the images of the stack's services, as listed by
+// "docker compose config --images".
+func (m *Manager) composeImages(ctx context.Context, env []string, stackInfo StackInfo) ([]string, error) {
+	fullArgs := composeFileArgs(stackInfo)
 	fullArgs = append(fullArgs, "config", "--images")
 	cmd := exec.CommandContext(ctx, "docker", fullArgs...)
 	cmd.Dir = m.cfg.RepoRoot
@@ -552,23 +1210,32 @@ func (m *Manager) checkImageUpdates(ctx context.Context, env []string, composePa
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return false, fmt.Errorf("failed to get image list: %v", err)
+		return nil, fmt.Errorf("failed to get image list: %v", err)
 	}
 
-	images := strings.Split(strings.TrimSpace(string(out)), "\n")
-	if len(images) == 0 || images[0] == "" {
-		return false, fmt.Errorf("no images found in compose file")
+	var images []string
+	for _, image := range strings.Split(string(out), "\n") {
+		if image = strings.TrimSpace(image); image != "" {
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images found in compose file")
+	}
+	return images, nil
+}
+
+// checkImageUpdates checks if remote images have updates without downloading them
+func (m *Manager) checkImageUpdates(ctx context.Context, env []string, stackInfo StackInfo, stack string, debug bool) (bool, error) {
+	images, err := m.composeImages(ctx, env, stackInfo)
+	if err != nil {
+		return false, err
 	}
 
 	log.Printf("%s: checking %d images for updates", stack, len(images))
 
 	// Check each image for updates
 	for _, image := range images {
-		image = strings.TrimSpace(image)
-		if image == "" {
-			continue
-		}
-
 		hasUpdate, err := m.hasImageUpdate(ctx, image, debug)
 		if err != nil {
 			// If we can't check, assume update exists (conservative)
@@ -633,14 +1300,19 @@ func (m *Manager) hasImageUpdate(ctx context.Context, image string, debug bool)
 	return false, nil
 }
 
-func (m *Manager) validateEnvVars(vars []string, env map[string]string) error {
+// validateEnvVars reports vars that are empty in env. Vars defined in a
+// service's env_file (envFileVars) count as set.
+func (m *Manager) validateEnvVars(vars []string, env, envFileVars map[string]string) error {
 	var missing []string
 	for _, v := range vars {
 		// Skip auto-provisioned variables (they're always available at runtime)
 		if isAutoProvisionedVar(v) {
 			continue
 		}
-		if value, ok := env[v]; !ok || strings.TrimSpace(value) == "" {
+		if value, ok := env[v]; ok && strings.TrimSpace(value) != "" {
+			continue
+		}
+		if value, ok := envFileVars[v]; !ok || strings.TrimSpace(value) == "" {
 			missing = append(missing, v)
 		}
 	}
@@ -650,6 +1322,63 @@ func (m *Manager) validateEnvVars(vars []string, env map[string]string) error {
 	return nil
 }
 
+// collectServiceEnvFileVars reads the env_file entries of every service in the
+// compose files. Relative paths resolve against the compose file's directory
+// and may reference vars from env; missing files are skipped. Vars
+// interpolated in a service's environment are left out: compose fills those
+// from the project env, so an env_file does not provide them.
+func collectServiceEnvFileVars(paths []string, env map[string]string) (map[string]string, error) {
+	result := make(map[string]string)
+	var interpolated []string
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		var parsed struct {
+			Services map[string]struct {
+				EnvFile     compose.EnvFileList `yaml:"env_file"`
+				Environment compose.LabelMap    `yaml:"environment"`
+			} `yaml:"services"`
+		}
+		if err := yaml.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+
+		for _, service := range parsed.Services {
+			for _, value := range service.Environment {
+				interpolated = append(interpolated, uniqueEnvVars(value)...)
+			}
+			for _, envFile := range service.EnvFile {
+				envFile = os.Expand(envFile, func(key string) string { return env[key] })
+				if !filepath.IsAbs(envFile) {
+					envFile = filepath.Join(filepath.Dir(p), envFile)
+				}
+				values, err := godotenv.Read(envFile)
+				if err != nil {
+					if errors.Is(err, os.ErrNotExist) {
+						continue
+					}
+					return nil, fmt.Errorf("failed to read env_file %s: %w", envFile, err)
+				}
+				for k, v := range values {
+					result[k] = v
+				}
+			}
+		}
+	}
+	for _, v := range interpolated {
+		delete(result, v)
+	}
+	return result, nil
+}
+
+// collectAllEnvVars returns the vars referenced by the compose files, leaving
+// out those every referencing service marks as optional via optionalVarsLabel.
 func collectAllEnvVars(paths []string) ([]string, error) {
 	seen := make(map[string]struct{})
 	var result []string
@@ -661,7 +1390,14 @@ func collectAllEnvVars(paths []string) ([]string, error) {
 			}
 			return nil, err
 		}
+		optional, err := optionalEnvVars(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
 		for _, v := range uniqueEnvVars(string(data)) {
+			if optional[v] {
+				continue
+			}
 			if _, ok := seen[v]; !ok {
 				seen[v] = struct{}{}
 				result = append(result, v)
@@ -671,6 +1407,76 @@ func collectAllEnvVars(paths []string) ([]string, error) {
 	return result, nil
 }
 
+// optionalEnvVars returns the vars listed in a service's optionalVarsLabel
+// that are not also referenced, without the label, by another service or
+// outside the services section.
+func optionalEnvVars(data []byte) (map[string]bool, error) {
+	var doc map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var services map[string]yaml.Node
+	if node, ok := doc["services"]; ok {
+		if err := node.Decode(&services); err != nil {
+			return nil, err
+		}
+	}
+
+	optional := make(map[string]bool)
+	required := make(map[string]bool)
+	for key, node := range doc {
+		if key == "services" {
+			continue
+		}
+		vars, err := nodeEnvVars(&node)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vars {
+			required[v] = true
+		}
+	}
+
+	for _, node := range services {
+		var service struct {
+			Labels compose.LabelMap `yaml:"labels"`
+		}
+		if err := node.Decode(&service); err != nil {
+			return nil, err
+		}
+		serviceOptional := make(map[string]bool)
+		for _, name := range strings.Split(service.Labels[optionalVarsLabel], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				serviceOptional[name] = true
+				optional[name] = true
+			}
+		}
+		vars, err := nodeEnvVars(&node)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vars {
+			if !serviceOptional[v] {
+				required[v] = true
+			}
+		}
+	}
+
+	for v := range required {
+		delete(optional, v)
+	}
+	return optional, nil
+}
+
+func nodeEnvVars(node *yaml.Node) ([]string, error) {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	return uniqueEnvVars(string(data)), nil
+}
+
 func addVarsToEnv(content, stack string, vars []string) (string, bool) {
 	if len(vars) == 0 {
 		return content, false
@@ -772,6 +1578,17 @@ func readEnvFile(path string) (map[string]string, string, error) {
 	return values, string(data), nil
 }
 
+// readStackEnvFile reads the optional stacks/<stack>/.env, whose values are
+// merged over the global .env. A missing file yields an empty map.
+func (m *Manager) readStackEnvFile(stack string) (map[string]string, error) {
+	path := filepath.Join(m.cfg.StacksDir, stack, ".env")
+	values, _, err := readEnvFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("stack %s: failed to read %s: %w", stack, path, err)
+	}
+	return values, nil
+}
+
 func writeEnvFile(path, content string) error {
 	mode := os.FileMode(0o644)
 	if info, err := os.Stat(path); err == nil {
@@ -790,7 +1607,10 @@ func (m *Manager) isStackOffline(stack string) bool {
 	offlineVar := strings.ToUpper(stack) + "_OFFLINE"
 
 	// Check .env file values first
-	if val, ok := m.envValues[offlineVar]; ok {
+	m.env.mu.Lock()
+	val, ok := m.env.values[offlineVar]
+	m.env.mu.Unlock()
+	if ok {
 		return strings.EqualFold(strings.TrimSpace(val), "true")
 	}
 
@@ -855,7 +1675,7 @@ func uniqueEnvVars(content string) []string {
 
 func (m *Manager) syncRemoteStack(ctx context.Context, stack string) error {
 	remoteMgr := remote.NewManager(m.cfg)
-	return remoteMgr.EnsureRemoteStack(ctx, stack, m.envValues)
+	return remoteMgr.EnsureRemoteStack(ctx, stack, m.env.valuesCopy())
 }
 
 func (m *Manager) buildStackEnv(ctx context.Context, stack string) (map[string]string, error) {
@@ -935,6 +1755,26 @@ func envMapFromOS() map[string]string {
 	return result
 }
 
+// alwaysPassthrough are forwarded even when env.passthrough is set; docker
+// needs them to find its plugins and config.
+var alwaysPassthrough = []string{"PATH", "HOME"}
+
+// hostEnv returns the host env forwarded to compose: all of it, or only the
+// allowlisted names when passthrough is set.
+func hostEnv(passthrough []string) map[string]string {
+	env := envMapFromOS()
+	if passthrough == nil {
+		return env
+	}
+	allowed := make(map[string]string)
+	for _, key := range slices.Concat(alwaysPassthrough, passthrough) {
+		if value, ok := env[key]; ok {
+			allowed[key] = value
+		}
+	}
+	return allowed
+}
+
 func (m *Manager) baseEnvCopy() map[string]string {
 	out := make(map[string]string, len(m.baseEnv))
 	for k, v := range m.baseEnv {
@@ -958,7 +1798,11 @@ func debugf(enabled bool, format string, args ...interface{}) {
 	}
 	fmt.Printf("[DEBUG]: "+format+"\n", args...)
 }
-func (m *Manager) copyBackupDir(stack, src, dest string, opts Options) error {
+
+// copyBackupDir copies src into dest, or archives it into the dest tarball
+// with --compress (tar headers keep file owners). A non-zero since limits
+// the copy to files modified after it (incremental backups).
+func (m *Manager) copyBackupDir(stack, src, dest string, since time.Time, opts Options) error {
 	info, err := os.Stat(src)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -972,15 +1816,59 @@ func (m *Manager) copyBackupDir(stack, src, dest string, opts Options) error {
 	}
 
 	if opts.DryRun {
-		fmt.Printf("  [DRY RUN] Would backup: %s -> %s\n", src, dest)
+		_, _ = fmt.Fprintf(m.stdout, "  [DRY RUN] Would backup: %s -> %s\n", src, dest)
 		debugf(opts.Debug, "%s: skipping copy (dry run) %s", stack, src)
 		return nil
 	}
 
+	if opts.Compress {
+		archived, err := fsutil.TarGzDir(src, dest, since)
+		if err != nil {
+			return fmt.Errorf("failed to backup %s -> %s: %w", src, dest, err)
+		}
+		if !since.IsZero() {
+			_, _ = fmt.Fprintf(m.stdout, "  ✓ Backed up %s to %s (%d changed file(s))\n", src, filepath.Base(dest), archived)
+		} else {
+			_, _ = fmt.Fprintf(m.stdout, "  ✓ Backed up %s to %s\n", src, filepath.Base(dest))
+		}
+		return nil
+	}
+
+	if !since.IsZero() {
+		copied, err := fsutil.CopyDirModifiedSince(src, dest, since)
+		if err != nil {
+			return fmt.Errorf("failed to backup %s -> %s: %w", src, dest, err)
+		}
+		if err := m.preserveOwnership(src, dest); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(m.stdout, "  ✓ Backed up %s (%d changed file(s))\n", src, copied)
+		return nil
+	}
+
 	if err := fsutil.CopyDir(src, dest); err != nil {
 		return fmt.Errorf("failed to backup %s -> %s: %w", src, dest, err)
 	}
-	fmt.Printf("  ✓ Backed up %s\n", src)
+	if err := m.preserveOwnership(src, dest); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(m.stdout, "  ✓ Backed up %s\n", src)
+	return nil
+}
+
+// preserveOwnership copies file owners from src to dest when
+// backup.preserve_ownership is set. Missing privileges only warn.
+func (m *Manager) preserveOwnership(src, dest string) error {
+	if !m.cfg.Global.Backup.PreserveOwnership {
+		return nil
+	}
+	skipped, err := fsutil.CopyOwnership(src, dest)
+	if err != nil {
+		return fmt.Errorf("failed to preserve ownership of %s: %w", dest, err)
+	}
+	if skipped > 0 {
+		_, _ = fmt.Fprintf(m.stderr, "  ! Could not preserve ownership of %d path(s) in %s (needs privileges)\n", skipped, dest)
+	}
 	return nil
 }
 
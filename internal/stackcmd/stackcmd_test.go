@@ -1,7 +1,9 @@
 package stackcmd
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -47,10 +49,159 @@ services:
 	logData, err := os.ReadFile(logPath)
 	require.NoError(t, err)
 	got := strings.TrimSpace(string(logData))
-	require.Contains(t, got, "compose -f")
+	require.Contains(t, got, "compose --project-directory "+filepath.Join(root, "stacks/demo")+" -p demo -f")
 	require.True(t, strings.HasSuffix(got, "config"), "expected compose config call, got %q", got)
 }
 
+func TestComposeProjectDirectoryOverride(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo/compose")
+	makeDirs(t, root, "stacks/demo/stackr")
+	writeFile(t, filepath.Join(root, ".env"), "")
+	writeFile(t, filepath.Join(root, "stacks/demo/compose/docker-compose.yml"), `
+services:
+  web:
+    image: nginx
+`)
+	writeFile(t, filepath.Join(root, "stacks/demo/stackr/config.yaml"), `
+compose_files:
+  - compose/docker-compose.yml
+project_directory: ..
+`)
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, TearDown: true}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t,
+		"compose --project-directory "+filepath.Join(root, "stacks/demo")+
+			" -p demo -f "+filepath.Join(root, "stacks/demo/compose/docker-compose.yml")+" down",
+		strings.TrimSpace(string(logData)))
+}
+
+func TestComposeProjectDirectoryKeepsProjectName(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo/app")
+	makeDirs(t, root, "stacks/demo/stackr")
+	writeFile(t, filepath.Join(root, ".env"), "")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), `
+services:
+  web:
+    image: nginx
+`)
+	writeFile(t, filepath.Join(root, "stacks/demo/stackr/config.yaml"), `
+project_directory: app
+`)
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, TearDown: true}))
+
+	// Without -p compose would name the project "app" after the directory
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t,
+		"compose --project-directory "+filepath.Join(root, "stacks/demo/app")+
+			" -p demo -f "+filepath.Join(root, "stacks/demo/docker-compose.yml")+" down",
+		strings.TrimSpace(string(logData)))
+}
+
+func TestRunComposeFileOverride(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), "")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), "services:\n  web:\n    image: nginx\n")
+	writeFile(t, filepath.Join(root, "stacks/demo/compose.prod.yml"), "services:\n  web:\n    image: nginx:${PROD_TAG}\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	// Env vars are collected from the given file, not docker-compose.yml
+	err = manager.Run(context.Background(), Options{Stacks: []string{"demo"}, TearDown: true, ComposeFile: "compose.prod.yml"})
+	require.ErrorContains(t, err, "PROD_TAG")
+
+	writeFile(t, filepath.Join(root, ".env"), "PROD_TAG=1.27\n")
+	manager, err = NewManager(cfg)
+	require.NoError(t, err)
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, TearDown: true, ComposeFile: "compose.prod.yml"}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t,
+		"compose --project-directory "+filepath.Join(root, "stacks/demo")+
+			" -p demo -f "+filepath.Join(root, "stacks/demo/compose.prod.yml")+" down",
+		strings.TrimSpace(string(logData)))
+
+	err = manager.Run(context.Background(), Options{Stacks: []string{"demo"}, TearDown: true, ComposeFile: "compose.staging.yml"})
+	require.ErrorContains(t, err, "compose file "+filepath.Join(root, "stacks/demo/compose.staging.yml")+" does not exist")
+}
+
+func TestRunPauseAndUnpause(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), "services:\n  web:\n    image: nginx\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	composeArgs := "compose --project-directory " + filepath.Join(root, "stacks/demo") +
+		" -p demo -f " + filepath.Join(root, "stacks/demo/docker-compose.yml")
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Pause: true}))
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Unpause: true}))
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{composeArgs + " pause", composeArgs + " unpause"},
+		strings.Split(strings.TrimSpace(string(logData)), "\n"))
+
+	cleanup()
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Pause: true, DryRun: true}))
+	_, err = os.Stat(logPath)
+	require.ErrorIs(t, err, os.ErrNotExist, "dry run must not pause the stack")
+}
+
 func TestManagerGetVarsAppendsMissingEnv(t *testing.T) {
 	root := t.TempDir()
 	makeDirs(t, root, "stacks/example")
@@ -83,6 +234,185 @@ services:
 	require.Contains(t, content, "IMAGE_TAG=")
 }
 
+func TestManagerGetVarsRecreateEnvPrunesStaleVars(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/example")
+	writeFile(t, filepath.Join(root, ".env"), envContent(`
+COMPOSE_DIRECTORY=stacks
+
+###### example vars #####
+OLD_SECRET=gone
+IMAGE_TAG=v1.2.3
+LEGACY_PORT=8080
+##########################
+
+OTHER=keep
+`))
+	writeFile(t, filepath.Join(root, "stacks/example/docker-compose.yml"), `
+services:
+  job:
+    image: busybox:${IMAGE_TAG}
+    environment:
+      - NEW_VAR=${NEW_VAR}
+`)
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	opts := Options{Stacks: []string{"example"}, GetVars: true, RecreateEnv: true}
+	require.NoError(t, manager.Run(context.Background(), opts))
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	require.Equal(t, envContent(`
+COMPOSE_DIRECTORY=stacks
+
+###### example vars #####
+IMAGE_TAG=v1.2.3
+NEW_VAR=
+##########################
+
+OTHER=keep
+`), string(data))
+}
+
+func TestValidateEnvVarsAcceptsServiceEnvFile(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), envContent(`
+IMAGE_TAG=1.25
+`))
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), `
+services:
+  web:
+    image: nginx:${IMAGE_TAG}
+    env_file:
+      - path: ./app.env
+        required: false
+    command: ["--db-password", "${DB_PASSWORD}"]
+`)
+	writeFile(t, filepath.Join(root, "stacks/demo/app.env"), "DB_PASSWORD=secret\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	_, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}}))
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "DB_PASSWORD", "env_file vars must not be appended to .env")
+}
+
+func TestValidateEnvVarsRequiresVarsInterpolatedInEnvironment(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), "")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), `
+services:
+  web:
+    image: nginx
+    env_file: ./app.env
+    environment:
+      DB_PASSWORD: ${DB_PASSWORD}
+`)
+	writeFile(t, filepath.Join(root, "stacks/demo/app.env"), "DB_PASSWORD=secret\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	_, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManagerWithWriters(cfg, io.Discard, io.Discard)
+	require.NoError(t, err)
+
+	// Compose interpolates environment from the project env, not the env_file
+	err = manager.Run(context.Background(), Options{Stacks: []string{"demo"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "DB_PASSWORD")
+}
+
+func TestOptionalLabelSkipsMissingVar(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), envContent(`
+IMAGE_TAG=1.25
+`))
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), `
+services:
+  web:
+    image: nginx:${IMAGE_TAG}
+    labels:
+      stackr.validate.optional: "EXTRA_OPTS, PROXY_URL"
+    environment:
+      - EXTRA_OPTS=${EXTRA_OPTS}
+      - PROXY_URL=${PROXY_URL}
+`)
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	_, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}}))
+
+	data, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "EXTRA_OPTS", "optional vars must not be appended to .env")
+}
+
+func TestCollectAllEnvVarsOptionalLabel(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	writeFile(t, path, `
+services:
+  web:
+    image: nginx:${IMAGE_TAG}
+    labels:
+      - stackr.validate.optional=SHARED,WEB_ONLY
+    environment:
+      - SHARED=${SHARED}
+      - WEB_ONLY=${WEB_ONLY}
+  worker:
+    image: busybox
+    environment:
+      - SHARED=${SHARED}
+`)
+
+	vars, err := collectAllEnvVars([]string{path})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"IMAGE_TAG", "SHARED"}, vars, "a var stays required while any unlabeled service uses it")
+}
+
 func stubDocker(t *testing.T) (string, func()) {
 	t.Helper()
 	binDir := t.TempDir()
@@ -302,6 +632,172 @@ func TestLoadAllStacks(t *testing.T) {
 	}
 }
 
+func TestLoadEnabledStacksProfileGated(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"api", "ml"} {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services: {}")
+	}
+	makeDirs(t, root, "stacks/ml/stackr")
+	writeFile(t, filepath.Join(root, "stacks", "ml", "stackr", "config.yaml"), "profiles:\n  - gpu\n")
+
+	load := func(profile string) []string {
+		m := &Manager{cfg: config.Config{
+			RepoRoot:  root,
+			StacksDir: filepath.Join(root, "stacks"),
+			Profile:   profile,
+		}}
+		got, err := m.loadEnabledStacks()
+		require.NoError(t, err)
+		return got
+	}
+
+	require.Equal(t, []string{"api"}, load(""))
+	require.Equal(t, []string{"api"}, load("staging"))
+	require.Equal(t, []string{"api", "ml"}, load("gpu"))
+}
+
+func TestRunAllSkipsExcludedStacks(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"api", "noisy", "web"} {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	}
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{All: true, DryRun: true, Exclude: []string{"noisy"}}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	got := string(logData)
+	require.Contains(t, got, filepath.Join(root, "stacks/api"))
+	require.Contains(t, got, filepath.Join(root, "stacks/web"))
+	require.NotContains(t, got, filepath.Join(root, "stacks/noisy"), "excluded stack must not be processed")
+}
+
+func TestRunIncludesComposeOverrideFile(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, "stacks", "demo", "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	writeFile(t, filepath.Join(root, "stacks", "demo", "docker-compose.override.yml"), "services:\n  app:\n    environment:\n      - LEVEL=${DEMO_LEVEL}\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	// The var only referenced by the override file is still detected
+	err = manager.Run(context.Background(), Options{Stacks: []string{"demo"}})
+	require.ErrorContains(t, err, "DEMO_LEVEL")
+
+	writeFile(t, cfg.EnvFile, "DEMO_LEVEL=debug\n")
+	manager, err = NewManager(cfg)
+	require.NoError(t, err)
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	fileArgs := "-f " + filepath.Join(root, "stacks", "demo", "docker-compose.yml") + " -f " + filepath.Join(root, "stacks", "demo", "docker-compose.override.yml")
+	for _, line := range strings.Split(strings.TrimSpace(string(logData)), "\n") {
+		require.Contains(t, line, fileArgs)
+	}
+}
+
+func TestRunMergesStackEnvFileOverGlobal(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "SHARED=global\nLEVEL=info\n")
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, "stacks", "demo", "docker-compose.yml"), `
+services:
+  app:
+    image: nginx
+    environment:
+      - SHARED=${SHARED}
+      - LEVEL=${LEVEL}
+      - SECRET=${DEMO_SECRET}
+`)
+	writeFile(t, filepath.Join(root, "stacks", "demo", ".env"), "LEVEL=debug\nDEMO_SECRET=s3cret\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	// DEMO_SECRET only exists in the stack .env, so validation must pass
+	require.NoError(t, manager.Run(context.Background(), Options{
+		Stacks:      []string{"demo"},
+		VarsOnly:    true,
+		VarsCommand: []string{"sh", "-c", "echo $SHARED $LEVEL $DEMO_SECRET"},
+	}))
+	require.Contains(t, stdout.String(), "global debug s3cret")
+
+	// Neither validation nor get-vars copies stack .env vars into the global one
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, GetVars: true}))
+	envData, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	require.Equal(t, "SHARED=global\nLEVEL=info\n", string(envData))
+}
+
+func TestRunEnvPassthrough(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, "stacks", "demo", "docker-compose.yml"), "services:\n  app:\n    image: nginx\n")
+	t.Setenv("HOST_ALLOWED", "allowed")
+	t.Setenv("HOST_EXTRA", "extra")
+	t.Setenv("HOST_SECRET", "leaked")
+
+	global := testGlobalConfig()
+	global.Env.Passthrough = []string{"HOST_ALLOWED"}
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{
+		Stacks:         []string{"demo"},
+		VarsOnly:       true,
+		VarsCommand:    []string{"sh", "-c", "echo allowed=$HOST_ALLOWED extra=$HOST_EXTRA secret=$HOST_SECRET global=$TEST_VAR"},
+		EnvPassthrough: []string{"HOST_EXTRA"},
+	}))
+	require.Contains(t, stdout.String(), "allowed=allowed extra=extra secret= global=test_value")
+}
+
 func TestRunValidationErrors(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -345,6 +841,17 @@ func TestRunValidationErrors(t *testing.T) {
 			},
 			errSubstr: "no stacks specified",
 		},
+		{
+			name: "AllStacksExcluded",
+			opts: Options{
+				Stacks:  []string{"demo"},
+				Exclude: []string{"demo"},
+			},
+			setup: func(t *testing.T, root string) {
+				makeDirs(t, root, "stacks")
+			},
+			errSubstr: "no stacks left after --exclude",
+		},
 	}
 
 	for _, tt := range tests {
@@ -423,5 +930,274 @@ func TestRunStackMissingCompose(t *testing.T) {
 	err = manager.Run(context.Background(), opts)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "stack orphan")
-	require.Contains(t, err.Error(), "has neither docker-compose.yml")
+	var missing *MissingComposeError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, "orphan", missing.Stack)
+	require.Equal(t, filepath.Join(root, "stacks", "orphan"), missing.Dir)
+	require.Contains(t, err.Error(), "has no compose file in "+missing.Dir)
+	require.Contains(t, err.Error(), "looked for docker-compose.yml, docker-compose.yaml, compose.yaml, compose.yml")
+	require.Contains(t, err.Error(), `run "stackr init"`)
+
+	// A compose file under a name stackr doesn't look for gets the same hint
+	writeFile(t, filepath.Join(root, "stacks", "orphan", "compose.prod.yml"), "services: {}\n")
+	err = manager.Run(context.Background(), opts)
+	require.ErrorAs(t, err, &missing)
+	require.Contains(t, err.Error(), "rename the compose file to one of these")
+}
+
+func TestBackupRejectsBackupDirInsideStacksDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		backupDir string
+		errSubstr string
+	}{
+		{name: "InsideStacksDir", backupDir: "stacks/backups", errSubstr: "inside the stacks dir"},
+		{name: "EqualToStacksDir", backupDir: "stacks", errSubstr: "inside the stacks dir"},
+		{name: "EqualToPoolBase", backupDir: ".ssd_pool", errSubstr: "same as pool SSD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			makeDirs(t, root, "stacks/demo")
+			writeFile(t, filepath.Join(root, ".env"), "")
+			writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), "services: {}")
+
+			global := testGlobalConfig()
+			global.Paths.BackupDir = tt.backupDir
+			cfg := config.Config{
+				RepoRoot:  root,
+				EnvFile:   filepath.Join(root, ".env"),
+				StacksDir: filepath.Join(root, "stacks"),
+				Global:    global,
+			}
+
+			manager, err := NewManager(cfg)
+			require.NoError(t, err)
+
+			err = manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Backup: true})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.errSubstr)
+		})
+	}
+}
+
+func TestUpdateTagDigestPinsResolvedDigest(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), envContent(`
+DEMO_IMAGE_TAG=v1.0.0
+`))
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), `
+services:
+  web:
+    image: "ghcr.io/acme/demo:${DEMO_IMAGE_TAG}"
+`)
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+	script := filepath.Join(filepath.Dir(logPath), "docker")
+	writeFile(t, script, "#!/bin/sh\necho \"$@\" >> \""+logPath+"\"\n"+
+		"if [ \"$1\" = \"buildx\" ]; then echo '{\"mediaType\":\"application/vnd.oci.image.manifest.v1+json\",\"digest\":\""+digest+"\",\"size\":1234}'; fi\n")
+
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	opts := Options{Stacks: []string{"demo"}, Update: true, DryRun: true, Tag: "v1.2.3", TagDigest: true}
+	require.NoError(t, manager.Run(context.Background(), opts))
+
+	envData, err := os.ReadFile(cfg.EnvFile)
+	require.NoError(t, err)
+	require.Contains(t, string(envData), "DEMO_IMAGE_TAG=v1.2.3@"+digest)
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(logData), "buildx imagetools inspect --format {{json .Manifest}} ghcr.io/acme/demo:v1.2.3")
+}
+
+func TestUpdatePrintEnvDiff(t *testing.T) {
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), envContent(`
+DEMO_IMAGE_TAG=v1.0.0
+DEMO_API_TOKEN=hunter2
+`))
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), `
+services:
+  web:
+    image: "ghcr.io/acme/demo:${DEMO_IMAGE_TAG}"
+`)
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	_, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+
+	opts := Options{Stacks: []string{"demo"}, Update: true, DryRun: true, Tag: "v1.2.3", PrintEnvDiff: true}
+	require.NoError(t, manager.Run(context.Background(), opts))
+	require.Contains(t, stdout.String(), "--- a/.env\n+++ b/.env\n@@ DEMO_IMAGE_TAG @@\n-DEMO_IMAGE_TAG=v1.0.0\n+DEMO_IMAGE_TAG=v1.2.3\n")
+	require.NotContains(t, stdout.String(), "DEMO_API_TOKEN")
+
+	stdout.Reset()
+	opts.PrintEnvDiff = false
+	opts.Tag = "v1.2.4"
+	require.NoError(t, manager.Run(context.Background(), opts))
+	require.NotContains(t, stdout.String(), "+++ b/.env")
+}
+
+func TestParseManifestDigestRejectsInvalidDigest(t *testing.T) {
+	_, err := parseManifestDigest([]byte(`{"digest":"sha256:nothex"}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid digest")
+
+	_, err = parseManifestDigest([]byte(`[{"digest":"sha256:abc"}]`))
+	require.Error(t, err)
+}
+
+func TestParseManifestDigestManifestList(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("cd", 32)
+	out := `{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"` + digest + `","size":856}` + "\n"
+	got, err := parseManifestDigest([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, digest, got)
+}
+
+func TestUpdatePullFlags(t *testing.T) {
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	// The local and remote digests match, so the update check finds nothing new
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+  *"config --images"*) echo ghcr.io/acme/web:1 ;;
+  images*) echo sha256:aaaa ;;
+  manifest*) echo '{"Descriptor":{"digest":"sha256:aaaa"}}' ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), "")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), "services:\n  web:\n    image: nginx\n")
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	run := func(opts Options) (string, string) {
+		t.Helper()
+		_ = os.Remove(logPath)
+		var stdout bytes.Buffer
+		manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+		require.NoError(t, err)
+		opts.Stacks = []string{"demo"}
+		opts.Update = true
+		require.NoError(t, manager.Run(context.Background(), opts))
+		data, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		return string(data), stdout.String()
+	}
+
+	calls, out := run(Options{})
+	require.Contains(t, calls, "config --images")
+	require.NotContains(t, calls, "up -d")
+	require.Contains(t, out, "all images up to date, skipping restart")
+
+	calls, out = run(Options{ForceRecreate: true})
+	require.Contains(t, calls, "config --images")
+	require.Contains(t, calls, "up -d --force-recreate\n")
+	require.Contains(t, out, "recreating anyway")
+
+	calls, out = run(Options{ApplyUnchanged: true})
+	require.Contains(t, calls, "config --images")
+	require.Contains(t, calls, "up -d\n")
+	require.Contains(t, out, "all images up to date, applying stack changes")
+
+	calls, _ = run(Options{NoPull: true})
+	require.NotContains(t, calls, "config --images")
+	require.NotContains(t, calls, " pull")
+	require.Contains(t, calls, "up -d\n")
+}
+
+func TestUpdatePullPolicy(t *testing.T) {
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	// web is on the host with the registry's digest, worker is missing
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+  *"config --images"*) printf 'ghcr.io/acme/web:1\nghcr.io/acme/worker:1\n' ;;
+  "image inspect"*ghcr.io/acme/worker:1) exit 1 ;;
+  images*) echo sha256:aaaa ;;
+  manifest*) echo '{"Descriptor":{"digest":"sha256:aaaa"}}' ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), "")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), "services:\n  web:\n    image: nginx\n")
+
+	run := func(policy string) (string, string) {
+		t.Helper()
+		_ = os.Remove(logPath)
+		global := testGlobalConfig()
+		global.PullPolicy = policy
+		cfg := config.Config{
+			RepoRoot:  root,
+			EnvFile:   filepath.Join(root, ".env"),
+			StacksDir: filepath.Join(root, "stacks"),
+			Global:    global,
+		}
+		var stdout bytes.Buffer
+		manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+		require.NoError(t, err)
+		require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Update: true}))
+		data, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		return string(data), stdout.String()
+	}
+
+	// always checks the registry; the check does not cover missing images
+	calls, out := run(config.PullAlways)
+	require.Contains(t, calls, "manifest inspect ghcr.io/acme/web:1")
+	require.NotContains(t, calls, "image inspect")
+	require.Contains(t, out, "all images up to date, skipping restart")
+
+	calls, out = run(config.PullMissing)
+	require.Contains(t, calls, "image inspect --format {{.Id}} ghcr.io/acme/web:1\n")
+	require.NotContains(t, calls, "pull ghcr.io/acme/web:1")
+	require.Contains(t, calls, "pull ghcr.io/acme/worker:1\n")
+	require.NotContains(t, calls, "manifest")
+	require.Contains(t, calls, "up -d\n")
+	require.Contains(t, out, "new images downloaded, restarting stack")
+
+	calls, _ = run(config.PullNever)
+	require.NotContains(t, calls, "config --images")
+	require.NotContains(t, calls, " pull")
+	require.Contains(t, calls, "up -d\n")
 }
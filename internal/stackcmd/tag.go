@@ -0,0 +1,31 @@
+package stackcmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/jamestiberiuskirk/stackr/internal/git"
+)
+
+// tagPattern is a v-prefixed semver with an optional pre-release suffix.
+var tagPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+(-[a-zA-Z0-9._-]+)?$`)
+
+// ValidTag reports whether tag satisfies the deploy tag policy: "latest" or
+// a semver such as v1.2.3 or v1.2.3-rc.1.
+func ValidTag(tag string) bool {
+	return tag == "latest" || tagPattern.MatchString(tag)
+}
+
+// TagFromGit returns "git describe --tags" of repoRoot as the deploy tag,
+// rejecting results outside the tag policy.
+func TagFromGit(ctx context.Context, repoRoot string) (string, error) {
+	tag, err := git.NewClient(repoRoot).Describe(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive tag from git: %w", err)
+	}
+	if !ValidTag(tag) {
+		return "", fmt.Errorf("git describe gave %q, which is not 'latest' or semver format (v1.2.3 or v1.2.3-prerelease)", tag)
+	}
+	return tag, nil
+}
@@ -0,0 +1,69 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/git"
+)
+
+func TestValidTag(t *testing.T) {
+	for _, tag := range []string{"latest", "v1.2.3", "v1.2.3-rc.1", "v1.2.3-4-gabc1234"} {
+		require.True(t, ValidTag(tag), tag)
+	}
+	for _, tag := range []string{"", "1.2.3", "v1.2", "main", "v1.2.3;rm"} {
+		require.False(t, ValidTag(tag), tag)
+	}
+}
+
+func TestTagFromGitAppliesDescribedTag(t *testing.T) {
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "docker"), "#!/bin/sh\nexit 0\n")
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/app")
+	writeFile(t, filepath.Join(root, ".env"), "APP_IMAGE_TAG=v1.0.0\n")
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: app:${APP_IMAGE_TAG}\n")
+
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test User"},
+		{"add", "."},
+		{"commit", "-m", "initial"},
+		{"tag", "v1.4.0"},
+	} {
+		require.NoError(t, git.RunGitCommand(ctx, root, args...))
+	}
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(ctx, Options{Stacks: []string{"app"}, Update: true, TagFromGit: true, DryRun: true}))
+	require.Contains(t, stdout.String(), "Using tag v1.4.0 from git describe")
+	data, err := os.ReadFile(filepath.Join(root, ".env"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "APP_IMAGE_TAG=v1.4.0")
+
+	require.NoError(t, git.RunGitCommand(ctx, root, "tag", "release-candidate"))
+	require.NoError(t, git.RunGitCommand(ctx, root, "tag", "-d", "v1.4.0"))
+	err = manager.Run(ctx, Options{Stacks: []string{"app"}, Update: true, TagFromGit: true, DryRun: true})
+	require.ErrorContains(t, err, `git describe gave "release-candidate"`)
+}
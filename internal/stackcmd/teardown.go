@@ -0,0 +1,34 @@
+package stackcmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// confirmTearDown lists the stacks about to be torn down and requires the
+// user to type "yes". It doesn't prompt with --yes, for dry runs, or when
+// stdin isn't a terminal, so scripts and cron jobs keep working.
+func (m *Manager) confirmTearDown(stacks []string, opts Options) error {
+	if opts.Yes || opts.DryRun || !m.isTerminal(m.stdin) {
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(m.stdout, "The following stacks will be torn down:")
+	for _, stack := range stacks {
+		_, _ = fmt.Fprintf(m.stdout, "  - %s\n", stack)
+	}
+	if m.ask("Type 'yes' to continue: ") != "yes" {
+		return errors.New("tear-down aborted; re-run with --yes to skip the prompt")
+	}
+	return nil
+}
+
+// isTerminal reports whether r is a terminal.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
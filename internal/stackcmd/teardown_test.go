@@ -0,0 +1,69 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func TestTearDownConfirmation(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"app", "db"} {
+		makeDirs(t, root, filepath.Join("stacks", stack))
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), "services:\n  web:\n    image: nginx\n")
+	}
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+	manager.isTerminal = func(io.Reader) bool { return true }
+
+	opts := Options{All: true, TearDown: true}
+
+	// Only a typed "yes" confirms
+	for _, answer := range []string{"y\n", "no\n", ""} {
+		stdout.Reset()
+		manager.stdin = strings.NewReader(answer)
+		err = manager.Run(context.Background(), opts)
+		require.ErrorContains(t, err, "tear-down aborted")
+		require.Contains(t, stdout.String(), "The following stacks will be torn down:\n  - app\n  - db\n")
+		require.NoFileExists(t, logPath)
+	}
+
+	manager.stdin = strings.NewReader("yes\n")
+	require.NoError(t, manager.Run(context.Background(), opts))
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(logData), " down"))
+
+	// --yes skips the prompt
+	stdout.Reset()
+	manager.stdin = strings.NewReader("")
+	opts.Yes = true
+	require.NoError(t, manager.Run(context.Background(), opts))
+	require.NotContains(t, stdout.String(), "will be torn down")
+
+	// So does a non-interactive stdin
+	stdout.Reset()
+	manager.isTerminal = isTerminal
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"app"}, TearDown: true}))
+	require.NotContains(t, stdout.String(), "will be torn down")
+}
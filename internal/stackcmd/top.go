@@ -0,0 +1,92 @@
+package stackcmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+)
+
+// containerStats is one line of "docker stats --format '{{json .}}'" output.
+type containerStats struct {
+	Name     string `json:"name"`
+	CPUPerc  string `json:"cpu_percent"`
+	MemUsage string `json:"mem_usage"`
+	MemPerc  string `json:"mem_percent"`
+	NetIO    string `json:"net_io"`
+	BlockIO  string `json:"block_io"`
+	PIDs     string `json:"pids"`
+}
+
+// stackTop prints a one-shot resource usage snapshot of the stack's running
+// containers, as a table or, with opts.JSON, as a JSON array.
+func (m *Manager) stackTop(ctx context.Context, env []string, stackInfo StackInfo, stack string, opts Options) error {
+	ids, err := m.composeOutput(ctx, env, stackInfo, "ps", "-q")
+	if err != nil {
+		return err
+	}
+
+	stats := []containerStats{}
+	if ids != "" {
+		args := append([]string{"stats", "--no-stream", "--format", "{{json .}}"}, strings.Fields(ids)...)
+		debugf(opts.Debug, "%s: running docker %s", stack, strings.Join(args, " "))
+		cmd := exec.CommandContext(ctx, "docker", args...)
+		cmd.Dir = m.cfg.RepoRoot
+		cmd.Env = env
+		out, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("docker stats failed: %w", err)
+		}
+		stats, err = parseContainerStats(string(out))
+		if err != nil {
+			return fmt.Errorf("stack %s: %w", stack, err)
+		}
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(m.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	if len(stats) == 0 {
+		_, _ = fmt.Fprintf(m.stdout, "%s: no running containers\n", stack)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(m.stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tCPU %\tMEM USAGE / LIMIT\tMEM %\tNET I/O\tBLOCK I/O\tPIDS")
+	for _, s := range stats {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.CPUPerc, s.MemUsage, s.MemPerc, s.NetIO, s.BlockIO, s.PIDs)
+	}
+	return w.Flush()
+}
+
+func parseContainerStats(out string) ([]containerStats, error) {
+	stats := []containerStats{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		// docker stats uses Go template field names as JSON keys
+		var raw struct {
+			Name     string
+			CPUPerc  string
+			MemUsage string
+			MemPerc  string
+			NetIO    string
+			BlockIO  string
+			PIDs     string
+		}
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse docker stats output: %w", err)
+		}
+		stats = append(stats, containerStats(raw))
+	}
+	return stats, scanner.Err()
+}
@@ -0,0 +1,98 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+// stubDockerStats installs a docker stub that logs its args, reports one
+// running container for "compose ps -q" and one stats line for "stats".
+func stubDockerStats(t *testing.T) string {
+	t.Helper()
+	binDir := t.TempDir()
+	logPath := filepath.Join(binDir, "docker.log")
+	script := `#!/bin/sh
+echo "$@" >> "` + logPath + `"
+case "$*" in
+  *"ps -q"*) echo abc123 ;;
+  stats*) echo '{"BlockIO":"0B / 0B","CPUPerc":"1.50%","Container":"abc123","ID":"abc123","MemPerc":"2.00%","MemUsage":"20MiB / 1GiB","Name":"demo-web-1","NetIO":"1kB / 2kB","PIDs":"3"}' ;;
+esac
+`
+	writeFile(t, filepath.Join(binDir, "docker"), script)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func setupTopStack(t *testing.T) config.Config {
+	t.Helper()
+	root := t.TempDir()
+	makeDirs(t, root, "stacks/demo")
+	writeFile(t, filepath.Join(root, ".env"), "")
+	writeFile(t, filepath.Join(root, "stacks/demo/docker-compose.yml"), `
+services:
+  web:
+    image: nginx
+`)
+	return config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+}
+
+func TestManagerTopInvokesDockerStats(t *testing.T) {
+	cfg := setupTopStack(t)
+	logPath := stubDockerStats(t)
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Top: true}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(logData)), "\n")
+	require.Len(t, calls, 2)
+	require.Contains(t, calls[0], "-f "+filepath.Join(cfg.StacksDir, "demo/docker-compose.yml")+" ps -q")
+	require.Equal(t, "stats --no-stream --format {{json .}} abc123", calls[1])
+
+	out := stdout.String()
+	require.Contains(t, out, "NAME")
+	require.Contains(t, out, "demo-web-1")
+	require.Contains(t, out, "20MiB / 1GiB")
+}
+
+func TestManagerTopJSON(t *testing.T) {
+	cfg := setupTopStack(t)
+	stubDockerStats(t)
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Stacks: []string{"demo"}, Top: true, JSON: true}))
+
+	var stats []containerStats
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &stats))
+	require.Equal(t, []containerStats{{
+		Name:     "demo-web-1",
+		CPUPerc:  "1.50%",
+		MemUsage: "20MiB / 1GiB",
+		MemPerc:  "2.00%",
+		NetIO:    "1kB / 2kB",
+		BlockIO:  "0B / 0B",
+		PIDs:     "3",
+	}}, stats)
+}
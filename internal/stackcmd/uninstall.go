@@ -0,0 +1,137 @@
+package stackcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// uninstall tears down every discovered stack and, when opts.Purge is set,
+// removes the stacks' pool volumes and the backup directory. Data of a stack
+// that failed to tear down may still be in use, so its pool volumes and the
+// backups are kept.
+func (m *Manager) uninstall(ctx context.Context, opts Options) error {
+	if !opts.Yes {
+		return errors.New("uninstall tears down every stack; re-run with --yes to confirm")
+	}
+
+	if opts.Purge {
+		if err := m.checkPurgeBackupDir(); err != nil {
+			return err
+		}
+	}
+
+	stacks, err := m.loadAllStacks()
+	if err != nil {
+		return err
+	}
+
+	downOpts := Options{
+		Debug:    opts.Debug,
+		DryRun:   opts.DryRun,
+		TearDown: true,
+	}
+
+	var stopped, failed, removed []string
+	for _, stack := range stacks {
+		_, _ = fmt.Fprintf(m.stdout, "Stack: %s\n", stack)
+		if err := m.runStack(ctx, stack, downOpts); err != nil {
+			_, _ = fmt.Fprintf(m.stderr, "failed to tear down %s: %v\n", stack, err)
+			failed = append(failed, stack)
+			continue
+		}
+		stopped = append(stopped, stack)
+	}
+
+	if opts.Purge {
+		var paths []string
+		for _, stack := range stopped {
+			for _, base := range m.poolBases {
+				paths = append(paths, filepath.Join(base, stack))
+			}
+		}
+		sort.Strings(paths)
+		if m.backupDir != "" && len(failed) == 0 {
+			paths = append(paths, m.backupDir)
+		}
+
+		for _, p := range paths {
+			if _, err := os.Stat(p); err != nil {
+				continue
+			}
+			if opts.DryRun {
+				_, _ = fmt.Fprintf(m.stdout, "[DRY RUN] Would remove %s\n", p)
+				removed = append(removed, p)
+				continue
+			}
+			if err := os.RemoveAll(p); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", p, err)
+			}
+			removed = append(removed, p)
+		}
+	}
+
+	_, _ = fmt.Fprintln(m.stdout, "\nUninstall summary:")
+	_, _ = fmt.Fprintf(m.stdout, "  Stacks torn down (%d): %s\n", len(stopped), joinOrNone(stopped))
+	if len(failed) > 0 {
+		_, _ = fmt.Fprintf(m.stdout, "  Stacks failed (%d): %s\n", len(failed), joinOrNone(failed))
+	}
+	if opts.Purge {
+		_, _ = fmt.Fprintf(m.stdout, "  Paths removed (%d):\n", len(removed))
+		for _, p := range removed {
+			_, _ = fmt.Fprintf(m.stdout, "    - %s\n", p)
+		}
+		if len(failed) > 0 {
+			_, _ = fmt.Fprintln(m.stdout, "  Pool volumes of failed stacks and backups kept")
+		}
+	} else {
+		_, _ = fmt.Fprintln(m.stdout, "  Pool volumes and backups kept (use --purge to remove them)")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to tear down %d stack(s): %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// checkPurgeBackupDir refuses to purge a backup dir that holds more than
+// backups: the repo root, the stacks dir, the .env file or a pool base. An
+// empty paths.backup_dir resolves to the repo root.
+func (m *Manager) checkPurgeBackupDir() error {
+	if m.backupDir == "" {
+		return nil
+	}
+	backupDir := filepath.Clean(m.backupDir)
+	protected := [][2]string{
+		{"repo root", m.cfg.RepoRoot},
+		{"stacks dir", m.cfg.StacksDir},
+		{"env file", m.cfg.EnvFile},
+	}
+	pools := slices.Sorted(maps.Keys(m.poolBases))
+	for _, name := range pools {
+		protected = append(protected, [2]string{"pool " + name, m.poolBases[name]})
+	}
+	for _, p := range protected {
+		name, path := p[0], p[1]
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		if isWithinDir(backupDir, filepath.Clean(path)) {
+			return fmt.Errorf("refusing to purge backup dir %s: it contains the %s (%s); set paths.backup_dir to a dedicated directory", backupDir, name, path)
+		}
+	}
+	return nil
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
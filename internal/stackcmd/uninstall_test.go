@@ -0,0 +1,172 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+)
+
+func setupUninstallRepo(t *testing.T) config.Config {
+	t.Helper()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "")
+	for _, stack := range []string{"alpha", "bravo"} {
+		makeDirs(t, root, "stacks/"+stack)
+		writeFile(t, filepath.Join(root, "stacks", stack, "docker-compose.yml"), `
+services:
+  app:
+    image: nginx
+`)
+		makeDirs(t, root, ".ssd_pool/"+stack)
+	}
+	makeDirs(t, root, "backups/20250101_000000")
+
+	return config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+}
+
+func TestUninstallRequiresConfirmation(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	err = manager.Run(context.Background(), Options{Uninstall: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--yes")
+
+	_, err = os.Stat(logPath)
+	require.True(t, os.IsNotExist(err), "docker must not be invoked without --yes")
+}
+
+func TestUninstallTearsDownAllStacks(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Uninstall: true, Yes: true}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	var downs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(logData)), "\n") {
+		if strings.HasSuffix(line, " down") {
+			downs = append(downs, line)
+		}
+	}
+	require.Len(t, downs, 2)
+	require.Contains(t, downs[0], filepath.Join("alpha", "docker-compose.yml"))
+	require.Contains(t, downs[1], filepath.Join("bravo", "docker-compose.yml"))
+
+	// Without --purge pools and backups are kept
+	require.DirExists(t, filepath.Join(cfg.RepoRoot, ".ssd_pool", "alpha"))
+	require.DirExists(t, filepath.Join(cfg.RepoRoot, "backups"))
+	require.Contains(t, stdout.String(), "Stacks torn down (2): alpha, bravo")
+}
+
+func TestUninstallPurgeRemovesPoolsAndBackups(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	_, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Uninstall: true, Yes: true, Purge: true}))
+
+	require.NoDirExists(t, filepath.Join(cfg.RepoRoot, ".ssd_pool", "alpha"))
+	require.NoDirExists(t, filepath.Join(cfg.RepoRoot, ".ssd_pool", "bravo"))
+	require.NoDirExists(t, filepath.Join(cfg.RepoRoot, "backups"))
+	require.Contains(t, stdout.String(), "Paths removed (3)")
+}
+
+func TestUninstallIncludesProfileGatedStacks(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	makeDirs(t, cfg.RepoRoot, "stacks/bravo/stackr")
+	writeFile(t, filepath.Join(cfg.RepoRoot, "stacks/bravo/stackr/config.yaml"), `
+profiles:
+  - gpu
+`)
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Run(context.Background(), Options{Uninstall: true, Yes: true, Purge: true}))
+
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(logData), filepath.Join("bravo", "docker-compose.yml"))
+	require.NoDirExists(t, filepath.Join(cfg.RepoRoot, ".ssd_pool", "bravo"))
+	require.Contains(t, stdout.String(), "Stacks torn down (2): alpha, bravo")
+}
+
+func TestUninstallPurgeKeepsDataOfFailedStacks(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+
+	// docker fails to tear down bravo only
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "docker"), `#!/bin/sh
+case "$*" in
+  *bravo*down) exit 1 ;;
+esac
+`)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	err = manager.Run(context.Background(), Options{Uninstall: true, Yes: true, Purge: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bravo")
+
+	require.NoDirExists(t, filepath.Join(cfg.RepoRoot, ".ssd_pool", "alpha"))
+	require.DirExists(t, filepath.Join(cfg.RepoRoot, ".ssd_pool", "bravo"))
+	require.DirExists(t, filepath.Join(cfg.RepoRoot, "backups"))
+	require.Contains(t, stdout.String(), "Paths removed (1)")
+}
+
+func TestUninstallPurgeRefusesBackupDirHoldingTheRepo(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	// An empty backup_dir resolves to the repo root
+	cfg.Global.Paths.BackupDir = ""
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	err = manager.Run(context.Background(), Options{Uninstall: true, Yes: true, Purge: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "refusing to purge")
+
+	require.FileExists(t, cfg.EnvFile)
+	require.DirExists(t, filepath.Join(cfg.StacksDir, "alpha"))
+	require.DirExists(t, filepath.Join(cfg.RepoRoot, ".ssd_pool", "alpha"))
+	_, err = os.Stat(logPath)
+	require.True(t, os.IsNotExist(err), "no stack is torn down when the purge is refused")
+}
@@ -0,0 +1,153 @@
+package stackcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// validate checks stacks without deploying them or writing anything: their
+// stackr config, "docker compose config -q" and that every env var they
+// reference is set. It checks opts.Stacks, or every stack when none are
+// given, prints each stack's problems and fails if any stack has one.
+func (m *Manager) validate(ctx context.Context, opts Options) error {
+	names := dedupePreserve(opts.Stacks)
+	explicit := len(names) > 0
+	if !explicit {
+		entries, err := os.ReadDir(m.cfg.StacksDir)
+		if err != nil {
+			return fmt.Errorf("failed to read stacks directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	var checked, invalid, skipped int
+	for _, name := range names {
+		problems, skipReason := m.validateStack(ctx, name, explicit)
+		switch {
+		case len(problems) > 0:
+			checked++
+			invalid++
+			_, _ = fmt.Fprintf(m.stdout, "✗ %s\n", name)
+			for _, problem := range problems {
+				_, _ = fmt.Fprintf(m.stdout, "    - %s\n", strings.ReplaceAll(problem, "\n", "\n      "))
+			}
+		case skipReason != "":
+			skipped++
+			_, _ = fmt.Fprintf(m.stdout, "- %s (skipped: %s)\n", name, skipReason)
+		default:
+			checked++
+			_, _ = fmt.Fprintf(m.stdout, "✓ %s\n", name)
+		}
+	}
+
+	_, _ = fmt.Fprintf(m.stdout, "\nValidated %d stack(s): %d invalid, %d skipped\n", checked, invalid, skipped)
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d stack(s) invalid", invalid, checked)
+	}
+	return nil
+}
+
+// validateStack returns the problems found in stack, or why it was not
+// checked. Directories that are not stacks are skipped unless the stack was
+// named explicitly.
+func (m *Manager) validateStack(ctx context.Context, stack string, explicit bool) (problems []string, skipReason string) {
+	stackDir := filepath.Join(m.cfg.StacksDir, stack)
+	if !dirExists(stackDir) {
+		return []string{fmt.Sprintf("stack %q does not exist", stack)}, ""
+	}
+
+	info, err := resolveStack(m.cfg, stack, stackDir)
+	if err != nil {
+		return []string{err.Error()}, ""
+	}
+	if info == nil {
+		if explicit {
+			return []string{(&MissingComposeError{Stack: stack, Dir: stackDir}).Error()}, ""
+		}
+		return nil, "not a stack"
+	}
+
+	composePaths := info.ComposePaths
+	if len(composePaths) == 0 {
+		return []string{"no compose files configured"}, ""
+	}
+	for _, p := range composePaths {
+		if fileExists(p) {
+			continue
+		}
+		if info.Type == StackTypeRemote {
+			return nil, fmt.Sprintf("remote stack not cloned yet; run \"stackr remote sync %s\"", stack)
+		}
+		problems = append(problems, fmt.Sprintf("compose file %s not found", p))
+	}
+	if len(problems) > 0 {
+		return problems, ""
+	}
+
+	envMap, _, _, err := m.composeEnv(ctx, stack, composePaths)
+	if err != nil {
+		return []string{err.Error()}, ""
+	}
+	envSlice := mapToSlice(envMap)
+
+	// Same checks a deploy makes, without appending missing vars to .env
+	vars, err := collectAllEnvVars(composePaths)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("failed to parse env vars: %v", err))
+	}
+	envFileVars, err := collectServiceEnvFileVars(composePaths, m.env.valuesCopy())
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := m.validateEnvVars(vars, envMap, envFileVars); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	args := append(composeFileArgs(*info), "config", "-q")
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Dir = m.cfg.RepoRoot
+	cmd.Env = envSlice
+	if out, err := cmd.CombinedOutput(); err != nil {
+		detail := strings.TrimSpace(m.maskText(string(out), envSlice))
+		if detail == "" {
+			detail = err.Error()
+		}
+		problems = append(problems, "docker compose config: "+detail)
+	}
+	return problems, ""
+}
+
+// ValidateRemote syncs remote stack and renders its compose config with the
+// stack's env, so variable substitution errors surface before any "up". It
+// returns the rendered config with secret values masked.
+func (m *Manager) ValidateRemote(ctx context.Context, stack string) (string, error) {
+	info, err := ResolveStackPath(m.cfg, stack)
+	if err != nil {
+		return "", err
+	}
+	if info.Type != StackTypeRemote {
+		return "", fmt.Errorf("stack %q is not a remote stack", stack)
+	}
+	if err := m.syncRemoteStack(ctx, stack); err != nil {
+		return "", fmt.Errorf("failed to sync remote stack: %w", err)
+	}
+
+	envMap, _, _, err := m.composeEnv(ctx, stack, info.ComposePaths)
+	if err != nil {
+		return "", err
+	}
+	envSlice := mapToSlice(envMap)
+	out, err := m.composeOutput(ctx, envSlice, info, "config")
+	if err != nil {
+		return "", err
+	}
+	return m.maskText(out, envSlice), nil
+}
@@ -0,0 +1,118 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/jamestiberiuskirk/stackr/internal/config"
+	"github.com/jamestiberiuskirk/stackr/internal/git"
+)
+
+func TestValidateReportsEveryInvalidStack(t *testing.T) {
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "docker"), `#!/bin/sh
+case "$*" in
+  *broken*"config -q") echo "services.app.ports must be a list" >&2; exit 1 ;;
+esac
+`)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "APP_IMAGE_TAG=v1\n")
+	for _, stack := range []string{"app", "broken", "missingvars", "badconfig", "notes"} {
+		makeDirs(t, root, filepath.Join("stacks", stack))
+	}
+	writeFile(t, filepath.Join(root, "stacks/app/docker-compose.yml"), "services:\n  app:\n    image: app:${APP_IMAGE_TAG}\n")
+	writeFile(t, filepath.Join(root, "stacks/broken/docker-compose.yml"), "services:\n  app:\n    image: app\n    ports: 80\n")
+	writeFile(t, filepath.Join(root, "stacks/missingvars/docker-compose.yml"), "services:\n  app:\n    image: app:${MISSING_TAG}\n    environment:\n      KEY: ${MISSING_KEY}\n")
+	writeFile(t, filepath.Join(root, "stacks/badconfig/docker-compose.yml"), "services:\n  app:\n    image: app\n")
+	makeDirs(t, root, "stacks/badconfig/stackr")
+	writeFile(t, filepath.Join(root, "stacks/badconfig/stackr/config.yaml"), "compose_files: [unterminated\n")
+
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    testGlobalConfig(),
+	}
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, io.Discard)
+	require.NoError(t, err)
+
+	err = manager.Run(context.Background(), Options{Validate: true})
+	require.EqualError(t, err, "3 of 4 stack(s) invalid")
+
+	out := stdout.String()
+	require.Contains(t, out, "✓ app\n")
+	require.Contains(t, out, "✗ broken\n    - docker compose config: services.app.ports must be a list\n")
+	require.Contains(t, out, "✗ missingvars\n    - environment variable(s) not set: MISSING_TAG, MISSING_KEY\n")
+	require.Contains(t, out, "✗ badconfig\n    - failed to load config for stack \"badconfig\"")
+	require.Contains(t, out, "- notes (skipped: not a stack)\n")
+	require.Contains(t, out, "Validated 4 stack(s): 3 invalid, 1 skipped\n")
+
+	data, err := os.ReadFile(filepath.Join(root, ".env"))
+	require.NoError(t, err)
+	require.Equal(t, "APP_IMAGE_TAG=v1\n", string(data), "validate must not append missing vars")
+
+	stdout.Reset()
+	require.NoError(t, manager.Run(context.Background(), Options{Validate: true, Stacks: []string{"app"}}))
+	require.Contains(t, stdout.String(), "Validated 1 stack(s): 0 invalid, 0 skipped\n")
+
+	stdout.Reset()
+	require.Error(t, manager.Run(context.Background(), Options{Validate: true, Stacks: []string{"notes", "ghost"}}))
+	require.Contains(t, stdout.String(), "✗ notes\n")
+	require.Contains(t, stdout.String(), "✗ ghost\n    - stack \"ghost\" does not exist\n")
+}
+
+func TestValidateRemoteRendersConfigAfterSync(t *testing.T) {
+	binDir := t.TempDir()
+	writeFile(t, filepath.Join(binDir, "docker"), `#!/bin/sh
+case "$*" in
+  *" config") printf 'services:\n  web:\n    image: "web:%s"\n    environment:\n      API_TOKEN: %s\n' "$WEB_IMAGE_TAG" "$API_TOKEN" ;;
+esac
+`)
+	require.NoError(t, os.Chmod(filepath.Join(binDir, "docker"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	sourceRepo := filepath.Join(root, "source")
+	makeDirs(t, root, "source")
+	initTestGitRepo(t, sourceRepo)
+	writeFile(t, filepath.Join(sourceRepo, "docker-compose.yml"), "services:\n  web:\n    image: web:${WEB_IMAGE_TAG}\n    environment:\n      API_TOKEN: ${API_TOKEN}\n")
+	require.NoError(t, git.RunGitCommand(context.Background(), sourceRepo, "add", "docker-compose.yml"))
+	require.NoError(t, git.RunGitCommand(context.Background(), sourceRepo, "commit", "-m", "Add compose file"))
+
+	makeDirs(t, root, "stacks/web")
+	writeFile(t, filepath.Join(root, "stacks/web/stackr-repo.yml"), "remote_repo:\n  url: "+sourceRepo+"\n  branch: main\n  release:\n    type: commit\n    ref: HEAD\n")
+	makeDirs(t, root, "stacks/local")
+	writeFile(t, filepath.Join(root, "stacks/local/docker-compose.yml"), "services:\n  app:\n    image: app\n")
+	writeFile(t, filepath.Join(root, ".env"), "WEB_IMAGE_TAG=v2\nAPI_TOKEN=hunter2\n")
+
+	global := testGlobalConfig()
+	global.RemoteStacksDir = ".stackr-repos"
+	cfg := config.Config{
+		RepoRoot:  root,
+		EnvFile:   filepath.Join(root, ".env"),
+		StacksDir: filepath.Join(root, "stacks"),
+		Global:    global,
+	}
+	manager, err := NewManagerWithWriters(cfg, io.Discard, io.Discard)
+	require.NoError(t, err)
+
+	out, err := manager.ValidateRemote(context.Background(), "web")
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(root, ".stackr-repos", "web", "docker-compose.yml"))
+	require.Contains(t, out, `image: "web:v2"`)
+	require.Contains(t, out, "API_TOKEN: ***")
+	require.NotContains(t, out, "hunter2")
+
+	_, err = manager.ValidateRemote(context.Background(), "local")
+	require.ErrorContains(t, err, "not a remote stack")
+}
@@ -0,0 +1,118 @@
+package stackcmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamestiberiuskirk/stackr/internal/watch"
+)
+
+// watchStacks updates a stack whenever files under its directory change,
+// until ctx is canceled. With opts.Stacks set only those stacks are deployed.
+// Changes within watch.deploy_cooldown of a stack's last deploy are deferred
+// until the cooldown ends.
+func (m *Manager) watchStacks(ctx context.Context, opts Options) error {
+	var (
+		mu         sync.Mutex
+		pending    []string
+		notify     = make(chan struct{}, 1)
+		lastDeploy = make(map[string]time.Time)
+		deferred   = make(map[string]*time.Timer)
+		cooldown   = m.cfg.Global.Watch.Cooldown()
+	)
+	defer func() {
+		for _, timer := range deferred {
+			timer.Stop()
+		}
+	}()
+
+	queue := func(stack string) {
+		mu.Lock()
+		if !slices.Contains(pending, stack) {
+			pending = append(pending, stack)
+		}
+		mu.Unlock()
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+
+	if err := watch.WatchStacks(ctx, m.cfg.StacksDir, m.cfg.WatchIgnore, func(path string) {
+		stack := m.stackForPath(path)
+		if stack == "" {
+			debugf(opts.Debug, "watch: ignoring change outside a stack (%s)", path)
+			return
+		}
+		if len(opts.Stacks) > 0 && !slices.Contains(opts.Stacks, stack) {
+			debugf(opts.Debug, "watch: ignoring change in %s (not watched)", stack)
+			return
+		}
+		queue(stack)
+	}); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", m.cfg.StacksDir, err)
+	}
+
+	watched := "all stacks"
+	if len(opts.Stacks) > 0 {
+		watched = strings.Join(opts.Stacks, ", ")
+	}
+	_, _ = fmt.Fprintf(m.stdout, "Watching %s for changes to %s (Ctrl+C to stop)\n", m.cfg.StacksDir, watched)
+
+	// Redeploy like "update" (pull policy, offline stacks, cron cleanup), but
+	// also when no image changed since the change is usually to the stack
+	deployOpts := Options{Debug: opts.Debug, DryRun: opts.DryRun, Update: true, ApplyUnchanged: true}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+			mu.Lock()
+			stacks := pending
+			pending = nil
+			mu.Unlock()
+
+			for _, stack := range stacks {
+				if last, ok := lastDeploy[stack]; ok && time.Since(last) < cooldown {
+					// Re-queue the stack once the cooldown ends; later
+					// changes until then ride on the same timer
+					if _, ok := deferred[stack]; !ok {
+						wait := cooldown - time.Since(last)
+						_, _ = fmt.Fprintf(m.stdout, "Change detected in %s, deferring redeploy by %s (cooldown %s)\n",
+							stack, wait.Round(time.Second), cooldown)
+						deferred[stack] = time.AfterFunc(wait, func() { queue(stack) })
+					}
+					continue
+				}
+				delete(deferred, stack)
+				lastDeploy[stack] = time.Now()
+				_, _ = fmt.Fprintf(m.stdout, "Change detected in %s, redeploying\n", stack)
+				if err := m.runStack(ctx, stack, deployOpts); err != nil {
+					log.Printf("watch: deploy of %s failed: %v", stack, err)
+					continue
+				}
+				_, _ = fmt.Fprintf(m.stdout, "Redeployed %s\n", stack)
+			}
+		}
+	}
+}
+
+// stackForPath maps a path inside the stacks dir to the stack it belongs to.
+func (m *Manager) stackForPath(path string) string {
+	rel, err := filepath.Rel(m.cfg.StacksDir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	stack, rest, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	// Files directly in the stacks dir do not belong to a stack
+	if rest == "" && !dirExists(filepath.Join(m.cfg.StacksDir, stack)) {
+		return ""
+	}
+	return stack
+}
@@ -0,0 +1,139 @@
+package stackcmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchRedeploysChangedStack(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var stdout bytes.Buffer
+	manager, err := NewManagerWithWriters(cfg, &stdout, &stdout)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run(ctx, Options{Watch: true, Stacks: []string{"alpha"}})
+	}()
+	// Give the watcher time to register
+	time.Sleep(200 * time.Millisecond)
+
+	upCalls := func() []string {
+		data, _ := os.ReadFile(logPath)
+		var ups []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasSuffix(line, "up -d") {
+				ups = append(ups, line)
+			}
+		}
+		return ups
+	}
+
+	// bravo is not in --stacks, so editing it must not deploy anything
+	writeFile(t, filepath.Join(cfg.StacksDir, "bravo", "docker-compose.yml"), "services:\n  app:\n    image: nginx:1\n")
+	time.Sleep(3 * time.Second)
+	require.Empty(t, upCalls())
+
+	writeFile(t, filepath.Join(cfg.StacksDir, "alpha", "docker-compose.yml"), "services:\n  app:\n    image: nginx:1\n")
+	require.Eventually(t, func() bool { return len(upCalls()) > 0 }, 10*time.Second, 100*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	ups := upCalls()
+	require.Len(t, ups, 1)
+	require.Contains(t, ups[0], filepath.Join("alpha", "docker-compose.yml"))
+
+	// The redeploy went through the update path, which pulls first
+	logData, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(logData), filepath.Join("alpha", "docker-compose.yml")+" pull")
+}
+
+func TestWatchDefersRedeployWithinCooldown(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	cfg.Global.Watch.DeployCooldown = "5s"
+	logPath, cleanup := stubDocker(t)
+	defer cleanup()
+
+	var (
+		mu     sync.Mutex
+		stdout bytes.Buffer
+	)
+	manager, err := NewManagerWithWriters(cfg, lockedWriter{&mu, &stdout}, lockedWriter{&mu, &stdout})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run(ctx, Options{Watch: true, Stacks: []string{"alpha"}})
+	}()
+	// Give the watcher time to register
+	time.Sleep(200 * time.Millisecond)
+
+	upCount := func() int {
+		data, _ := os.ReadFile(logPath)
+		return strings.Count(string(data), "up -d")
+	}
+	output := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return stdout.String()
+	}
+
+	composePath := filepath.Join(cfg.StacksDir, "alpha", "docker-compose.yml")
+	writeFile(t, composePath, "services:\n  app:\n    image: nginx:1\n")
+	require.Eventually(t, func() bool { return upCount() == 1 }, 10*time.Second, 100*time.Millisecond)
+
+	// A second burst after the debounce window is still inside the cooldown,
+	// so it is deployed once the cooldown ends
+	writeFile(t, composePath, "services:\n  app:\n    image: nginx:2\n")
+	require.Eventually(t, func() bool { return strings.Contains(output(), "deferring") }, 10*time.Second, 100*time.Millisecond)
+	require.Equal(t, 1, upCount())
+	require.Eventually(t, func() bool { return upCount() == 2 }, 10*time.Second, 100*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Contains(t, output(), "Change detected in alpha, deferring redeploy")
+}
+
+// lockedWriter serializes writes so a test can read output the watcher is
+// still writing.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func TestStackForPath(t *testing.T) {
+	cfg := setupUninstallRepo(t)
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, "alpha", manager.stackForPath(filepath.Join(cfg.StacksDir, "alpha", "config", "app.yml")))
+	require.Equal(t, "bravo", manager.stackForPath(filepath.Join(cfg.StacksDir, "bravo")))
+	require.Equal(t, "", manager.stackForPath(filepath.Join(cfg.StacksDir, "README.md")))
+	require.Equal(t, "", manager.stackForPath(filepath.Join(cfg.RepoRoot, ".env")))
+}
@@ -0,0 +1,150 @@
+// Package stacklock provides per-stack locks shared by stackrd's runner and
+// cron scheduler, so a deploy and a cron run of the same stack never overlap.
+package stacklock
+
+import (
+	"context"
+	"sync"
+)
+
+// Locks holds one read/write lock per stack: cron runs share a stack's lock,
+// deploys hold it alone. A waiting deploy keeps new cron runs from starting
+// so it isn't starved. A nil *Locks never blocks, for callers that run
+// without a daemon to coordinate with.
+type Locks struct {
+	mu     sync.Mutex
+	stacks map[string]*stackLock
+}
+
+type stackLock struct {
+	readers        int
+	writer         bool
+	writersWaiting int
+	// released is closed and replaced whenever the lock is released
+	released chan struct{}
+}
+
+func (s *stackLock) free(shared bool) bool {
+	if shared {
+		return !s.writer && s.writersWaiting == 0
+	}
+	return !s.writer && s.readers == 0
+}
+
+// New returns a set of locks with every stack free.
+func New() *Locks {
+	return &Locks{stacks: map[string]*stackLock{}}
+}
+
+// Lock blocks until stack's lock can be held alone or ctx is done. On
+// success the returned func releases it.
+func (l *Locks) Lock(ctx context.Context, stack string) (func(), error) {
+	return l.acquire(ctx, stack, false)
+}
+
+// RLock blocks until stack's lock can be shared or ctx is done. On success
+// the returned func releases it.
+func (l *Locks) RLock(ctx context.Context, stack string) (func(), error) {
+	return l.acquire(ctx, stack, true)
+}
+
+// TryLock holds stack's lock alone if it is free. On success the returned
+// func releases it.
+func (l *Locks) TryLock(stack string) (func(), bool) {
+	return l.try(stack, false)
+}
+
+// TryRLock shares stack's lock if no deploy holds or waits for it. On
+// success the returned func releases it.
+func (l *Locks) TryRLock(stack string) (func(), bool) {
+	return l.try(stack, true)
+}
+
+func (l *Locks) try(stack string, shared bool) (func(), bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.stack(stack)
+	if !s.free(shared) {
+		return nil, false
+	}
+	return l.take(s, shared), true
+}
+
+func (l *Locks) acquire(ctx context.Context, stack string, shared bool) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	s := l.stack(stack)
+	if !shared {
+		s.writersWaiting++
+	}
+	for {
+		if s.free(shared) {
+			if !shared {
+				s.writersWaiting--
+			}
+			release := l.take(s, shared)
+			l.mu.Unlock()
+			return release, nil
+		}
+		released := s.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+			l.mu.Lock()
+		case <-ctx.Done():
+			l.mu.Lock()
+			if !shared {
+				s.writersWaiting--
+				// Readers held back by this writer may go ahead now
+				l.wake(s)
+			}
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// stack returns stack's lock state; l.mu must be held.
+func (l *Locks) stack(stack string) *stackLock {
+	s, ok := l.stacks[stack]
+	if !ok {
+		s = &stackLock{released: make(chan struct{})}
+		l.stacks[stack] = s
+	}
+	return s
+}
+
+// take marks s as held and returns the func that releases it; l.mu must be
+// held.
+func (l *Locks) take(s *stackLock, shared bool) func() {
+	if shared {
+		s.readers++
+	} else {
+		s.writer = true
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if shared {
+				s.readers--
+			} else {
+				s.writer = false
+			}
+			l.wake(s)
+		})
+	}
+}
+
+// wake lets everyone waiting on s re-check it; l.mu must be held.
+func (l *Locks) wake(s *stackLock) {
+	close(s.released)
+	s.released = make(chan struct{})
+}
@@ -0,0 +1,127 @@
+package stacklock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireBlocked asserts that nothing arrives on acquired for a moment.
+func requireBlocked(t *testing.T, acquired <-chan func(), msg string) {
+	t.Helper()
+	select {
+	case <-acquired:
+		t.Fatal(msg)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// requireAcquired waits for a lock to arrive on acquired and releases it.
+func requireAcquired(t *testing.T, acquired <-chan func(), msg string) {
+	t.Helper()
+	select {
+	case unlock := <-acquired:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatal(msg)
+	}
+}
+
+// writerWaiting reports whether a Lock of stack is waiting, holding back new
+// readers.
+func writerWaiting(l *Locks, stack string) bool {
+	if unlock, ok := l.TryRLock(stack); ok {
+		unlock()
+		return false
+	}
+	return true
+}
+
+func lockAsync(ctx context.Context, lock func(context.Context, string) (func(), error), stack string) <-chan func() {
+	acquired := make(chan func(), 1)
+	go func() {
+		if unlock, err := lock(ctx, stack); err == nil {
+			acquired <- unlock
+		}
+	}()
+	return acquired
+}
+
+func TestLockIsExclusive(t *testing.T) {
+	l := New()
+	unlock, ok := l.TryLock("app")
+	require.True(t, ok)
+
+	_, ok = l.TryLock("app")
+	require.False(t, ok, "app is already locked")
+	_, ok = l.TryRLock("app")
+	require.False(t, ok, "app is already locked")
+	unlockOther, ok := l.TryLock("db")
+	require.True(t, ok, "other stacks are independent")
+	unlockOther()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.Lock(ctx, "app")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	acquired := lockAsync(context.Background(), l.RLock, "app")
+	requireBlocked(t, acquired, "RLock returned while app was locked")
+	unlock()
+	requireAcquired(t, acquired, "RLock did not return after app was released")
+}
+
+func TestRLockIsShared(t *testing.T) {
+	l := New()
+	unlockA, ok := l.TryRLock("app")
+	require.True(t, ok)
+	unlockB, ok := l.TryRLock("app")
+	require.True(t, ok, "cron runs share the lock")
+	_, ok = l.TryLock("app")
+	require.False(t, ok)
+
+	acquired := lockAsync(context.Background(), l.Lock, "app")
+	// A waiting Lock holds back new readers
+	require.Eventually(t, func() bool { return writerWaiting(l, "app") }, time.Second, time.Millisecond)
+	requireBlocked(t, acquired, "Lock returned while app was shared")
+
+	unlockA()
+	requireBlocked(t, acquired, "Lock returned while app was still shared")
+	unlockB()
+	unlockB() // releasing twice is harmless
+	requireAcquired(t, acquired, "Lock did not return after app was released")
+}
+
+func TestCancelledLockLetsReadersIn(t *testing.T) {
+	l := New()
+	unlock, ok := l.TryRLock("app")
+	require.True(t, ok)
+	defer unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lockAsync(ctx, l.Lock, "app")
+	require.Eventually(t, func() bool { return writerWaiting(l, "app") }, time.Second, time.Millisecond)
+
+	acquired := lockAsync(context.Background(), l.RLock, "app")
+	requireBlocked(t, acquired, "RLock returned past a waiting Lock")
+	cancel()
+	requireAcquired(t, acquired, "RLock did not return after the waiting Lock gave up")
+}
+
+func TestNilLocksNeverBlock(t *testing.T) {
+	var l *Locks
+	unlock, ok := l.TryLock("app")
+	require.True(t, ok)
+	unlock()
+	unlock, ok = l.TryRLock("app")
+	require.True(t, ok)
+	unlock()
+	unlock, err := l.Lock(context.Background(), "app")
+	require.NoError(t, err)
+	unlock()
+	unlock, err = l.RLock(context.Background(), "app")
+	require.NoError(t, err)
+	unlock()
+}
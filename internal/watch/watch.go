@@ -4,7 +4,9 @@ import (
 	"context"
 	"log"
 	"os"
+	pathpkg "path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -13,23 +15,77 @@ import (
 const debounceWindow = 2 * time.Second
 
 // WatchStacks monitors root (recursively) for any filesystem changes and invokes cb after
-// debouncing bursts of events. The watcher stops when ctx is canceled.
-func WatchStacks(ctx context.Context, root string, cb func(string)) error {
+// debouncing bursts of events. Changes to paths matching one of the patterns
+// ignore returns (see Ignored) are dropped; it is called for every change so
+// the patterns can follow a config reload. The watcher stops when ctx is
+// canceled.
+func WatchStacks(ctx context.Context, root string, ignore func() []string, cb func(string)) error {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return err
 	}
 
-	if err := addRecursive(watcher, root); err != nil {
+	skip := func(path string) bool { return Ignored(root, path, ignore()) }
+	if err := addRecursive(watcher, root, skip); err != nil {
 		_ = watcher.Close()
 		return err
 	}
 
-	go run(ctx, watcher, cb)
+	go run(ctx, watcher, skip, cb)
 	return nil
 }
 
-func run(ctx context.Context, watcher *fsnotify.Watcher, cb func(string)) {
+// Ignored reports whether path, under root, matches one of the glob
+// patterns, which are relative to root. A pattern without a slash matches
+// a file or directory of that name anywhere, "**" matches any number of
+// directories, and everything under a matching directory is ignored too.
+func Ignored(root, path string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i <= len(elems); i++ {
+		for _, pattern := range patterns {
+			if !strings.Contains(pattern, "/") {
+				if ok, _ := pathpkg.Match(pattern, elems[i-1]); ok {
+					return true
+				}
+				continue
+			}
+			if matchElems(strings.Split(strings.Trim(pattern, "/"), "/"), elems[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchElems matches path elements against pattern elements, where "**"
+// matches zero or more elements.
+func matchElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	ok, _ := pathpkg.Match(pattern[0], elems[0])
+	return ok && matchElems(pattern[1:], elems[1:])
+}
+
+func run(ctx context.Context, watcher *fsnotify.Watcher, skip func(string) bool, cb func(string)) {
 	defer func() {
 		_ = watcher.Close()
 	}()
@@ -70,10 +126,13 @@ func run(ctx context.Context, watcher *fsnotify.Watcher, cb func(string)) {
 			if event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Write|fsnotify.Rename) == 0 {
 				continue
 			}
+			if skip(event.Name) {
+				continue
+			}
 
 			if event.Op&fsnotify.Create != 0 {
 				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
-					if err := addRecursive(watcher, event.Name); err != nil {
+					if err := addRecursive(watcher, event.Name, skip); err != nil {
 						log.Printf("failed to add new directory to watcher (%s): %v", event.Name, err)
 					}
 				}
@@ -95,7 +154,7 @@ func run(ctx context.Context, watcher *fsnotify.Watcher, cb func(string)) {
 	}
 }
 
-func addRecursive(watcher *fsnotify.Watcher, root string) error {
+func addRecursive(watcher *fsnotify.Watcher, root string, skip func(string) bool) error {
 	return filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -104,6 +163,10 @@ func addRecursive(watcher *fsnotify.Watcher, root string) error {
 		if entry.Type()&os.ModeSymlink != 0 || !entry.IsDir() {
 			return nil
 		}
+		// Ignored directories aren't watched at all
+		if skip(path) {
+			return filepath.SkipDir
+		}
 
 		if err := watcher.Add(path); err != nil {
 			return err
@@ -20,7 +20,7 @@ func TestWatchStacksDebouncesEvents(t *testing.T) {
 	var events []string
 	done := make(chan struct{})
 
-	require.NoError(t, WatchStacks(ctx, root, func(path string) {
+	require.NoError(t, WatchStacks(ctx, root, func() []string { return nil }, func(path string) {
 		mu.Lock()
 		events = append(events, path)
 		mu.Unlock()
@@ -44,3 +44,62 @@ func TestWatchStacksDebouncesEvents(t *testing.T) {
 	require.NotEmpty(t, events[0])
 	cancel()
 }
+
+func TestIgnored(t *testing.T) {
+	root := "/srv/stacks"
+	patterns := []string{"**/logs/**", "*.db", ".stackr-repos", "web/cache/*.tmp"}
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{path: "web/logs", want: true},
+		{path: "web/logs/access.log", want: true},
+		{path: "web/data/logs/nested/x", want: true},
+		{path: "app/state.db", want: true},
+		{path: "app/data/state.db", want: true},
+		{path: ".stackr-repos/remote/docker-compose.yml", want: true},
+		{path: "web/cache/a.tmp", want: true},
+		{path: "web/cache/a.txt", want: false},
+		{path: "other/web/cache/a.tmp", want: false},
+		{path: "web/docker-compose.yml", want: false},
+		{path: "web/catalogs/x", want: false},
+		{path: "app/state.db-journal", want: false},
+	} {
+		require.Equal(t, tt.want, Ignored(root, filepath.Join(root, tt.path), patterns), tt.path)
+	}
+
+	require.False(t, Ignored(root, root, []string{"*"}), "the root itself is never ignored")
+	require.False(t, Ignored(root, "/elsewhere/app.db", patterns), "paths outside root are not ignored")
+	require.False(t, Ignored(root, filepath.Join(root, "app/state.db"), nil))
+}
+
+func TestWatchStacksSkipsIgnoredChanges(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "web", "logs"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "api"), 0o755))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan string, 10)
+	require.NoError(t, WatchStacks(ctx, root, func() []string { return []string{"**/logs/**", "*.db"} }, func(path string) {
+		events <- path
+	}))
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "web", "logs", "app.log"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "web", "state.db"), []byte("a"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "api", "logs", "new"), 0o755))
+	select {
+	case path := <-events:
+		t.Fatalf("ignored change triggered the callback: %s", path)
+	case <-time.After(debounceWindow + 500*time.Millisecond):
+	}
+
+	compose := filepath.Join(root, "web", "docker-compose.yml")
+	require.NoError(t, os.WriteFile(compose, []byte("services: {}\n"), 0o644))
+	select {
+	case path := <-events:
+		require.Equal(t, compose, path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for watcher event")
+	}
+}